@@ -1,6 +1,10 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"net/http"
+	"os"
 	"strconv"
 	"sync"
 	"time"
@@ -8,6 +12,7 @@ import (
 	"github.com/s3ndd/coffeeshop/internal/coffeeshop"
 	"github.com/s3ndd/coffeeshop/internal/config"
 	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/tracing"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 )
@@ -15,7 +20,33 @@ import (
 // main is the entry point of the application
 // It creates a new coffee shop and serves 100 customers
 // It then prints the metrics summary and closes the coffee shop
+// With --replay, it recomputes the metrics summary from a previously recorded event log instead
+// With --report, it also writes an HTML report of the run, for example: coffeeshop run --report out.html
+// With --tui, it shows a live dashboard of the shop in the terminal and writes the logs to --log-file
 func main() {
+	replayPath := flag.String("replay", "", "recompute the metrics summary from the given event log instead of running the shop")
+	reportPath := flag.String("report", "", "write an HTML report of the run to the given file")
+	tui := flag.Bool("tui", false, "show a live dashboard of the shop in the terminal")
+	logFile := flag.String("log-file", "coffeeshop.log", "the file the logs are written to with --tui")
+	// "coffeeshop run" is the same as "coffeeshop", the shop is run unless --replay is given
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "run" {
+		args = args[1:]
+	}
+	_ = flag.CommandLine.Parse(args)
+
+	// The dashboard takes over the terminal, so the logs go to a file
+	if *tui {
+		if err := utils.SetLogOutputPaths(*logFile); err != nil {
+			utils.Logger().WithError(err).Fatal("Error opening log file")
+		}
+	}
+
+	if *replayPath != "" {
+		replay(*replayPath)
+		return
+	}
+
 	logger := utils.Logger()
 	logger.Info("Starting coffee shop")
 
@@ -27,15 +58,71 @@ func main() {
 	// ordersWg is used to wait for all orders to be completed
 	ordersWg := &sync.WaitGroup{}
 
-	// Create a new event system and start the event listener
-	eventSystem := monitor.NewEventSystem()
-	go eventSystem.StartEventListener()
+	// Record the spans of every customer's journey, the trace IDs are in the log lines either way
+	var spanRecorder *tracing.RecordingTracer
+	if cfg.Monitor().SpansPath != "" {
+		spanRecorder = tracing.NewRecordingTracer()
+		tracing.SetGlobalTracer(spanRecorder)
+	}
+
+	// Create a new event system, the metrics and the event log subscribe to it
+	eventSystem, err := monitor.NewEventSystem(cfg.Monitor())
+	if err != nil {
+		logger.WithError(err).Fatal("Error creating event system")
+	}
+
+	// Record a trace of the run
+	// The trace is written after the run, it slows down the shop rather than drop events when it falls behind
+	var traceRecorder *monitor.TraceRecorder
+	if cfg.Monitor().TracePath != "" {
+		traceRecorder = monitor.NewTraceRecorder()
+		eventSystem.SubscribeWithPolicy("trace", traceRecorder, nil, monitor.Block)
+	}
+
+	// Record the completed orders for the report
+	// The report is written after the run, it slows down the shop rather than drop events when it falls behind
+	var reportRecorder *monitor.ReportRecorder
+	if *reportPath != "" {
+		reportRecorder = monitor.NewReportRecorder()
+		eventSystem.SubscribeWithPolicy("report", reportRecorder, nil, monitor.Block)
+	}
 
 	// Create a new coffee shop
 	coffeeShop := coffeeshop.NewCoffeeShop(cfg.CoffeeShop(), ordersWg, eventSystem)
 	// Open the coffee shop
 	coffeeShop.Open()
 
+	// Serve the live metrics and events of the coffee shop
+	// The event stream drops events rather than slowing down the shop when it falls behind
+	var server *http.Server
+	var eventStream *monitor.EventStream
+	if address := cfg.Monitor().ServerAddress; address != "" {
+		eventStream = monitor.NewEventStream()
+		eventSystem.SubscribeWithPolicy("eventStream", eventStream, nil, monitor.DropOldest)
+		server = monitor.NewServer(address, eventSystem.Metrics(), coffeeShop, eventStream)
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.WithError(err).Error("Error serving metrics")
+			}
+		}()
+		logger.WithField("address", address).Info("Serving metrics")
+	}
+
+	// Sample the queues of the coffee shop
+	var queueSampler *monitor.QueueSampler
+	if sampling := cfg.Monitor().QueueSampling; sampling.IntervalSeconds > 0 {
+		queueSampler = monitor.NewQueueSampler(coffeeShop, time.Duration(sampling.IntervalSeconds*float64(time.Second)))
+		queueSampler.Start()
+	}
+
+	// Show the live dashboard
+	var dashboard *monitor.Dashboard
+	if *tui {
+		dashboard = monitor.NewDashboard(coffeeShop, eventSystem.Metrics())
+		eventSystem.SubscribeWithPolicy("dashboard", dashboard, nil, monitor.DropOldest)
+		dashboard.Start(os.Stdout, 500*time.Millisecond)
+	}
+
 	// For simulation purposes, we will serve 20 customers
 	for i := 0; i < 20; i++ {
 		customer := types.NewCustomer(strconv.Itoa(i), cfg)
@@ -46,13 +133,74 @@ func main() {
 
 	// Wait for all orders to be completed
 	ordersWg.Wait()
+	// Close the coffee shop, no delivery arrives once the orders are done
+	coffeeShop.Close()
+
+	// Export the queue lengths
+	if queueSampler != nil {
+		queueSampler.Stop()
+		sampling := cfg.Monitor().QueueSampling
+		if err := queueSampler.Export(sampling.CSVPath, sampling.JSONPath); err != nil {
+			logger.WithError(err).Error("Error exporting queue samples")
+		}
+	}
 
 	// Print the metrics summary
 	eventSystem.Stop()
+	if dashboard != nil {
+		dashboard.Stop(os.Stdout)
+	}
 	eventSystem.PrintMetricsSummary()
+	if spanRecorder != nil {
+		if err := spanRecorder.Export(cfg.Monitor().SpansPath); err != nil {
+			logger.WithError(err).Error("Error writing spans")
+		}
+	}
+	if traceRecorder != nil {
+		if err := traceRecorder.Export(cfg.Monitor().TracePath); err != nil {
+			logger.WithError(err).Error("Error writing trace")
+		}
+	}
+	if reportRecorder != nil {
+		input := monitor.ReportInput{Config: cfg, Metrics: eventSystem.Metrics().Snapshot()}
+		if queueSampler != nil {
+			input.QueueSamples = queueSampler.Samples()
+		}
+		if err := reportRecorder.Export(*reportPath, input); err != nil {
+			logger.WithError(err).Error("Error writing report")
+		} else {
+			logger.WithField("report", *reportPath).Info("Report written")
+		}
+	}
+	if path := cfg.Monitor().DrinkReportPath; path != "" {
+		if err := eventSystem.Metrics().ExportDrinkReport(path); err != nil {
+			logger.WithError(err).Error("Error writing drink report")
+		}
+	}
 
-	// Close the coffee shop
-	coffeeShop.Close()
+	if eventStream != nil {
+		eventStream.Close()
+	}
+	if server != nil {
+		server.Close()
+	}
 
 	logger.Info("Coffee shop closed")
 }
+
+// replay feeds the recorded events into a fresh metrics object and prints its summary
+// The histogram buckets are read from the config file, so new buckets can be back-filled against old runs
+func replay(path string) {
+	logger := utils.Logger().WithField("eventLog", path)
+	logger.Info("Replaying event log")
+
+	buckets, err := monitor.ParseHistogramBuckets(config.LoadConfig().Monitor().HistogramBuckets)
+	if err != nil {
+		logger.WithError(err).Fatal("Error reading histogram buckets")
+	}
+	metrics := monitor.NewMetrics(buckets...)
+	if err := monitor.ReplayEventLog(path, metrics); err != nil {
+		logger.WithError(err).Fatal("Error replaying event log")
+	}
+	metrics.PrintSummary()
+}
@@ -1,11 +1,17 @@
 package barista
 
 import (
+	"context"
 	"sync"
+	"time"
 
-	"github.com/s3ndd/coffeeshop/internal/coffeeshop/brewer"
-	"github.com/s3ndd/coffeeshop/internal/coffeeshop/grinder"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/coldbrew"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/inventory"
+	"github.com/s3ndd/coffeeshop/internal/config"
 	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/recipe"
+	"github.com/s3ndd/coffeeshop/internal/tracing"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 )
@@ -18,25 +24,47 @@ type Baristaer interface {
 
 // Barista is a worker that processes orders
 type Barista struct {
-	ID          int
-	grinderPool chan *grinder.Grinder
-	brewerPool  chan *brewer.Brewer
+	ID int
+	// pools are the equipment pools by class of equipment, each step of a recipe acquires its equipment from one of them
+	pools map[recipe.Equipment]equipment.Acquirer
+	// equipmentTimeout is how long the barista waits for equipment before reporting it, it waits without reporting if it is zero
+	equipmentTimeout time.Duration
+	// coldBrew is the cold brew stock the cold brew orders are drawn from and the baristas replenish
+	coldBrew *coldbrew.Stock
+	// extras is how each extra is added, by name
+	extras map[string]config.ExtraSettings
+	// recipes are the recipes of the coffee types, by name, the coffee types missing follow the default recipe
+	recipes     map[string]recipe.Recipe
+	inventory   inventory.Inventoryer
 	available   chan struct{}
 	ordersWg    *sync.WaitGroup
 	eventSystem monitor.EventSystemer
 }
 
 // NewBarista creates a new barista
-// the grinderPool and brewerPool are used to get available grinders and brewers
+// the pools are used to get available equipment of each class, like grinders and brewers
+// the equipmentTimeout is how long the barista waits for equipment before reporting the wait, zero to never report it
+// the coldBrew is the stock the cold brew orders are drawn from, it may be nil if no coffee type is cold brew
+// the extras tell which extras are steamed and how long the condiments take to add
+// the recipes are the steps the barista follows for each coffee type
+// the inventory is where the ingredients reserved by the cashier are consumed from
 // the ordersWg is used to wait for all orders to be processed
-func NewBarista(id int, grinderPool chan *grinder.Grinder, brewerPool chan *brewer.Brewer, ordersWg *sync.WaitGroup, eventSystem monitor.EventSystemer) *Barista {
+func NewBarista(id int, pools map[recipe.Equipment]equipment.Acquirer, equipmentTimeout time.Duration, coldBrew *coldbrew.Stock, extras []config.ExtraSettings, recipes map[string]recipe.Recipe, inventory inventory.Inventoryer, ordersWg *sync.WaitGroup, eventSystem monitor.EventSystemer) *Barista {
+	extrasByName := make(map[string]config.ExtraSettings, len(extras))
+	for _, extra := range extras {
+		extrasByName[extra.Name] = extra
+	}
 	return &Barista{
-		ID:          id,
-		grinderPool: grinderPool,
-		brewerPool:  brewerPool,
-		available:   make(chan struct{}, 1),
-		ordersWg:    ordersWg,
-		eventSystem: eventSystem,
+		ID:               id,
+		pools:            pools,
+		equipmentTimeout: equipmentTimeout,
+		coldBrew:         coldBrew,
+		extras:           extrasByName,
+		recipes:          recipes,
+		inventory:        inventory,
+		available:        make(chan struct{}, 1),
+		ordersWg:         ordersWg,
+		eventSystem:      eventSystem,
 	}
 }
 
@@ -51,45 +79,423 @@ func (b *Barista) MarkBusy() {
 }
 
 // ProcessOrder processes an order
-// It gets an available grinder and brewer from the pool, processes the order, and returns the grinder and brewer to the pool
-// The processing workflow is
-// 1. Get an available grinder from the pool
-// 2. Grind coffee
-// 3. Return the grinder to the pool
-// 4. Get an available brewer from the pool
-// 5. Brew coffee
-// 6. Return the brewer to the pool
-// 7. Complete order and notify the customer
+// It follows the recipe of the coffee type step by step, the default recipe is
+// 1. Get an available grinder that handles the beans of the coffee from the pool, grind the beans and return the grinder
+// 2. Get an available brewer from the pool, brew the coffee and return the brewer
+// 3. If the coffee has milk and is not iced, get an available steamer from the pool, steam the milk and return the steamer
+// 4. If the coffee is iced, get an available ice dispenser from the pool, dispense the ice and return the ice dispenser
+// 5. Add the condiments, like sugar, at the condiment station
+// 6. Pour the coffee in a cup, complete order and notify the customer
+// A cold brew is drawn from the cold brew stock instead of steps 1 and 2,
+// and the barista prepares a batch of cold brew when the stock is short or falls to its replenish point
+// Each step on equipment gets a unit of its class from its pool, keeps it for the duration of the step and returns it,
+// so a new kind of equipment only needs a pool
+// The steps performed together, like a shot and the steaming of its milk, reserve their units at once and keep them until the last is done
+// The units due for a cleaning are taken out of their pool and cleaned by the barista as soon as they are released, before the next group of steps
+// If a unit breaks down and throws the coffee away, the barista starts over from the first step whose output was lost, see recipe.Restart,
+// and what was thrown away is charged to the inventory as waste
+// If the equipment of a group of steps cannot be reserved, the order is cancelled and the items reserved for it go back to the inventory
+// The beans, extras and cup reserved by the cashier are consumed from the inventory as they are used
+// An event is sent to the monitor at each step, so the time spent by the order at each stage can be measured
+// The steps are child spans of the processing span, the coffee carries their span context to the equipment
 func (b *Barista) ProcessOrder(order *types.Order) {
-	logger := utils.Logger().WithFields(utils.LogFields{
-		"barista":  b.ID,
-		"customer": order.Customer().Name(),
-	})
+	logger := order.Logger().WithField("barista", b.ID)
+	tracer := tracing.GlobalTracer()
+	span := tracer.StartSpan(order.SpanContext(), "process order")
+	span.SetAttribute("barista", b.ID)
 
 	// send event to monitor
-	b.eventSystem.SendEvent(monitor.Event{Type: monitor.OrderProcessed, Data: order})
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.BaristaAssignedPayload{Barista: b.ID, Order: monitor.NewOrderSnapshot(order)}))
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.OrderProcessedPayload{Barista: b.ID, Order: monitor.NewOrderSnapshot(order)}))
 
 	logger.Info("Barista is processing order")
-	// Get an available grinder from the pool
-	grinder := <-b.grinderPool
-	// Grind coffee
-	grinder.Grind(order.Coffee())
-	<-order.Coffee().BeansReady()
-	// Return the grinder to the pool
-	b.grinderPool <- grinder
-
-	// Get an available brewer from the pool
-	brewer := <-b.brewerPool
-	// Brew coffee
-	brewer.Brew(order.Coffee())
-	<-order.Coffee().WaterReady()
-	// Return the brewer to the pool
-	b.brewerPool <- brewer
-
-	// Complete order and notify the customer
+	steps := b.steps(order)
+	for next := 0; next < len(steps); {
+		group := steps[next:].Groups()[0]
+		performed, err := b.performSteps(order, group, span)
+		if err != nil {
+			b.cancel(order, err, span)
+			return
+		}
+		if performed == len(group) {
+			next += performed
+			continue
+		}
+		aborted := next + performed
+		b.waste(order, steps[aborted])
+		next = steps.Restart(aborted)
+	}
+
+	// Pour the coffee in a cup, complete order and notify the customer
+	// the beans are consumed here if the recipe neither grinds them nor draws cold brew
+	b.inventory.Consume(order, inventory.Beans)
+	for _, extra := range order.Coffee().Extras() {
+		b.inventory.Consume(order, extra)
+	}
+	b.inventory.Consume(order, inventory.Cups)
+	span.End()
 	order.Complete()
-	b.ordersWg.Done()
 	// send event to monitor
-	b.eventSystem.SendEvent(monitor.Event{Type: monitor.OrderCompleted, Data: order})
+	// the events are sent before the order is marked as done, so they are not lost when the event system is stopped
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.OrderCompletedPayload{Barista: b.ID, Order: monitor.NewOrderSnapshot(order)}))
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.OrderPickedUpPayload{Barista: b.ID, Order: monitor.NewOrderSnapshot(order)}))
+	// Replenish the cold brew once the customer has the coffee, before the shop can close
+	if b.coldBrew != nil && b.coldBrew.StartBatch() {
+		b.prepareColdBrew(tracing.SpanContext{})
+	}
+	b.ordersWg.Done()
 	logger.Info("Barista is done processing order")
 }
+
+// cancel gives up the order, the items reserved for it go back to the inventory and the customer leaves without a coffee
+func (b *Barista) cancel(order *types.Order, err error, span tracing.Span) {
+	b.inventory.Release(order)
+	span.SetAttribute("cancelled", err.Error())
+	span.End()
+	order.Cancel(err.Error())
+	// the event is sent before the order is marked as done, so it is not lost when the event system is stopped
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.OrderCancelledPayload{Barista: b.ID, Reason: err.Error(), Order: monitor.NewOrderSnapshot(order)}))
+	b.ordersWg.Done()
+}
+
+// recipe returns the recipe of the coffee type
+func (b *Barista) recipe(coffeeType types.CoffeeType) recipe.Recipe {
+	if steps, ok := b.recipes[coffeeType.Name]; ok {
+		return steps
+	}
+	return recipe.Default(coffeeType.Iced, coffeeType.ColdBrew)
+}
+
+// steps returns the steps of the recipe of the coffee type the order needs, see needed
+// The first step needed of each group starts a group, even if it was performed together with a step skipped before it
+func (b *Barista) steps(order *types.Order) recipe.Recipe {
+	var steps recipe.Recipe
+	for _, group := range b.recipe(order.Coffee().CoffeeType()).Groups() {
+		needed := b.needed(order, group)
+		if len(needed) > 0 {
+			needed[0].Together = false
+		}
+		steps = append(steps, needed...)
+	}
+	return steps
+}
+
+// performSteps performs a group of steps of the recipe of the order, see recipe.Groups
+// The equipment of the group is reserved at once and held until its last step is done, or until a unit breaks down and throws the coffee away
+// The units due for a cleaning are cleaned once they are released, so the next group, or the steps started over,
+// can use them again even if the pool has no other unit
+// It returns the number of steps performed before the one aborted, all of them if none was,
+// or the error of the reservation if the equipment cannot be reserved, then no step is performed
+func (b *Barista) performSteps(order *types.Order, steps recipe.Recipe, span tracing.Span) (int, error) {
+	units, err := b.reserve(order, steps)
+	if err != nil {
+		return 0, err
+	}
+	performed := b.performReserved(order, steps, units, span)
+	b.clean(b.release(order, units), span)
+	return performed, nil
+}
+
+// waste charges what the aborted step threw away with the coffee to the inventory
+// A grind, a brew or a shot throws the beans away, a steam the steamed extras, like the milk
+func (b *Barista) waste(order *types.Order, step recipe.Step) {
+	switch step.Action {
+	case recipe.Grind, recipe.Brew, recipe.Shot:
+		b.inventory.Waste(order, inventory.Beans)
+	case recipe.Steam:
+		var wasted []string
+		for _, extra := range order.Coffee().Extras() {
+			if b.extras[extra].Steamed && !contains(wasted, extra) {
+				wasted = append(wasted, extra)
+				b.inventory.Waste(order, extra)
+			}
+		}
+	}
+}
+
+// performReserved performs the steps on the units reserved for them and returns the number of steps performed
+// It stops at the step whose unit broke down and threw the coffee away
+func (b *Barista) performReserved(order *types.Order, steps recipe.Recipe, units reservations, span tracing.Span) int {
+	for i, step := range steps {
+		switch {
+		case step.Action == recipe.ColdBrew:
+			b.drawColdBrew(order, span)
+		case step.Action == recipe.Condiments:
+			b.addCondiments(order, step, span)
+		case step.Equipment == recipe.None:
+			b.performByHand(order, step, span)
+		default:
+			if !b.useEquipment(order, step, units.of(step.Equipment), span) {
+				return i
+			}
+		}
+	}
+	return len(steps)
+}
+
+// needed returns the steps of the group the order needs
+// A steam step is skipped if the coffee has no steamed extra or no milk, an ice step if it has no ice,
+// and a step on equipment the shop has no unit of is skipped
+func (b *Barista) needed(order *types.Order, steps recipe.Recipe) recipe.Recipe {
+	coffee := order.Coffee()
+	needed := make(recipe.Recipe, 0, len(steps))
+	for _, step := range steps {
+		if step.Action == recipe.Steam && (!b.hasSteamedExtra(coffee) || !coffee.MilkNeeded().IsPositive()) {
+			continue
+		}
+		if step.Action == recipe.Ice && !coffee.IceNeeded().IsPositive() {
+			continue
+		}
+		if pool, ok := b.pools[step.Equipment]; step.Equipment != recipe.None && (!ok || pool.Capacity() == 0) {
+			order.Logger().WithFields(utils.LogFields{"barista": b.ID, "step": step.String()}).Info("Recipe step skipped, the shop has no such equipment")
+			continue
+		}
+		needed = append(needed, step)
+	}
+	return needed
+}
+
+// reservation is a unit reserved for a group of steps and the pool it was taken from
+type reservation struct {
+	class recipe.Equipment
+	pool  equipment.Acquirer
+	unit  equipment.Equipment
+}
+
+// reservations are the units reserved for a group of steps, one per class of equipment
+type reservations []reservation
+
+// of returns the unit reserved for the class of equipment
+func (r reservations) of(class recipe.Equipment) reservation {
+	for _, reserved := range r {
+		if reserved.class == class {
+			return reserved
+		}
+	}
+	return reservation{}
+}
+
+// reserve reserves a unit that accepts the coffee of each class of equipment the steps need at once, see equipment.Reserve
+// Each wait longer than the equipment timeout is reported with the classes that had no unit,
+// while the barista keeps its place in the lines, so an order is never stuck unnoticed
+// An EquipmentAcquired event is sent for each unit, whatever its class, along with the event of the unit if it reports its use
+// It returns the error of the reservation if the units cannot be reserved
+func (b *Barista) reserve(order *types.Order, steps recipe.Recipe) (reservations, error) {
+	var classes []string
+	var pools []equipment.Acquirer
+	for _, step := range steps {
+		if step.Equipment == recipe.None || contains(classes, string(step.Equipment)) {
+			continue
+		}
+		classes = append(classes, string(step.Equipment))
+		pools = append(pools, b.pools[step.Equipment])
+	}
+	if len(pools) == 0 {
+		return nil, nil
+	}
+
+	waitStart := time.Now()
+	units, err := equipment.ReservePatiently(context.Background(), order.Coffee(), b.equipmentTimeout, func(waiting []int, waitTime time.Duration) {
+		missing := make([]string, 0, len(waiting))
+		for _, i := range waiting {
+			missing = append(missing, classes[i])
+		}
+		b.report(&monitor.EquipmentTimedOutPayload{OrderRef: monitor.NewOrderRef(order), Barista: b.ID, Equipment: missing, WaitTime: waitTime})
+		order.Logger().WithFields(utils.LogFields{"barista": b.ID, "equipment": missing, "waitTime": waitTime}).Info("Barista timed out waiting for equipment, waiting again")
+	}, pools...)
+	if err != nil {
+		order.Logger().WithError(err).WithFields(utils.LogFields{"barista": b.ID, "equipment": classes}).Error("Order cancelled, the equipment cannot be reserved")
+		return nil, err
+	}
+	waitTime := time.Since(waitStart)
+	reserved := make(reservations, 0, len(units))
+	for i, unit := range units {
+		reserved = append(reserved, reservation{class: recipe.Equipment(classes[i]), pool: pools[i], unit: unit})
+		b.report(&monitor.EquipmentAcquiredPayload{OrderRef: monitor.NewOrderRef(order), Barista: b.ID, Kind: monitor.ResourceKind(classes[i]), Equipment: unit.Tag(), WaitTime: waitTime})
+		if reporter, ok := unit.(equipment.Reporter); ok {
+			b.report(reporter.Acquired(order, b.ID, waitTime))
+		}
+	}
+	return reserved, nil
+}
+
+// contains returns whether the name, like a class of equipment or an extra, is one of the names
+func contains(names []string, name string) bool {
+	for _, other := range names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
+
+// release returns the units reserved for a group of steps to their pools
+// A unit due for a cleaning is withdrawn first, so it stays out of its pool until the barista cleans it, and it is returned
+func (b *Barista) release(order *types.Order, units reservations) reservations {
+	var dirty reservations
+	for _, reserved := range units {
+		if cleanable, ok := reserved.unit.(equipment.Cleanable); ok && cleanable.Cleaning().Due() {
+			cleanable.Cleaning().Withdraw()
+			dirty = append(dirty, reserved)
+		}
+		reserved.pool.ReleaseEquipment(reserved.unit)
+		if reporter, ok := reserved.unit.(equipment.Reporter); ok {
+			b.report(reporter.Released(order, b.ID))
+		}
+	}
+	return dirty
+}
+
+// clean cleans the units due for a cleaning, each is back in its pool at its full rate once it is cleaned
+// The cleanings are child spans of the processing span, the order waits for them
+func (b *Barista) clean(dirty reservations, span tracing.Span) {
+	for _, reserved := range dirty {
+		cleanSpan := tracing.GlobalTracer().StartSpan(span.Context(), "clean equipment")
+		cleanSpan.SetAttribute(reserved.pool.Kind(), reserved.unit.Tag())
+		reserved.unit.(equipment.Cleanable).Cleaning().Clean(b.ID, reserved.unit.Rate())
+		cleanSpan.End()
+	}
+}
+
+// stepTime returns the time the step takes for the order, rate is the rate of its equipment, zero for a step by hand
+// A formula that cannot be evaluated, like a division by a rate of zero, takes no time
+func (b *Barista) stepTime(order *types.Order, step recipe.Step, rate int) time.Duration {
+	coffee := order.Coffee()
+	condimentTime, _ := b.condiments(coffee)
+	variables := recipe.Variables{
+		recipe.SizeVariable:       float64(coffee.Size()),
+		recipe.BeansVariable:      coffee.BeansNeeded().InexactFloat64(),
+		recipe.WaterVariable:      coffee.WaterNeeded().InexactFloat64(),
+		recipe.MilkVariable:       coffee.MilkNeeded().InexactFloat64(),
+		recipe.IceVariable:        coffee.IceNeeded().InexactFloat64(),
+		recipe.CondimentsVariable: condimentTime.Seconds(),
+	}
+	if step.Equipment != recipe.None {
+		variables[recipe.RateVariable] = float64(rate)
+	}
+	duration, err := step.Time(variables)
+	if err != nil {
+		order.Logger().WithError(err).WithFields(utils.LogFields{"barista": b.ID, "step": step.String()}).Info("Recipe step takes no time")
+	}
+	return duration
+}
+
+// performByHand performs a step that needs no equipment, like pouring the coffee
+func (b *Barista) performByHand(order *types.Order, step recipe.Step, span tracing.Span) {
+	duration := b.stepTime(order, step, 0)
+	stepSpan := tracing.GlobalTracer().StartSpan(span.Context(), string(step.Action))
+	time.Sleep(duration)
+	stepSpan.End()
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.StepPerformedPayload{OrderRef: monitor.NewOrderRef(order), Barista: b.ID, Action: string(step.Action), Duration: duration}))
+}
+
+// useEquipment performs the step on the unit reserved for it
+// The events are those of the equipment if it reports its use, a StepPerformed event otherwise
+// A grind step consumes the beans reserved for the order
+// It returns false if the unit broke down and threw the coffee away, the coffee must be prepared again
+func (b *Barista) useEquipment(order *types.Order, step recipe.Step, reserved reservation, span tracing.Span) bool {
+	if reserved.unit == nil {
+		return true
+	}
+	coffee := order.Coffee()
+	unit := reserved.unit
+	reporter, reports := unit.(equipment.Reporter)
+	stepSpan := tracing.GlobalTracer().StartSpan(span.Context(), string(step.Action))
+	stepSpan.SetAttribute(reserved.pool.Kind(), unit.Tag())
+	coffee.SetSpanContext(stepSpan.Context())
+	duration := b.stepTime(order, step, unit.Rate())
+	if reports {
+		b.report(reporter.Started(order, b.ID))
+	}
+	if err := unit.Process(coffee, duration); err != nil {
+		stepSpan.SetAttribute("aborted", err.Error())
+		stepSpan.End()
+		b.report(&monitor.StepAbortedPayload{OrderRef: monitor.NewOrderRef(order), Barista: b.ID, Action: string(step.Action), Equipment: unit.Tag()})
+		order.Logger().WithError(err).WithFields(utils.LogFields{"barista": b.ID, "step": step.String(), reserved.pool.Kind(): unit.Tag()}).Info("Recipe step aborted, the coffee is prepared again")
+		return false
+	}
+	stepSpan.End()
+	if step.Action == recipe.Grind {
+		b.inventory.Consume(order, inventory.Beans)
+	}
+	if reports {
+		b.report(reporter.Finished(order, b.ID))
+	} else {
+		b.report(&monitor.StepPerformedPayload{OrderRef: monitor.NewOrderRef(order), Barista: b.ID, Action: string(step.Action), Equipment: unit.Tag(), Duration: duration})
+	}
+	return true
+}
+
+// report sends the event of the payload to the monitor, if there is one
+func (b *Barista) report(payload monitor.Payload) {
+	if payload != nil {
+		b.eventSystem.SendEvent(monitor.NewEvent(payload))
+	}
+}
+
+// drawColdBrew draws the cold brew of the order from the stock instead of grinding and brewing it
+// When the stock is short and no other barista is preparing a batch, the barista prepares one first
+// The beans reserved for the order are consumed with the cold brew, they were used by the batch it is drawn from
+func (b *Barista) drawColdBrew(order *types.Order, span tracing.Span) {
+	ounces := order.Coffee().WaterNeeded()
+	waitStart := time.Now()
+	stock, ok := b.coldBrew.Draw(ounces)
+	for !ok {
+		b.prepareColdBrew(span.Context())
+		stock, ok = b.coldBrew.Draw(ounces)
+	}
+	b.inventory.Consume(order, inventory.Beans)
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.ColdBrewDrawnPayload{OrderRef: monitor.NewOrderRef(order), Barista: b.ID, Ounces: ounces, Stock: stock, WaitTime: time.Since(waitStart)}))
+}
+
+// prepareColdBrew prepares a batch of cold brew and adds it to the stock
+// The span is a child of the parent, or the root of a new trace when the batch is not prepared for an order
+func (b *Barista) prepareColdBrew(parent tracing.SpanContext) {
+	span := tracing.GlobalTracer().StartSpan(parent, "prepare cold brew")
+	span.SetAttribute("barista", b.ID)
+	start := time.Now()
+	stock := b.coldBrew.PrepareBatch()
+	span.End()
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.ColdBrewReplenishedPayload{Barista: b.ID, Batch: b.coldBrew.BatchOunces(), Stock: stock, PrepareTime: time.Since(start)}))
+	utils.Logger().WithFields(parent.LogFields()).WithFields(utils.LogFields{"barista": b.ID, "stock": stock}).Info("Barista prepared a batch of cold brew")
+}
+
+// hasSteamedExtra returns whether one of the extras of the coffee is steamed
+func (b *Barista) hasSteamedExtra(coffee *types.Coffee) bool {
+	for _, extra := range coffee.Extras() {
+		if b.extras[extra].Steamed {
+			return true
+		}
+	}
+	return false
+}
+
+// condiments returns the extras of the coffee that are not steamed and the seconds configured to add them
+func (b *Barista) condiments(coffee *types.Coffee) (time.Duration, []string) {
+	var condiments []string
+	var condimentTime time.Duration
+	for _, extra := range coffee.Extras() {
+		settings := b.extras[extra]
+		if settings.Steamed || settings.Seconds <= 0 {
+			continue
+		}
+		condiments = append(condiments, extra)
+		condimentTime += time.Duration(settings.Seconds * float64(time.Second))
+	}
+	return condimentTime, condiments
+}
+
+// addCondiments adds the extras that are not steamed at the condiment station, by default each takes the seconds configured for it
+func (b *Barista) addCondiments(order *types.Order, step recipe.Step, span tracing.Span) {
+	_, condiments := b.condiments(order.Coffee())
+	if len(condiments) == 0 {
+		return
+	}
+	condimentTime := b.stepTime(order, step, 0)
+	condimentSpan := tracing.GlobalTracer().StartSpan(span.Context(), string(step.Action))
+	condimentSpan.SetAttribute("condiments", condiments)
+	time.Sleep(condimentTime)
+	condimentSpan.End()
+	order.Coffee().SetCondimentTime(condimentTime)
+	b.eventSystem.SendEvent(monitor.NewEvent(&monitor.CondimentsAddedPayload{OrderRef: monitor.NewOrderRef(order), Barista: b.ID, Condiments: condiments, CondimentTime: condimentTime}))
+}
@@ -1,13 +1,17 @@
 package barista
 
 import (
+	"sync/atomic"
+
 	"github.com/s3ndd/coffeeshop/internal/types"
 )
 
 // BaristaPool represents a pool of baristas
+// busy is the number of baristas currently processing an order
 type BaristaPool struct {
 	orderQueue types.OrderQueueer
 	baristas   []Baristaer
+	busy       atomic.Int64
 }
 
 // NewBaristaPool creates a new barista pool
@@ -27,10 +31,17 @@ func (bp *BaristaPool) Start() {
 			for order := range bp.orderQueue.Subscribe() {
 				// mark the barista as busy
 				b.MarkBusy()
+				bp.busy.Add(1)
 				b.ProcessOrder(order)
 				// mark the barista as available again
+				bp.busy.Add(-1)
 				b.MarkAvailable()
 			}
 		}(barista)
 	}
 }
+
+// Busy returns the number of baristas currently processing an order
+func (bp *BaristaPool) Busy() int {
+	return int(bp.busy.Load())
+}
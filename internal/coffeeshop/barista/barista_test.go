@@ -3,17 +3,101 @@ package barista
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/s3ndd/coffeeshop/internal/coffeeshop/brewer"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/coldbrew"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
 	"github.com/s3ndd/coffeeshop/internal/coffeeshop/grinder"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/icedispenser"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/inventory"
 	"github.com/s3ndd/coffeeshop/internal/coffeeshop/mocks"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/steamer"
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/recipe"
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// eventRecorder collects the events sent while the orders are processed
+type eventRecorder struct {
+	events []monitor.Event
+	mutex  sync.Mutex
+}
+
+func (r *eventRecorder) SendEvent(event monitor.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+}
+
+// payloads returns the payloads of the events of the given types, in the order they were sent
+func (r *eventRecorder) payloads(eventTypes ...monitor.EventType) []monitor.Payload {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var payloads []monitor.Payload
+	for _, event := range r.events {
+		for _, eventType := range eventTypes {
+			if event.Type == eventType {
+				payloads = append(payloads, event.Payload)
+			}
+		}
+	}
+	return payloads
+}
+
+// types returns the types of the events sent of the given types, in order
+func (r *eventRecorder) types(only ...monitor.EventType) []monitor.EventType {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var eventTypes []monitor.EventType
+	for _, event := range r.events {
+		for _, eventType := range only {
+			if event.Type == eventType {
+				eventTypes = append(eventTypes, event.Type)
+			}
+		}
+	}
+	return eventTypes
+}
+
+// start starts the units of the pool and stops them at the end of the test
+func start(t *testing.T, pool interface {
+	Start()
+	Stop()
+}) {
+	pool.Start()
+	t.Cleanup(pool.Stop)
+}
+
+// newOrder creates an order of the coffee type for a new customer
+func newOrder(coffeeType types.CoffeeType, extras ...string) *types.Order {
+	return types.NewOrder(types.NewCustomer("Shelly Shi", mocks.CreateMockConfig()), coffeeType, types.Standard, extras)
+}
+
+// process has the barista process the order, the test fails if it is not done within a few seconds
+func process(t *testing.T, barista *Barista, order *types.Order) {
+	barista.ordersWg.Add(1)
+	done := make(chan struct{})
+	go func() {
+		barista.ProcessOrder(order)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("The order is still being processed")
+	}
+}
+
 func TestBaristaProcessOrder(t *testing.T) {
 	// create mock objects for the grinder and brewer pools
-	grinderPool := make(chan *grinder.Grinder, 1)
-	brewerPool := make(chan *brewer.Brewer, 1)
+	grinderPool := grinder.NewGrinderPool(1)
+	brewerPool := brewer.NewBrewerPool(1)
 
 	mockGrinderPool := new(mocks.MockGrinderPool)
 	mockGrinderPool.On("GrinderPool").Return(grinderPool)
@@ -25,7 +109,13 @@ func TestBaristaProcessOrder(t *testing.T) {
 	eventSystem := mocks.NewMockEventSystem()
 
 	// create a barista with the mock objects
-	barista := NewBarista(1, mockGrinderPool.GrinderPool(), mockBrewerPool.BrewerPool(), &sync.WaitGroup{}, eventSystem)
+	pools := map[recipe.Equipment]equipment.Acquirer{
+		recipe.Grinder:      mockGrinderPool.GrinderPool(),
+		recipe.Brewer:       mockBrewerPool.BrewerPool(),
+		recipe.Steamer:      steamer.NewSteamerPool(1),
+		recipe.IceDispenser: icedispenser.NewIceDispenserPool(1),
+	}
+	barista := NewBarista(1, pools, 0, nil, nil, nil, inventory.NewInventory(nil, eventSystem), &sync.WaitGroup{}, eventSystem)
 
 	// test MarkAvailable and MarkBusy
 	barista.MarkAvailable()
@@ -35,3 +125,234 @@ func TestBaristaProcessOrder(t *testing.T) {
 	barista.MarkAvailable()
 	assert.Equal(t, len(barista.available), 1)
 }
+
+func TestBaristaCleansTheEquipmentBeforeTheNextSteps(t *testing.T) {
+	events := &eventRecorder{}
+	unit := grinder.NewGrinder("grinder1", 100)
+	unit.SetCleaning(equipment.NewCleaning("grinder", "grinder1", config.CleaningSettings{EveryUses: 1, DurationSeconds: 0.01}, events))
+	grinders := grinder.NewGrinderPool(1)
+	grinders.Add(unit)
+	start(t, grinders)
+
+	// the beans are ground twice, the single grinder is due for a cleaning after each grind
+	recipes := map[string]recipe.Recipe{"Turkish": {
+		recipe.MustStep(string(recipe.Grind), "", "0.01"),
+		recipe.MustStep(string(recipe.Grind), "", "0.01"),
+		recipe.MustStep(string(recipe.Pour), "", "0"),
+	}}
+	pools := map[recipe.Equipment]equipment.Acquirer{recipe.Grinder: grinders}
+	barista := NewBarista(1, pools, 0, nil, nil, recipes, inventory.NewInventory(nil, events), &sync.WaitGroup{}, events)
+
+	process(t, barista, newOrder(types.CoffeeType{Name: "Turkish", BeansToWaterRatio: utils.FloatToDecimal(0.1), SizeInOunces: 4}))
+	assert.Len(t, events.payloads(monitor.EquipmentCleaned), 2, "The grinder is cleaned before the second grind and after it")
+	assert.Len(t, events.payloads(monitor.GrindFinished), 2)
+	assert.Eventually(t, func() bool {
+		return grinders.Available() == 1
+	}, time.Second, time.Millisecond, "The grinder is back in its pool")
+	assert.Zero(t, unit.Cleaning().Uses())
+}
+
+func TestBaristaStartsOverFromTheGrindWhenTheBrewerBreaksDown(t *testing.T) {
+	events := &eventRecorder{}
+	grinders, brewers := grinder.NewGrinderPool(1), brewer.NewBrewerPool(2)
+	grinders.Add(grinder.NewGrinder("grinder1", 100))
+	// the first brewer breaks down during the brew and is repaired long after the order is done
+	unreliable := brewer.NewBrewer("brewer1", 10)
+	unreliable.SetReliability(equipment.NewReliability("brewer", "brewer1", config.ReliabilitySettings{
+		MTBF:      config.DistributionSettings{MeanSeconds: 0.01},
+		MTTR:      config.DistributionSettings{MeanSeconds: 10},
+		OnFailure: config.AbortOnFailure,
+	}, events))
+	brewers.Add(unreliable)
+	brewers.Add(brewer.NewBrewer("brewer2", 10))
+	start(t, grinders)
+	start(t, brewers)
+
+	recipes := map[string]recipe.Recipe{"Americano": {
+		recipe.MustStep(string(recipe.Grind), "", "0.01"),
+		recipe.MustStep(string(recipe.Brew), "", "0.05"),
+		recipe.MustStep(string(recipe.Pour), "", "0"),
+	}}
+	stock := inventory.NewInventory([]config.InventoryItemSettings{{Name: inventory.Beans, StartingStock: decimal.NewFromInt(100)}}, events)
+	pools := map[recipe.Equipment]equipment.Acquirer{recipe.Grinder: grinders, recipe.Brewer: brewers}
+	barista := NewBarista(1, pools, 0, nil, nil, recipes, stock, &sync.WaitGroup{}, events)
+
+	order := newOrder(types.CoffeeType{Name: "Americano", BeansToWaterRatio: utils.FloatToDecimal(0.1), SizeInOunces: 4})
+	require.NoError(t, stock.Reserve(order))
+	process(t, barista, order)
+
+	aborted := events.payloads(monitor.StepAborted)
+	require.Len(t, aborted, 1)
+	assert.Equal(t, "brewer1", aborted[0].(*monitor.StepAbortedPayload).Equipment)
+	assert.Len(t, events.payloads(monitor.GrindFinished), 2, "The beans thrown away with the coffee are ground again")
+	brewed := events.payloads(monitor.BrewFinished)
+	require.Len(t, brewed, 1)
+	assert.Equal(t, "brewer2", brewed[0].(*monitor.BrewFinishedPayload).Brewer)
+
+	beans, _ := stock.Stock(inventory.Beans)
+	assert.True(t, decimal.NewFromInt(100).Sub(order.Coffee().BeansNeeded().Mul(decimal.NewFromInt(2))).Equal(beans),
+		"The beans thrown away are charged as waste, %s left", beans)
+	consumed := events.payloads(monitor.IngredientConsumed)
+	require.Len(t, consumed, 2)
+	assert.True(t, consumed[0].(*monitor.IngredientConsumedPayload).Amount.Equal(order.Coffee().BeansNeeded()))
+	assert.True(t, consumed[1].(*monitor.IngredientConsumedPayload).Waste.Equal(order.Coffee().BeansNeeded()))
+}
+
+func TestBaristaProcessOrderFollowsTheDefaultRecipe(t *testing.T) {
+	// the equipment is fast enough for the default steps to take no time
+	grinders, brewers := grinder.NewGrinderPool(1), brewer.NewBrewerPool(1)
+	steamers, iceDispensers := steamer.NewSteamerPool(1), icedispenser.NewIceDispenserPool(1)
+	grinders.Add(grinder.NewGrinder("grinder1", 1000))
+	brewers.Add(brewer.NewBrewer("brewer1", 1000))
+	steamers.Add(steamer.NewSteamer("steamer1", 1000))
+	iceDispensers.Add(icedispenser.NewIceDispenser("iceDispenser1", 1000))
+	start(t, grinders)
+	start(t, brewers)
+	start(t, steamers)
+	start(t, iceDispensers)
+	pools := map[recipe.Equipment]equipment.Acquirer{
+		recipe.Grinder:      grinders,
+		recipe.Brewer:       brewers,
+		recipe.Steamer:      steamers,
+		recipe.IceDispenser: iceDispensers,
+	}
+	extras := []config.ExtraSettings{{Name: "milk", Steamed: true}, {Name: "sugar", Seconds: 0.01}}
+
+	latte := types.CoffeeType{Name: "Latte", BeansToWaterRatio: utils.FloatToDecimal(0.1), SizeInOunces: 8, MilkInOunces: utils.FloatToDecimal(4)}
+	icedLatte := latte
+	icedLatte.Name, icedLatte.Iced, icedLatte.IceInOunces = "Iced Latte", true, utils.FloatToDecimal(4)
+	tests := []struct {
+		name   string
+		order  *types.Order
+		stages []monitor.EventType
+	}{
+		{"without a steamed extra the milk is not steamed", newOrder(latte, "sugar"),
+			[]monitor.EventType{monitor.GrindFinished, monitor.BrewFinished, monitor.CondimentsAdded, monitor.StepPerformed, monitor.OrderCompleted}},
+		{"the milk is steamed after the brew", newOrder(latte, "milk"),
+			[]monitor.EventType{monitor.GrindFinished, monitor.BrewFinished, monitor.SteamFinished, monitor.StepPerformed, monitor.OrderCompleted}},
+		{"an iced coffee is poured over ice instead", newOrder(icedLatte, "milk"),
+			[]monitor.EventType{monitor.GrindFinished, monitor.BrewFinished, monitor.IceDispensed, monitor.StepPerformed, monitor.OrderCompleted}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events := &eventRecorder{}
+			barista := NewBarista(1, pools, 0, nil, extras, nil, inventory.NewInventory(nil, events), &sync.WaitGroup{}, events)
+			process(t, barista, test.order)
+			assert.Equal(t, test.stages, events.types(monitor.GrindFinished, monitor.BrewFinished, monitor.SteamFinished, monitor.IceDispensed,
+				monitor.CondimentsAdded, monitor.StepPerformed, monitor.OrderCompleted))
+			assert.NotNil(t, test.order.ServedTime())
+		})
+	}
+}
+
+func TestBaristaReservesTheStepsPerformedTogether(t *testing.T) {
+	events := &eventRecorder{}
+	brewers, steamers := brewer.NewBrewerPool(1), steamer.NewSteamerPool(1)
+	brewers.Add(brewer.NewBrewer("brewer1", 10))
+	steamers.Add(steamer.NewSteamer("steamer1", 10))
+	start(t, brewers)
+	start(t, steamers)
+
+	shot := recipe.MustStep(string(recipe.Shot), "", "0.01")
+	shot.Together = true
+	steam := recipe.MustStep(string(recipe.Steam), "", "0.01")
+	steam.Together = true
+	recipes := map[string]recipe.Recipe{"Cortado": {recipe.MustStep(string(recipe.Brew), "", "0.01"), shot, steam}}
+	pools := map[recipe.Equipment]equipment.Acquirer{recipe.Brewer: brewers, recipe.Steamer: steamers}
+	extras := []config.ExtraSettings{{Name: "milk", Steamed: true}}
+	barista := NewBarista(1, pools, 0, nil, extras, recipes, inventory.NewInventory(nil, events), &sync.WaitGroup{}, events)
+
+	held := steamers.Acquire(nil)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, 1, brewers.Available(), "The brewer is not held while the steamer is busy")
+		steamers.Release(held)
+	}()
+	process(t, barista, newOrder(types.CoffeeType{Name: "Cortado", BeansToWaterRatio: utils.FloatToDecimal(0.1), SizeInOunces: 4, MilkInOunces: utils.FloatToDecimal(2)}, "milk"))
+
+	assert.Equal(t, []monitor.EventType{monitor.BrewFinished, monitor.BrewFinished, monitor.SteamFinished, monitor.BrewerReleased, monitor.SteamerReleased},
+		events.types(monitor.BrewFinished, monitor.SteamFinished, monitor.BrewerReleased, monitor.SteamerReleased),
+		"The brewer is held until the milk of the shot is steamed")
+	acquired := events.payloads(monitor.EquipmentAcquired)
+	require.Len(t, acquired, 2)
+	brewerAcquired, steamerAcquired := acquired[0].(*monitor.EquipmentAcquiredPayload), acquired[1].(*monitor.EquipmentAcquiredPayload)
+	assert.Equal(t, monitor.BrewerResource, brewerAcquired.Kind)
+	assert.Equal(t, monitor.SteamerResource, steamerAcquired.Kind)
+	assert.Equal(t, brewerAcquired.WaitTime, steamerAcquired.WaitTime, "The brewer and the steamer are reserved at once")
+	assert.GreaterOrEqual(t, brewerAcquired.WaitTime, 20*time.Millisecond)
+}
+
+func TestBaristaDrawsColdBrewFromTheStock(t *testing.T) {
+	events := &eventRecorder{}
+	iceDispensers := icedispenser.NewIceDispenserPool(1)
+	iceDispensers.Add(icedispenser.NewIceDispenser("iceDispenser1", 1000))
+	start(t, iceDispensers)
+	pools := map[recipe.Equipment]equipment.Acquirer{recipe.IceDispenser: iceDispensers}
+	// the stock is empty, the barista prepares a batch before drawing the order
+	coldBrew := coldbrew.NewStock(config.ColdBrewSettings{BatchOunces: decimal.NewFromInt(20), BatchSeconds: 0.01})
+	stock := inventory.NewInventory([]config.InventoryItemSettings{{Name: inventory.Beans, StartingStock: decimal.NewFromInt(100)}}, events)
+	barista := NewBarista(1, pools, 0, coldBrew, nil, nil, stock, &sync.WaitGroup{}, events)
+
+	order := newOrder(types.CoffeeType{Name: "Cold Brew", BeansToWaterRatio: utils.FloatToDecimal(0.1), SizeInOunces: 8, Iced: true, IceInOunces: utils.FloatToDecimal(4), ColdBrew: true})
+	require.NoError(t, stock.Reserve(order))
+	process(t, barista, order)
+
+	assert.Equal(t, []monitor.EventType{monitor.ColdBrewReplenished, monitor.ColdBrewDrawn, monitor.IceDispensed, monitor.OrderCompleted},
+		events.types(monitor.ColdBrewReplenished, monitor.ColdBrewDrawn, monitor.GrindFinished, monitor.BrewFinished, monitor.IceDispensed, monitor.OrderCompleted),
+		"A cold brew is neither ground nor brewed")
+	drawn := events.payloads(monitor.ColdBrewDrawn)[0].(*monitor.ColdBrewDrawnPayload)
+	assert.True(t, order.Coffee().WaterNeeded().Equal(drawn.Ounces))
+	assert.True(t, decimal.NewFromInt(20).Sub(drawn.Ounces).Equal(coldBrew.Ounces()))
+	consumed := events.payloads(monitor.IngredientConsumed)
+	require.Len(t, consumed, 1, "The beans are consumed with the cold brew")
+	assert.True(t, order.Coffee().BeansNeeded().Equal(consumed[0].(*monitor.IngredientConsumedPayload).Amount))
+}
+
+func TestBaristaSkipsTheStepsOnEquipmentWithoutAUnit(t *testing.T) {
+	events := &eventRecorder{}
+	grinders, brewers := grinder.NewGrinderPool(1), brewer.NewBrewerPool(1)
+	grinders.Add(grinder.NewGrinder("grinder1", 1000))
+	brewers.Add(brewer.NewBrewer("brewer1", 1000))
+	start(t, grinders)
+	start(t, brewers)
+	// the shop has a pool of steamers, but no steamer in it
+	steamers := steamer.NewSteamerPool(1)
+	start(t, steamers)
+	pools := map[recipe.Equipment]equipment.Acquirer{recipe.Grinder: grinders, recipe.Brewer: brewers, recipe.Steamer: steamers}
+	extras := []config.ExtraSettings{{Name: "milk", Steamed: true}}
+	barista := NewBarista(1, pools, 0, nil, extras, nil, inventory.NewInventory(nil, events), &sync.WaitGroup{}, events)
+
+	process(t, barista, newOrder(types.CoffeeType{Name: "Latte", BeansToWaterRatio: utils.FloatToDecimal(0.1), SizeInOunces: 8, MilkInOunces: utils.FloatToDecimal(4)}, "milk"))
+
+	assert.Equal(t, []monitor.EventType{monitor.GrindFinished, monitor.BrewFinished, monitor.StepPerformed, monitor.OrderCompleted},
+		events.types(monitor.GrindFinished, monitor.BrewFinished, monitor.SteamFinished, monitor.StepPerformed, monitor.OrderCompleted))
+}
+
+func TestBaristaCancelsTheOrderWhenTheEquipmentCannotBeReserved(t *testing.T) {
+	events := &eventRecorder{}
+	brewers, otherBrewers := brewer.NewBrewerPool(1), brewer.NewBrewerPool(1)
+	brewers.Add(brewer.NewBrewer("brewer1", 1000))
+	otherBrewers.Add(brewer.NewBrewer("brewer2", 1000))
+	start(t, brewers)
+	start(t, otherBrewers)
+	// the steamers are configured with a pool of brewers, a reservation cannot take two units of the same kind
+	steam := recipe.MustStep(string(recipe.Steam), "", "0")
+	steam.Together = true
+	recipes := map[string]recipe.Recipe{"Latte": {recipe.MustStep(string(recipe.Brew), "", "0"), steam}}
+	pools := map[recipe.Equipment]equipment.Acquirer{recipe.Brewer: brewers, recipe.Steamer: otherBrewers}
+	extras := []config.ExtraSettings{{Name: "milk", Steamed: true}}
+	latte := types.CoffeeType{Name: "Latte", BeansToWaterRatio: utils.FloatToDecimal(0.1), SizeInOunces: 8, MilkInOunces: utils.FloatToDecimal(4)}
+	order := newOrder(latte, "milk")
+	// the stock has the beans of a single latte
+	stock := inventory.NewInventory([]config.InventoryItemSettings{{Name: inventory.Beans, StartingStock: order.Coffee().BeansNeeded()}}, events)
+	barista := NewBarista(1, pools, 0, nil, extras, recipes, stock, &sync.WaitGroup{}, events)
+	require.NoError(t, stock.Reserve(order))
+	require.Error(t, stock.Reserve(newOrder(latte, "milk")))
+	process(t, barista, order)
+
+	assert.Equal(t, []monitor.EventType{monitor.OrderCancelled}, events.types(monitor.BrewFinished, monitor.SteamFinished, monitor.OrderCompleted, monitor.OrderCancelled),
+		"The order is not completed without its steps")
+	assert.Nil(t, order.ServedTime())
+	assert.Empty(t, events.payloads(monitor.IngredientConsumed))
+	assert.NoError(t, stock.Reserve(newOrder(latte, "milk")), "The beans of the order cancelled can be reserved by the next one")
+}
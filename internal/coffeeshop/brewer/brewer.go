@@ -3,52 +3,76 @@ package brewer
 import (
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 	"github.com/shopspring/decimal"
 )
 
 // Brewer represents a coffee brewer
-// It has a brewing channel where it receives the coffee to brew
-// The brewing channel is unbuffered, so the brewer will block until the coffee is brewed
-// tag is the name of the brewer
+// Its worker brews one coffee at a time, the brewer blocks until the coffee is brewed
 // ouncesWaterPerSecond is the number of ounces of water that can be brewed per second
+// The brewer may break down and slow down with use, see equipment.Worker
 type Brewer struct {
-	tag                  string
+	*equipment.Worker
 	ouncesWaterPerSecond int
-	brewingChannel       chan *types.Coffee
 }
 
 // NewBrewer creates a new coffee brewer
 func NewBrewer(tag string, ouncesWaterPerSecond int) *Brewer {
-	return &Brewer{
-		tag:                  tag,
-		ouncesWaterPerSecond: ouncesWaterPerSecond,
-		brewingChannel:       make(chan *types.Coffee),
-	}
-}
-
-// Start starts the brewer
-func (b *Brewer) Start() {
-	logger := utils.Logger().WithField("brewer", b.tag)
-	go func() {
-		for coffee := range b.brewingChannel {
-			logger = logger.WithFields(utils.LogFields{
-				"coffee": coffee.CoffeeType().Name,
-				"size":   coffee.Size(),
-				"water":  coffee.WaterNeeded(),
-			})
-			logger.Info("Brewing coffee")
-			brewingTime := time.Duration(coffee.WaterNeeded().Div(decimal.NewFromInt(int64(b.ouncesWaterPerSecond))).IntPart()) * time.Second
-			time.Sleep(brewingTime)
-			coffee.SetBrewTime(brewingTime)
-			coffee.SetWaterReady(true)
-			logger.Info("Coffee is brewed")
-		}
-	}()
-}
-
-// Brew adds the coffee to the brewer's brewing channel
+	brewer := &Brewer{ouncesWaterPerSecond: ouncesWaterPerSecond}
+	brewer.Worker = equipment.NewWorker(tag, equipment.Work{
+		Kind:     "brewer",
+		Starting: "Brewing coffee",
+		Done:     "Coffee is brewed",
+		Aborted:  "Coffee is thrown away, the brewer broke down",
+		Fields: func(coffee *types.Coffee) utils.LogFields {
+			return utils.LogFields{"water": coffee.WaterNeeded(), "rate": brewer.EffectiveRate()}
+		},
+		Record:   (*types.Coffee).AddBrewTime,
+		SetReady: (*types.Coffee).SetWaterReady,
+		Ready:    (*types.Coffee).WaterReady,
+	})
+	return brewer
+}
+
+// Rate returns the ounces of water the brewer brews per second
+func (b *Brewer) Rate() int {
+	return b.ouncesWaterPerSecond
+}
+
+// EffectiveRate returns the ounces of water the brewer brews per second now, slowed down by its uses since it was cleaned
+func (b *Brewer) EffectiveRate() float64 {
+	return b.Cleaning().Rate(b.ouncesWaterPerSecond)
+}
+
+// Brew hands the coffee to the brewer, it takes the time to brew its water at the rate of the brewer
 func (b *Brewer) Brew(coffee *types.Coffee) {
-	b.brewingChannel <- coffee
+	b.BrewFor(coffee, time.Duration(coffee.WaterNeeded().Div(decimal.NewFromInt(int64(b.ouncesWaterPerSecond))).IntPart())*time.Second)
+}
+
+// BrewFor hands the coffee to the brewer, brewing it takes the given duration, like a step of a recipe
+func (b *Brewer) BrewFor(coffee *types.Coffee, duration time.Duration) {
+	b.Submit(coffee, duration)
+}
+
+// Acquired returns the event of a barista getting the brewer for the order
+func (b *Brewer) Acquired(order *types.Order, barista int, waitTime time.Duration) monitor.Payload {
+	return &monitor.BrewerAcquiredPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Brewer: b.Tag(), WaitTime: waitTime}
+}
+
+// Started returns the event of the brewer starting to brew the order
+func (b *Brewer) Started(order *types.Order, barista int) monitor.Payload {
+	return &monitor.BrewStartedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Brewer: b.Tag(), Water: order.Coffee().WaterNeeded()}
+}
+
+// Finished returns the event of the coffee of the order being brewed
+func (b *Brewer) Finished(order *types.Order, barista int) monitor.Payload {
+	return &monitor.BrewFinishedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Brewer: b.Tag(), BrewTime: order.Coffee().BrewTime()}
+}
+
+// Released returns the event of a barista returning the brewer to the pool
+func (b *Brewer) Released(order *types.Order, barista int) monitor.Payload {
+	return &monitor.BrewerReleasedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Brewer: b.Tag()}
 }
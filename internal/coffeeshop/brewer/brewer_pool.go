@@ -1,39 +1,14 @@
 package brewer
 
 import (
-	"sync"
-
-	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
 )
 
 // BrewerPool is a pool of brewers
-type BrewerPool chan *Brewer
+type BrewerPool = equipment.Pool[*Brewer]
 
 // NewBrewerPool creates a new brewer pool
-func NewBrewerPool(size int) BrewerPool {
-	return make(BrewerPool, size)
-}
-
-// AddBrewer adds a brewer to the pool
-func (bp BrewerPool) AddBrewer(brewer *Brewer) {
-	bp <- brewer
-}
-
-// Start starts all brewers in the pool
-func (bp BrewerPool) Start() {
-	wg := &sync.WaitGroup{}
-	for i := 0; i < len(bp); i++ {
-		brewer := <-bp
-		wg.Add(1)
-		go func(b *Brewer) {
-			defer wg.Done()
-			b.Start()
-		}(brewer)
-		bp <- brewer
-	}
-
-	// Wait for all brewers to be started
-	wg.Wait()
-
-	utils.Logger().Info("All brewers are started")
+// size is the number of brewers expected in the pool
+func NewBrewerPool(size int) *BrewerPool {
+	return equipment.NewPool[*Brewer]("brewer", size)
 }
@@ -11,16 +11,19 @@ import (
 
 func TestBrewerPoolCreation(t *testing.T) {
 	brewerPool := NewBrewerPool(2)
-	assert.Equal(t, 2, cap(brewerPool), "Brewer pool should have a capacity of 2")
+	brewerPool.Add(NewBrewer("testBrewer1", 5))
+	brewerPool.Add(NewBrewer("testBrewer2", 7))
+	assert.Equal(t, 2, brewerPool.Capacity(), "Brewer pool should have a capacity of 2")
+	assert.Equal(t, "brewer", brewerPool.Kind())
 }
 
 func TestBrewerPoolAddBrewer(t *testing.T) {
 	brewerPool := NewBrewerPool(1)
 	brewer := NewBrewer("testBrewer", 5)
 
-	brewerPool.AddBrewer(brewer)
+	brewerPool.Add(brewer)
 
-	assert.Equal(t, 1, len(brewerPool), "Brewer pool should have 1 brewer")
+	assert.Equal(t, 1, brewerPool.Available(), "Brewer pool should have 1 brewer")
 }
 
 func TestBrewerPoolStart(t *testing.T) {
@@ -28,8 +31,8 @@ func TestBrewerPoolStart(t *testing.T) {
 	brewer1 := NewBrewer("testBrewer1", 5)
 	brewer2 := NewBrewer("testBrewer2", 7)
 
-	brewerPool.AddBrewer(brewer1)
-	brewerPool.AddBrewer(brewer2)
+	brewerPool.Add(brewer1)
+	brewerPool.Add(brewer2)
 
 	brewerPool.Start()
 
@@ -47,13 +50,13 @@ func TestBrewerPoolStart(t *testing.T) {
 		SizeInOunces:      12,
 	}, types.Standard, []string{})
 
-	brewer1ToTest := <-brewerPool
+	brewer1ToTest := brewerPool.Acquire(coffee1)
 	brewer1ToTest.Brew(coffee1)
-	brewerPool <- brewer1ToTest
+	brewerPool.Release(brewer1ToTest)
 
-	brewer2ToTest := <-brewerPool
+	brewer2ToTest := brewerPool.Acquire(coffee2)
 	brewer2ToTest.Brew(coffee2)
-	brewerPool <- brewer2ToTest
+	brewerPool.Release(brewer2ToTest)
 
 	time.Sleep(3 * time.Second)
 
@@ -63,3 +66,16 @@ func TestBrewerPoolStart(t *testing.T) {
 	isWaterReady2 := <-coffee2.WaterReady()
 	assert.True(t, isWaterReady2, "The coffee2 should be brewed")
 }
+
+func TestBrewerPoolBusy(t *testing.T) {
+	brewerPool := NewBrewerPool(2)
+	brewerPool.Add(NewBrewer("testBrewer1", 5))
+	brewerPool.Add(NewBrewer("testBrewer2", 7))
+	assert.Equal(t, 0, brewerPool.Busy(), "No brewer should be busy")
+
+	brewer := brewerPool.Acquire(nil)
+	assert.Equal(t, 1, brewerPool.Busy(), "The brewer taken from the pool should be busy")
+
+	brewerPool.Release(brewer)
+	assert.Equal(t, 0, brewerPool.Busy(), "No brewer should be busy once returned")
+}
@@ -32,3 +32,21 @@ func TestBrewerStart(t *testing.T) {
 	isWaterReady := <-coffee.WaterReady()
 	assert.True(t, isWaterReady, "The coffee should be brewed")
 }
+
+func TestBrewerBrewForAddsUp(t *testing.T) {
+	brewer := NewBrewer("testBrewer", 5)
+	brewer.Start()
+	assert.Equal(t, 5, brewer.Rate())
+
+	coffee := types.NewCoffee(types.CoffeeType{
+		Name:         "TestCoffee",
+		SizeInOunces: 12,
+	}, types.Standard, []string{})
+
+	// a brew then an extra shot on the same coffee, like a recipe with a shot step
+	brewer.BrewFor(coffee, 20*time.Millisecond)
+	<-coffee.WaterReady()
+	brewer.BrewFor(coffee, 10*time.Millisecond)
+	<-coffee.WaterReady()
+	assert.Equal(t, 30*time.Millisecond, coffee.BrewTime())
+}
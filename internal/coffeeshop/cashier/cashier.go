@@ -1,28 +1,38 @@
 package cashier
 
 import (
+	"errors"
+	"sync"
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/inventory"
 	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/tracing"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 )
 
 // Cashier represents a cashier in the coffee shop
 // It has a customer queue and a shared order queue
+// The ingredients of an order are reserved in the inventory before the order is queued
 type Cashier struct {
 	id            int
 	customerQueue chan *types.Customer
 	orderQueue    types.OrderQueueer
+	inventory     inventory.Inventoryer
+	ordersWg      *sync.WaitGroup
 	eventSystem   monitor.EventSystemer
 }
 
 // NewCashier creates a new cashier
-func NewCashier(id int, maximumCustomers int, orderQueue types.OrderQueueer, eventSystem monitor.EventSystemer) *Cashier {
+// the ordersWg is marked done for the orders the cashier rejects, the baristas do it for the others
+func NewCashier(id int, maximumCustomers int, orderQueue types.OrderQueueer, inventory inventory.Inventoryer, ordersWg *sync.WaitGroup, eventSystem monitor.EventSystemer) *Cashier {
 	cashier := &Cashier{
 		id:            id,
 		customerQueue: make(chan *types.Customer, maximumCustomers),
 		orderQueue:    orderQueue,
+		inventory:     inventory,
+		ordersWg:      ordersWg,
 		eventSystem:   eventSystem,
 	}
 
@@ -31,21 +41,43 @@ func NewCashier(id int, maximumCustomers int, orderQueue types.OrderQueueer, eve
 
 // Start starts the cashier and listens for customers
 func (c *Cashier) Start() {
-	logger := utils.Logger().WithField("cashier", c.id)
 	go func() {
 		for customer := range c.customerQueue {
-			logger.WithField("customer", customer.Name()).Info("Customer is placing order")
+			span := tracing.GlobalTracer().StartSpan(customer.SpanContext(), "take order")
+			span.SetAttribute("cashier", c.id)
+			customer.Logger().WithField("cashier", c.id).Info("Customer is placing order")
 			order := customer.PlaceOrder()
+			span.SetAttribute("orderId", order.ID())
 			// add random delay to Simulate the customer placing the order
 			time.Sleep(utils.RandomDelaySeconds())
-			c.orderQueue.Publish(order)
+			if err := c.inventory.Reserve(order); err != nil {
+				c.reject(order, err)
+				span.End()
+				continue
+			}
 			// send event to monitor
-			c.eventSystem.SendEvent(monitor.Event{Type: monitor.OrderReceived, Data: order})
-			logger.WithField("customer", customer.Name()).Info("Customer is done placing order")
+			c.eventSystem.SendEvent(monitor.NewEvent(&monitor.OrderReceivedPayload{Cashier: c.id, Order: monitor.NewOrderSnapshot(order)}))
+			// the order is announced before it is published, a waiting barista may take it at once
+			c.eventSystem.SendEvent(monitor.NewEvent(&monitor.OrderQueuedPayload{Cashier: c.id, QueueSize: c.orderQueue.Size() + 1, Order: monitor.NewOrderSnapshot(order)}))
+			c.orderQueue.Publish(order)
+			order.Logger().WithField("cashier", c.id).Info("Customer is done placing order")
+			span.End()
 		}
 	}()
 }
 
+// reject turns down an order that cannot be prepared, the customer leaves without a coffee
+func (c *Cashier) reject(order *types.Order, err error) {
+	payload := &monitor.OrderRejectedPayload{Cashier: c.id, Order: monitor.NewOrderSnapshot(order)}
+	var outOfStock *inventory.OutOfStockError
+	if errors.As(err, &outOfStock) {
+		payload.Item = outOfStock.Item
+	}
+	c.eventSystem.SendEvent(monitor.NewEvent(payload))
+	order.Cancel(err.Error())
+	c.ordersWg.Done()
+}
+
 // ID returns the cashier's ID
 func (c *Cashier) ID() int {
 	return c.id
@@ -1,9 +1,11 @@
 package cashier
 
 import (
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/inventory"
 	"github.com/s3ndd/coffeeshop/internal/coffeeshop/mocks"
 	"github.com/stretchr/testify/assert"
 )
@@ -13,8 +15,8 @@ func TestCashierPool(t *testing.T) {
 	mockEventSystem := &mocks.MockEventSystem{}
 
 	// Create cashiers
-	cashier1 := NewCashier(1, 10, mockOrderQueue, mockEventSystem)
-	cashier2 := NewCashier(2, 10, mockOrderQueue, mockEventSystem)
+	cashier1 := NewCashier(1, 10, mockOrderQueue, inventory.NewInventory(nil, mockEventSystem), &sync.WaitGroup{}, mockEventSystem)
+	cashier2 := NewCashier(2, 10, mockOrderQueue, inventory.NewInventory(nil, mockEventSystem), &sync.WaitGroup{}, mockEventSystem)
 
 	// Create a cashier pool
 	cashierPool := NewCashierPool(2)
@@ -38,7 +40,7 @@ func TestCashierPool(t *testing.T) {
 	assert.Equal(t, cashier1, cashierPool[1])
 
 	// Test pushing and popping cashiers in the cashier pool
-	cashier3 := NewCashier(3, 10, mockOrderQueue, mockEventSystem)
+	cashier3 := NewCashier(3, 10, mockOrderQueue, inventory.NewInventory(nil, mockEventSystem), &sync.WaitGroup{}, mockEventSystem)
 	cashierPool.Push(cashier3)
 	assert.Equal(t, 3, cashierPool.Len())
 	assert.Equal(t, cashier3, cashierPool[2])
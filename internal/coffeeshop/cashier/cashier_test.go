@@ -1,10 +1,13 @@
 package cashier
 
 import (
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/inventory"
 	"github.com/s3ndd/coffeeshop/internal/coffeeshop/mocks"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -14,13 +17,14 @@ func TestCashier(t *testing.T) {
 	mockOrderQueue := new(mocks.MockOrderQueue)
 	mockEventSystem := new(mocks.MockEventSystem)
 
-	cashier := NewCashier(1, 5, mockOrderQueue, mockEventSystem)
+	cashier := NewCashier(1, 5, mockOrderQueue, inventory.NewInventory(nil, mockEventSystem), &sync.WaitGroup{}, mockEventSystem)
 	assert.Equal(t, 1, cashier.ID(), "Cashier ID should be 1")
 
 	cashier.ServeCustomer(types.NewCustomer("Shelly Shi", mocks.CreateMockConfig()))
 	assert.Equal(t, 1, cashier.CustomerQueueSize(), "Cashier should have 1 customer in the queue")
 
 	mockOrderQueue.On("Publish", mock.Anything)
+	mockOrderQueue.On("Size").Return(1)
 	mockEventSystem.On("SendEvent", mock.Anything)
 
 	cashier.Start()
@@ -29,3 +33,44 @@ func TestCashier(t *testing.T) {
 	mockOrderQueue.AssertExpectations(t)
 	mockEventSystem.AssertExpectations(t)
 }
+
+// eventRecorder records the types of the events sent, in order
+type eventRecorder struct {
+	eventTypes []monitor.EventType
+	mutex      sync.Mutex
+}
+
+func (r *eventRecorder) SendEvent(event monitor.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.eventTypes = append(r.eventTypes, event.Type)
+}
+
+func (r *eventRecorder) types() []monitor.EventType {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]monitor.EventType(nil), r.eventTypes...)
+}
+
+func TestCashierQueuesTheOrderBeforeAWaitingBaristaTakesIt(t *testing.T) {
+	events := &eventRecorder{}
+	orderQueue := types.NewOrderQueue(0)
+	cashier := NewCashier(1, 5, orderQueue, inventory.NewInventory(nil, events), &sync.WaitGroup{}, events)
+
+	// the barista is already waiting on the queue and takes the order as soon as it is published
+	taken := make(chan *types.Order)
+	go func() {
+		order := <-orderQueue.Subscribe()
+		events.SendEvent(monitor.NewEvent(&monitor.BaristaAssignedPayload{Barista: 1, Order: monitor.NewOrderSnapshot(order)}))
+		taken <- order
+	}()
+	cashier.ServeCustomer(types.NewCustomer("Shelly Shi", mocks.CreateMockConfig()))
+	cashier.Start()
+
+	select {
+	case <-taken:
+	case <-time.After(6 * time.Second):
+		t.Fatal("The order was not published")
+	}
+	assert.Equal(t, []monitor.EventType{monitor.OrderReceived, monitor.OrderQueued, monitor.BaristaAssigned}, events.types())
+}
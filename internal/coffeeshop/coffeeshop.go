@@ -1,95 +1,264 @@
 package coffeeshop
 
 import (
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/s3ndd/coffeeshop/internal/coffeeshop/barista"
 	brewer1 "github.com/s3ndd/coffeeshop/internal/coffeeshop/brewer"
 	cashier2 "github.com/s3ndd/coffeeshop/internal/coffeeshop/cashier"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/coldbrew"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
 	greeter2 "github.com/s3ndd/coffeeshop/internal/coffeeshop/greeter"
 	grinder2 "github.com/s3ndd/coffeeshop/internal/coffeeshop/grinder"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/icedispenser"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/inventory"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/steamer"
 	"github.com/s3ndd/coffeeshop/internal/config"
 	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/recipe"
 	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
 )
 
 type CoffeeShop struct {
-	grinderPool grinder2.GrinderPool
-	brewerPool  brewer1.BrewerPool
-	greeterPool greeter2.GreeterPool
-	cashierPool cashier2.CashierPool
+	grinderPool      *grinder2.GrinderPool
+	brewerPool       *brewer1.BrewerPool
+	steamerPool      *steamer.SteamerPool
+	iceDispenserPool *icedispenser.IceDispenserPool
+	greeterPool      greeter2.GreeterPool
+	cashierPool      cashier2.CashierPool
+	// cashiers keeps the cashiers in creation order, the cashier pool is reordered by the greeters
+	cashiers    []*cashier2.Cashier
 	baristaPool *barista.BaristaPool
 	orderQueue  *types.OrderQueue
+	inventory   *inventory.Inventory
+	// resources are the names of the baristas and of the units of each kind of equipment, registered with the monitor on opening
+	resources   map[monitor.ResourceKind][]string
 	ordersWg    *sync.WaitGroup
+	eventSystem monitor.EventSystemer
 }
 
 func NewCoffeeShop(coffeeShop *config.CoffeeShopSettings, ordersWg *sync.WaitGroup, eventSystem monitor.EventSystemer) *CoffeeShop {
 	// create an order queue
 	orderQueue := types.NewOrderQueue(coffeeShop.OrderQueueSize)
 
+	// create the inventory, shared by the cashiers reserving the ingredients and the baristas consuming them
+	shopInventory := inventory.NewInventory(coffeeShop.Inventory, eventSystem)
+
 	// create cashiers
 	cashierPool := cashier2.NewCashierPool(coffeeShop.NumberOfCashiers)
+	cashiers := make([]*cashier2.Cashier, 0, coffeeShop.NumberOfCashiers)
 	for i := 0; i < coffeeShop.NumberOfCashiers; i++ {
-		cashier := cashier2.NewCashier(i, coffeeShop.CashierQueueSize, orderQueue, eventSystem)
+		cashier := cashier2.NewCashier(i, coffeeShop.CashierQueueSize, orderQueue, shopInventory, ordersWg, eventSystem)
 		cashierPool.AddCashier(cashier)
+		cashiers = append(cashiers, cashier)
 	}
 
 	// create greeters
 	greeterPool := greeter2.NewGreeterPool(coffeeShop.NumberOfGreeters)
 	for i := 0; i < coffeeShop.NumberOfGreeters; i++ {
-		greeter := greeter2.NewGreeter(i, &cashierPool)
+		greeter := greeter2.NewGreeter(i, &cashierPool, eventSystem)
 		greeterPool.AddGreeter(greeter)
 	}
 
-	// create grinder pool
+	// create grinder pool, each grinder only handles the beans it is configured for
+	// the grinders and brewers break down and are maintained according to their reliability, out of their pool meanwhile
+	resources := make(map[monitor.ResourceKind][]string)
 	grinderPool := grinder2.NewGrinderPool(len(coffeeShop.GrinderSettings))
 	for _, settings := range coffeeShop.GrinderSettings {
-		grinder := grinder2.NewGrinder(settings.Tag, settings.GramsPerSecond)
-		grinderPool.AddGrinder(grinder)
+		beans := make([]config.BeanSettings, 0, len(settings.Beans))
+		for _, name := range settings.Beans {
+			if bean, ok := coffeeShop.Bean(name); ok {
+				beans = append(beans, bean)
+			}
+		}
+		grinder := grinder2.NewGrinder(settings.Tag, settings.GramsPerSecond, beans...)
+		grinder.SetReliability(equipment.NewReliability(string(recipe.Grinder), settings.Tag, settings.Reliability, eventSystem))
+		grinder.SetCleaning(equipment.NewCleaning(string(recipe.Grinder), settings.Tag, settings.Cleaning, eventSystem))
+		grinderPool.Add(grinder)
+		resources[monitor.GrinderResource] = append(resources[monitor.GrinderResource], settings.Tag)
 	}
 
 	// create brewer pool
 	brewerPool := brewer1.NewBrewerPool(len(coffeeShop.BrewerSettings))
 	for _, settings := range coffeeShop.BrewerSettings {
 		brewer := brewer1.NewBrewer(settings.Tag, settings.OuncesWaterPerSecond)
-		brewerPool.AddBrewer(brewer)
+		brewer.SetReliability(equipment.NewReliability(string(recipe.Brewer), settings.Tag, settings.Reliability, eventSystem))
+		brewer.SetCleaning(equipment.NewCleaning(string(recipe.Brewer), settings.Tag, settings.Cleaning, eventSystem))
+		brewerPool.Add(brewer)
+		resources[monitor.BrewerResource] = append(resources[monitor.BrewerResource], settings.Tag)
+	}
+
+	// create steamer pool
+	steamerPool := steamer.NewSteamerPool(len(coffeeShop.SteamerSettings))
+	for _, settings := range coffeeShop.SteamerSettings {
+		steamerPool.Add(steamer.NewSteamer(settings.Tag, settings.OuncesMilkPerSecond))
+		resources[monitor.SteamerResource] = append(resources[monitor.SteamerResource], settings.Tag)
+	}
+
+	// create ice dispenser pool
+	iceDispenserPool := icedispenser.NewIceDispenserPool(len(coffeeShop.IceDispensers))
+	for _, settings := range coffeeShop.IceDispensers {
+		iceDispenserPool.Add(icedispenser.NewIceDispenser(settings.Tag, settings.OuncesIcePerSecond))
+		resources[monitor.IceDispenserResource] = append(resources[monitor.IceDispenserResource], settings.Tag)
+	}
+
+	// the cold brew stock is only kept if batches are configured, the cold brew coffee types are validated against it
+	var coldBrew *coldbrew.Stock
+	if coffeeShop.ColdBrew.BatchOunces.IsPositive() {
+		coldBrew = coldbrew.NewStock(coffeeShop.ColdBrew)
+	}
+
+	// the recipes are validated with the config, a coffee type whose recipe does not parse follows the default recipe
+	recipes := make(map[string]recipe.Recipe, len(coffeeShop.CoffeeTypes))
+	for i := range coffeeShop.CoffeeTypes {
+		if steps, err := coffeeShop.CoffeeTypes[i].ParseRecipe(); err == nil {
+			recipes[coffeeShop.CoffeeTypes[i].Name] = steps
+		}
+	}
+
+	// the baristas acquire the equipment of each step of a recipe from the pool of its class
+	// and report the waits longer than the equipment timeout
+	equipmentTimeout := time.Duration(coffeeShop.EquipmentTimeoutSeconds * float64(time.Second))
+	pools := map[recipe.Equipment]equipment.Acquirer{
+		recipe.Grinder:      grinderPool,
+		recipe.Brewer:       brewerPool,
+		recipe.Steamer:      steamerPool,
+		recipe.IceDispenser: iceDispenserPool,
 	}
 
 	// create barista pool
 	baristas := make([]barista.Baristaer, coffeeShop.NumberOfBaristas)
 	for i := 0; i < coffeeShop.NumberOfBaristas; i++ {
-		barista := barista.NewBarista(i, grinderPool, brewerPool, ordersWg, eventSystem)
+		barista := barista.NewBarista(i, pools, equipmentTimeout, coldBrew, coffeeShop.Extras, recipes, shopInventory, ordersWg, eventSystem)
 		baristas[i] = barista
+		resources[monitor.BaristaResource] = append(resources[monitor.BaristaResource], strconv.Itoa(i))
 	}
 
 	baristaPool := barista.NewBaristaPool(orderQueue, baristas)
 
 	return &CoffeeShop{
-		grinderPool: grinderPool,
-		brewerPool:  brewerPool,
-		greeterPool: greeterPool,
-		cashierPool: cashierPool,
-		baristaPool: baristaPool,
-		orderQueue:  orderQueue,
-		ordersWg:    ordersWg,
+		grinderPool:      grinderPool,
+		brewerPool:       brewerPool,
+		steamerPool:      steamerPool,
+		iceDispenserPool: iceDispenserPool,
+		greeterPool:      greeterPool,
+		cashierPool:      cashierPool,
+		cashiers:         cashiers,
+		baristaPool:      baristaPool,
+		orderQueue:       orderQueue,
+		inventory:        shopInventory,
+		resources:        resources,
+		ordersWg:         ordersWg,
+		eventSystem:      eventSystem,
 	}
 }
 
 // Open opens the coffee shop
+// The baristas and the equipment are registered with the monitor first, so the ones never used count as idle
 func (cs *CoffeeShop) Open() {
+	for _, kind := range []monitor.ResourceKind{monitor.BaristaResource, monitor.GrinderResource, monitor.BrewerResource, monitor.SteamerResource, monitor.IceDispenserResource} {
+		if names := cs.resources[kind]; len(names) > 0 {
+			cs.eventSystem.SendEvent(monitor.NewEvent(&monitor.ResourcesRegisteredPayload{Kind: kind, Names: names}))
+		}
+	}
 	cs.cashierPool.Start()
 	cs.baristaPool.Start()
 	cs.grinderPool.Start()
 	cs.brewerPool.Start()
+	cs.steamerPool.Start()
+	cs.iceDispenserPool.Start()
 }
 
 // Close closes the coffee shop
+// The pending deliveries are cancelled, so no event is sent once the orders are done,
+// and the equipment is stopped, it must be called once all orders are done
 func (cs *CoffeeShop) Close() {
-	// TODO: implement
+	cs.inventory.Close()
+	cs.grinderPool.Stop()
+	cs.brewerPool.Stop()
+	cs.steamerPool.Stop()
+	cs.iceDispenserPool.Stop()
+	for _, stats := range cs.EquipmentStats() {
+		utils.Logger().WithField("pool", stats).Info("Equipment pool statistics")
+	}
+	// TODO: stop the staff
+}
+
+// EquipmentStats returns the statistics of each equipment pool and of its units
+func (cs *CoffeeShop) EquipmentStats() []equipment.PoolStats {
+	return []equipment.PoolStats{
+		cs.grinderPool.Stats(),
+		cs.brewerPool.Stats(),
+		cs.steamerPool.Stats(),
+		cs.iceDispenserPool.Stats(),
+	}
 }
 
 // ServeCustomer serves a customer
 func (cs *CoffeeShop) ServeCustomer(customer *types.Customer) {
 	cs.ordersWg.Add(1)
+	cs.eventSystem.SendEvent(monitor.NewEvent(&monitor.CustomerArrivedPayload{CustomerRef: monitor.NewCustomerRef(customer)}))
 	cs.greeterPool.AssignCustomer(customer)
 }
+
+// CashierQueueSizes returns the number of customers waiting in line for each cashier, by cashier ID
+func (cs *CoffeeShop) CashierQueueSizes() map[int]int {
+	sizes := make(map[int]int, len(cs.cashiers))
+	for _, cashier := range cs.cashiers {
+		sizes[cashier.ID()] = cashier.CustomerQueueSize()
+	}
+	return sizes
+}
+
+// OrderQueueSize returns the number of orders waiting for a barista
+func (cs *CoffeeShop) OrderQueueSize() int {
+	return cs.orderQueue.Size()
+}
+
+// BusyBaristas returns the number of baristas processing an order
+func (cs *CoffeeShop) BusyBaristas() int {
+	return cs.baristaPool.Busy()
+}
+
+// BusyGrinders returns the number of grinders in use
+func (cs *CoffeeShop) BusyGrinders() int {
+	return cs.grinderPool.Busy()
+}
+
+// BusyBrewers returns the number of brewers in use
+func (cs *CoffeeShop) BusyBrewers() int {
+	return cs.brewerPool.Busy()
+}
+
+// AvailableGrinders returns the number of grinders waiting in the pool
+func (cs *CoffeeShop) AvailableGrinders() int {
+	return cs.grinderPool.Available()
+}
+
+// AvailableBrewers returns the number of brewers waiting in the pool
+func (cs *CoffeeShop) AvailableBrewers() int {
+	return cs.brewerPool.Available()
+}
+
+// BusySteamers returns the number of steamers in use
+func (cs *CoffeeShop) BusySteamers() int {
+	return cs.steamerPool.Busy()
+}
+
+// AvailableSteamers returns the number of steamers waiting in the pool
+func (cs *CoffeeShop) AvailableSteamers() int {
+	return cs.steamerPool.Available()
+}
+
+// BusyIceDispensers returns the number of ice dispensers in use
+func (cs *CoffeeShop) BusyIceDispensers() int {
+	return cs.iceDispenserPool.Busy()
+}
+
+// AvailableIceDispensers returns the number of ice dispensers waiting in the pool
+func (cs *CoffeeShop) AvailableIceDispensers() int {
+	return cs.iceDispenserPool.Available()
+}
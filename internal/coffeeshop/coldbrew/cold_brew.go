@@ -0,0 +1,94 @@
+package coldbrew
+
+import (
+	"sync"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+// Stock is the cold brew prepared in batches ahead of the orders, in ounces
+// The orders draw from the stock and the baristas replenish it, one batch at a time
+type Stock struct {
+	settings config.ColdBrewSettings
+	ounces   decimal.Decimal
+	// preparing is whether a barista is preparing a batch
+	preparing bool
+	mutex     sync.Mutex
+	// prepared is signalled when a batch is added to the stock
+	prepared *sync.Cond
+}
+
+// NewStock creates the cold brew stock with its starting stock
+func NewStock(settings config.ColdBrewSettings) *Stock {
+	s := &Stock{
+		settings: settings,
+		ounces:   settings.StartingStock,
+	}
+	s.prepared = sync.NewCond(&s.mutex)
+	return s
+}
+
+// Draw takes the ounces of an order from the stock and returns the stock left
+// When the stock is short, it waits for the batch being prepared, or returns false without drawing if no batch is,
+// the caller then prepares a batch with PrepareBatch and draws again
+func (s *Stock) Draw(ounces decimal.Decimal) (decimal.Decimal, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for s.ounces.LessThan(ounces) {
+		if !s.preparing {
+			s.preparing = true
+			return s.ounces, false
+		}
+		s.prepared.Wait()
+	}
+	s.ounces = s.ounces.Sub(ounces)
+	return s.ounces, true
+}
+
+// StartBatch returns whether the caller should prepare a batch with PrepareBatch,
+// because the stock is at or below the replenish point and no batch is being prepared
+func (s *Stock) StartBatch() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.preparing || s.ounces.GreaterThan(s.settings.ReplenishPoint) {
+		return false
+	}
+	s.preparing = true
+	return true
+}
+
+// PrepareBatch prepares a batch, adds it to the stock and returns the stock
+// It is only called after Draw returned false or StartBatch returned true, so one batch is prepared at a time
+func (s *Stock) PrepareBatch() decimal.Decimal {
+	time.Sleep(s.BatchTime())
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.ounces = s.ounces.Add(s.settings.BatchOunces)
+	s.preparing = false
+	s.prepared.Broadcast()
+	return s.ounces
+}
+
+// Ounces returns the cold brew left in the stock
+func (s *Stock) Ounces() decimal.Decimal {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.ounces
+}
+
+// BatchOunces returns the cold brew added to the stock by a batch
+func (s *Stock) BatchOunces() decimal.Decimal {
+	return s.settings.BatchOunces
+}
+
+// BatchTime returns the time it takes to prepare a batch
+func (s *Stock) BatchTime() time.Duration {
+	return time.Duration(s.settings.BatchSeconds * float64(time.Second))
+}
@@ -0,0 +1,61 @@
+package coldbrew
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStock() *Stock {
+	return NewStock(config.ColdBrewSettings{
+		StartingStock:  decimal.NewFromInt(20),
+		ReplenishPoint: decimal.NewFromInt(8),
+		BatchOunces:    decimal.NewFromInt(32),
+		BatchSeconds:   0.05,
+	})
+}
+
+func TestStockDraw(t *testing.T) {
+	stock := newTestStock()
+
+	left, ok := stock.Draw(decimal.NewFromInt(10))
+	assert.True(t, ok)
+	assert.Equal(t, "10", left.String())
+	assert.False(t, stock.StartBatch(), "The stock is above the replenish point")
+
+	left, ok = stock.Draw(decimal.NewFromInt(4))
+	assert.True(t, ok)
+	assert.Equal(t, "6", left.String())
+	assert.True(t, stock.StartBatch(), "The stock is at or below the replenish point")
+	assert.False(t, stock.StartBatch(), "Only one batch is prepared at a time")
+
+	assert.Equal(t, "38", stock.PrepareBatch().String())
+	assert.False(t, stock.StartBatch())
+}
+
+func TestStockDrawWaitsForTheBatch(t *testing.T) {
+	stock := newTestStock()
+
+	_, ok := stock.Draw(decimal.NewFromInt(30))
+	assert.False(t, ok, "The stock is short and no batch is being prepared")
+	assert.Equal(t, "20", stock.Ounces().String(), "Nothing is drawn when the stock is short")
+
+	drawn := make(chan decimal.Decimal)
+	go func() {
+		left, ok := stock.Draw(decimal.NewFromInt(25))
+		assert.True(t, ok, "The draw waits for the batch being prepared")
+		drawn <- left
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-drawn:
+		t.Fatal("The draw should wait for the batch")
+	default:
+	}
+	stock.PrepareBatch()
+	assert.Equal(t, "27", (<-drawn).String())
+}
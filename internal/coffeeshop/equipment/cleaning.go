@@ -0,0 +1,150 @@
+package equipment
+
+import (
+	"sync"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+)
+
+// Cleanable is implemented by the equipment that slows down with use and is cleaned by a barista, the pool takes it out of service meanwhile
+// A unit without a cleaning cycle returns nil and never gets dirty
+type Cleanable interface {
+	Cleaning() *Cleaning
+}
+
+// Cleaning is the cleaning cycle of a unit of equipment
+// Each use since its last cleaning slows the unit down, and after the configured number of uses it is due for a cleaning,
+// which a barista performs while the unit is out of its pool
+// The methods of a nil Cleaning do nothing, so a unit without a cleaning cycle never slows down
+type Cleaning struct {
+	kind     string
+	tag      string
+	settings config.CleaningSettings
+	// uses is the number of uses since the last cleaning
+	uses int
+	// withdraw takes the unit out of its pool until the channel is closed, it is set when the unit is added to a pool
+	withdraw func(until <-chan struct{})
+	// cleaned is closed once the unit withdrawn for its cleaning is cleaned, it is nil while the unit is in service
+	cleaned     chan struct{}
+	withdrawAt  time.Time
+	eventSystem monitor.EventSystemer
+	mutex       sync.Mutex
+}
+
+// NewCleaning creates the cleaning cycle of the unit of the given kind and tag
+func NewCleaning(kind string, tag string, settings config.CleaningSettings, eventSystem monitor.EventSystemer) *Cleaning {
+	return &Cleaning{
+		kind:        kind,
+		tag:         tag,
+		settings:    settings,
+		eventSystem: eventSystem,
+	}
+}
+
+// attach sets how the unit is taken out of its pool
+func (c *Cleaning) attach(withdraw func(until <-chan struct{})) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	c.withdraw = withdraw
+	c.mutex.Unlock()
+}
+
+// Use counts a use of the unit that takes the given duration when the unit is clean, and returns the time it takes at its current rate
+func (c *Cleaning) Use(duration time.Duration) time.Duration {
+	if c == nil {
+		return duration
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	slowed := time.Duration(float64(duration) * c.slowdown())
+	c.uses++
+	return slowed
+}
+
+// Rate returns the given full rate of the unit slowed down by its uses since its last cleaning
+func (c *Cleaning) Rate(rate int) float64 {
+	if c == nil {
+		return float64(rate)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return float64(rate) / c.slowdown()
+}
+
+// slowdown returns how many times longer a use takes than when the unit is clean, it must be called with the mutex held
+func (c *Cleaning) slowdown() float64 {
+	return 1 + c.settings.DegradationPerUse*float64(c.uses)
+}
+
+// Uses returns the number of uses since the last cleaning
+func (c *Cleaning) Uses() int {
+	if c == nil {
+		return 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.uses
+}
+
+// Due returns whether the unit must be cleaned before it is used again
+func (c *Cleaning) Due() bool {
+	if c == nil || c.settings.EveryUses <= 0 {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.uses >= c.settings.EveryUses
+}
+
+// Withdraw takes the unit out of its pool until it is cleaned, a unit in use stays out once it is released
+// It is called by the barista holding the unit, so the unit is not handed to anyone else before it is cleaned
+func (c *Cleaning) Withdraw() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	if c.cleaned != nil {
+		c.mutex.Unlock()
+		return
+	}
+	c.cleaned = make(chan struct{})
+	c.withdrawAt = time.Now()
+	withdraw, cleaned := c.withdraw, c.cleaned
+	c.mutex.Unlock()
+
+	if withdraw != nil {
+		withdraw(cleaned)
+	}
+	c.eventSystem.SendEvent(monitor.NewEvent(&monitor.EquipmentOutOfServicePayload{Kind: c.kind, Equipment: c.tag, Reason: monitor.CleaningOutage, Duration: seconds(c.settings.DurationSeconds)}))
+}
+
+// Clean has the barista clean the unit, which takes the configured cleaning time, and puts it back in its pool at its full rate
+// rate is the full rate of the unit, the unit is withdrawn first if it is still in its pool
+func (c *Cleaning) Clean(barista int, rate int) {
+	if c == nil {
+		return
+	}
+	c.Withdraw()
+	logger := utils.Logger().WithFields(utils.LogFields{c.kind: c.tag, "barista": barista, "uses": c.Uses(), "rate": c.Rate(rate)})
+	logger.Info("Barista is cleaning the equipment")
+	start := time.Now()
+	time.Sleep(seconds(c.settings.DurationSeconds))
+
+	c.mutex.Lock()
+	uses, slowed := c.uses, float64(rate)/c.slowdown()
+	cleaned, withdrawAt := c.cleaned, c.withdrawAt
+	c.uses = 0
+	c.cleaned = nil
+	c.mutex.Unlock()
+
+	close(cleaned)
+	c.eventSystem.SendEvent(monitor.NewEvent(&monitor.EquipmentBackInServicePayload{Kind: c.kind, Equipment: c.tag, Reason: monitor.CleaningOutage, Downtime: time.Since(withdrawAt)}))
+	c.eventSystem.SendEvent(monitor.NewEvent(&monitor.EquipmentCleanedPayload{Kind: c.kind, Equipment: c.tag, Barista: barista, Uses: uses, Rate: slowed, CleaningTime: time.Since(start)}))
+	logger.Info("Equipment is cleaned")
+}
@@ -0,0 +1,60 @@
+package equipment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/stretchr/testify/assert"
+)
+
+// cleanableOven is an oven with a cleaning cycle
+type cleanableOven struct {
+	oven
+	cleaning *Cleaning
+}
+
+func (o *cleanableOven) Cleaning() *Cleaning { return o.cleaning }
+
+func TestCleaningUse(t *testing.T) {
+	cleaning := NewCleaning("oven", "oven1", config.CleaningSettings{EveryUses: 2, DurationSeconds: 1, DegradationPerUse: 0.5}, &eventRecorder{})
+
+	assert.Equal(t, 2*time.Second, cleaning.Use(2*time.Second), "A clean oven runs at its full rate")
+	assert.False(t, cleaning.Due())
+	assert.Equal(t, 3*time.Second, cleaning.Use(2*time.Second))
+	assert.Equal(t, 5.0, cleaning.Rate(10), "The rate is halved after two uses")
+	assert.True(t, cleaning.Due())
+
+	var never *Cleaning
+	assert.Equal(t, time.Second, never.Use(time.Second))
+	assert.Equal(t, 10.0, never.Rate(10))
+	assert.False(t, never.Due(), "A unit without a cleaning cycle is never cleaned")
+}
+
+func TestCleaningTakesTheUnitOutOfItsPool(t *testing.T) {
+	events := &eventRecorder{}
+	dirty := &cleanableOven{oven: oven{tag: "oven1"}}
+	dirty.cleaning = NewCleaning("oven", "oven1", config.CleaningSettings{EveryUses: 1, DurationSeconds: 0.03, DegradationPerUse: 1}, events)
+	pool := NewPool[*cleanableOven]("oven", 1)
+	pool.Add(dirty)
+
+	unit := pool.Acquire(nil)
+	dirty.cleaning.Use(time.Second)
+	assert.True(t, dirty.cleaning.Due())
+	dirty.cleaning.Withdraw()
+	pool.Release(unit)
+	assert.Equal(t, 0, pool.Available(), "The dirty oven stays out of the pool until it is cleaned")
+	assert.Equal(t, 1, pool.OutOfService())
+
+	dirty.cleaning.Clean(1, 10)
+	assert.Eventually(t, func() bool {
+		return pool.Available() == 1
+	}, time.Second, time.Millisecond, "The oven is back once it is cleaned")
+	assert.Zero(t, dirty.cleaning.Uses())
+	assert.Equal(t, 10.0, dirty.cleaning.Rate(10), "The oven is back to its full rate")
+	assert.Equal(t, []monitor.EventType{monitor.EquipmentOutOfService, monitor.EquipmentBackInService, monitor.EquipmentCleaned}, events.types())
+	cleaned := events.events[2].Payload.(*monitor.EquipmentCleanedPayload)
+	assert.Equal(t, 1, cleaned.Uses)
+	assert.Equal(t, 5.0, cleaned.Rate)
+}
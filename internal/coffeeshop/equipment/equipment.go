@@ -0,0 +1,42 @@
+package equipment
+
+import (
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/types"
+)
+
+// Equipment is a unit of equipment of the coffee shop, like a grinder, a brewer or a steamer
+// Start starts the unit and Stop stops it once it has no more coffee to process
+// Process hands a coffee to the unit and blocks until it is processed, which takes the given duration,
+// it returns ErrBroken if the unit broke down and threw the coffee away
+// Rate is how much the unit processes per second, in its own unit, like grams of beans for a grinder
+type Equipment interface {
+	Tag() string
+	Rate() int
+	Start()
+	Stop()
+	Process(coffee *types.Coffee, duration time.Duration) error
+}
+
+// Matcher is implemented by the equipment that only handles some coffees, like a grinder set for some beans
+// The other equipment handles any coffee
+type Matcher interface {
+	Accepts(coffee *types.Coffee) bool
+}
+
+// Reporter is implemented by the equipment that describes its use with its own events, like GrinderAcquired
+// A nil payload is not sent, for example when the equipment has no event for the start of the processing
+type Reporter interface {
+	Acquired(order *types.Order, barista int, waitTime time.Duration) monitor.Payload
+	Started(order *types.Order, barista int) monitor.Payload
+	Finished(order *types.Order, barista int) monitor.Payload
+	Released(order *types.Order, barista int) monitor.Payload
+}
+
+// accepts returns whether the equipment handles the coffee, any equipment handles a nil coffee
+func accepts(equipment Equipment, coffee *types.Coffee) bool {
+	matcher, ok := equipment.(Matcher)
+	return coffee == nil || !ok || matcher.Accepts(coffee)
+}
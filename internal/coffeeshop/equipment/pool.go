@@ -0,0 +1,519 @@
+package equipment
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+)
+
+// ErrTimeout is returned when no equipment is acquired before the timeout
+var ErrTimeout = errors.New("timed out waiting for equipment")
+
+// tickets numbers the waits for equipment of all the pools, the baristas waiting in the line of a pool are served in the order of their tickets
+var tickets atomic.Uint64
+
+// Acquirer is a pool of equipment whatever the type of its units, it is how the baristas use any equipment
+// It is only implemented by Pool, Reserve takes units from several pools at once under their locks
+type Acquirer interface {
+	Kind() string
+	AcquireEquipment(coffee *types.Coffee) Equipment
+	AcquireEquipmentContext(ctx context.Context, coffee *types.Coffee) (Equipment, error)
+	ReleaseEquipment(equipment Equipment)
+	Capacity() int
+	acquirePatiently(ctx context.Context, coffee *types.Coffee, patience <-chan time.Time, impatient func()) (Equipment, error)
+	lock()
+	unlock()
+	reservable(coffee *types.Coffee, ticket uint64, wake chan<- struct{}) bool
+	reserved(coffee *types.Coffee, ticket uint64, start time.Time) Equipment
+	leave(ticket uint64)
+	timedOut()
+}
+
+// Pool is a pool of equipment of one kind, like the grinders of the shop
+// A unit is acquired for a coffee, only a unit that accepts the coffee is handed out
+// The baristas waiting for a unit are served in the order of their tickets, each by the first released unit that accepts its coffee
+// A reservation of several pools that found no unit in the pool waits in its line too: the units it accepts are kept available for it,
+// so the baristas who came after it cannot take them first, see Reserve
+type Pool[T Equipment] struct {
+	kind      string
+	items     []T
+	available []T
+	waiters   []*waiter[T]
+	stats     map[string]*ItemStats
+	// reservations are the reservations waiting in the line of the pool, in the order of their tickets
+	reservations []*pending
+	// withdrawn are the units out of service that are not in use, by tag, they are back in the pool at the end of their outages
+	withdrawn map[string]T
+	// acquisitions, timeouts and waitTime are counted for the whole pool
+	acquisitions int
+	timeouts     int
+	waitTime     time.Duration
+	mutex        sync.Mutex
+}
+
+// waiter is a barista waiting for a unit that accepts the coffee
+type waiter[T Equipment] struct {
+	ticket uint64
+	coffee *types.Coffee
+	start  time.Time
+	item   chan T
+}
+
+// pending is a reservation waiting in the line of a pool, it is woken up when a unit is released
+type pending struct {
+	ticket uint64
+	coffee *types.Coffee
+	wake   chan<- struct{}
+}
+
+// ItemStats are the statistics of a unit of a pool
+// BusyTime is the time it was held by the baristas, not counting the current acquisition
+// Outages is the number of times it was taken out of service, Downtime the time it was out, not counting the current outage
+type ItemStats struct {
+	Tag          string        `json:"tag"`
+	Acquisitions int           `json:"acquisitions"`
+	BusyTime     time.Duration `json:"busyTime"`
+	Busy         bool          `json:"busy"`
+	OutOfService bool          `json:"outOfService"`
+	Outages      int           `json:"outages"`
+	Downtime     time.Duration `json:"downtime"`
+	acquiredAt   time.Time
+	// outages is the number of outages in progress, they may overlap, like a breakdown during a maintenance
+	outages    int
+	withdrawAt time.Time
+}
+
+// PoolStats are the statistics of a pool and of each of its units
+// WaitTime is the total time the baristas waited to acquire a unit, Timeouts the acquisitions that gave up
+// OutOfService is the number of units out of service, in use or not
+type PoolStats struct {
+	Kind         string        `json:"kind"`
+	Capacity     int           `json:"capacity"`
+	Busy         int           `json:"busy"`
+	Available    int           `json:"available"`
+	OutOfService int           `json:"outOfService"`
+	Waiting      int           `json:"waiting"`
+	Acquisitions int           `json:"acquisitions"`
+	Timeouts     int           `json:"timeouts"`
+	WaitTime     time.Duration `json:"waitTime"`
+	Items        []ItemStats   `json:"items"`
+}
+
+// NewPool creates a new pool of the given kind of equipment
+// size is the number of units expected in the pool
+func NewPool[T Equipment](kind string, size int) *Pool[T] {
+	return &Pool[T]{
+		kind:      kind,
+		items:     make([]T, 0, size),
+		available: make([]T, 0, size),
+		stats:     make(map[string]*ItemStats, size),
+		withdrawn: make(map[string]T),
+	}
+}
+
+// Kind returns the kind of equipment in the pool
+func (p *Pool[T]) Kind() string {
+	return p.kind
+}
+
+// Add adds a unit to the pool
+// A unit with a reliability model is taken out of the pool while it is broken down or maintained
+func (p *Pool[T]) Add(item T) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.items = append(p.items, item)
+	p.available = append(p.available, item)
+	p.stats[item.Tag()] = &ItemStats{Tag: item.Tag()}
+	if reliable, ok := any(item).(Reliable); ok {
+		reliable.Reliability().attach(func(until <-chan struct{}) {
+			p.Withdraw(item, until)
+		})
+	}
+	if cleanable, ok := any(item).(Cleanable); ok {
+		cleanable.Cleaning().attach(func(until <-chan struct{}) {
+			p.Withdraw(item, until)
+		})
+	}
+}
+
+// Withdraw takes the unit out of service until the channel is closed, for example while it is repaired or cleaned
+// A unit in use is kept out of the pool once it is released, the waiting baristas are served by the other units meanwhile
+func (p *Pool[T]) Withdraw(item T, until <-chan struct{}) {
+	p.mutex.Lock()
+	stats := p.stats[item.Tag()]
+	if stats.outages == 0 {
+		stats.withdrawAt = time.Now()
+	}
+	stats.outages++
+	stats.Outages++
+	stats.OutOfService = true
+	for i, available := range p.available {
+		if available.Tag() == item.Tag() {
+			p.available = append(p.available[:i], p.available[i+1:]...)
+			p.withdrawn[item.Tag()] = item
+			break
+		}
+	}
+	p.mutex.Unlock()
+
+	go func() {
+		<-until
+		p.restore(item)
+	}()
+}
+
+// restore ends an outage of the unit, it is back in the pool at the end of its last outage unless it is in use
+func (p *Pool[T]) restore(item T) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.stats[item.Tag()]
+	stats.outages--
+	if stats.outages > 0 {
+		return
+	}
+	stats.OutOfService = false
+	stats.Downtime += time.Since(stats.withdrawAt)
+	if _, ok := p.withdrawn[item.Tag()]; ok {
+		delete(p.withdrawn, item.Tag())
+		p.putBack(item)
+	}
+}
+
+// Start starts all units in the pool
+func (p *Pool[T]) Start() {
+	for _, item := range p.items {
+		item.Start()
+	}
+	utils.Logger().WithField("kind", p.kind).Info("All equipment of the pool is started")
+}
+
+// Stop stops all units in the pool, they must not be acquired anymore
+func (p *Pool[T]) Stop() {
+	for _, item := range p.items {
+		item.Stop()
+	}
+	utils.Logger().WithField("kind", p.kind).Info("All equipment of the pool is stopped")
+}
+
+// Acquire takes a unit that accepts the coffee from the pool, any unit if the coffee is nil
+// It blocks until one is available, so the pool must have a unit for the coffee
+func (p *Pool[T]) Acquire(coffee *types.Coffee) T {
+	item, _ := p.AcquireContext(context.Background(), coffee)
+	return item
+}
+
+// AcquireTimeout takes a unit that accepts the coffee from the pool like Acquire,
+// it returns ErrTimeout if none is available before the timeout, right away if the timeout is not positive
+func (p *Pool[T]) AcquireTimeout(coffee *types.Coffee, timeout time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	item, err := p.AcquireContext(ctx, coffee)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return item, ErrTimeout
+	}
+	return item, err
+}
+
+// AcquireContext takes a unit that accepts the coffee from the pool like Acquire,
+// it returns the error of the context if it is done before a unit is available
+// A unit available right away is taken even if the context is already done
+func (p *Pool[T]) AcquireContext(ctx context.Context, coffee *types.Coffee) (T, error) {
+	return p.acquire(ctx, coffee, nil, nil)
+}
+
+// acquire takes a unit that accepts the coffee from the pool like AcquireContext
+// Each time the patience ticks while the barista waits, the timeout is counted and impatient is called,
+// and the barista keeps its place in the line
+func (p *Pool[T]) acquire(ctx context.Context, coffee *types.Coffee, patience <-chan time.Time, impatient func()) (T, error) {
+	start := time.Now()
+	p.mutex.Lock()
+	ticket := tickets.Add(1)
+	if i := p.free(coffee, ticket); i >= 0 {
+		item := p.take(i, start)
+		p.mutex.Unlock()
+		return item, nil
+	}
+	var none T
+	if ctx.Err() != nil {
+		p.timeouts++
+		p.mutex.Unlock()
+		return none, ctx.Err()
+	}
+	w := &waiter[T]{ticket: ticket, coffee: coffee, start: start, item: make(chan T, 1)}
+	p.waiters = append(p.waiters, w)
+	p.mutex.Unlock()
+
+	for waiting := true; waiting; {
+		select {
+		case item := <-w.item:
+			return item, nil
+		case <-patience:
+			p.timedOut()
+			impatient()
+		case <-ctx.Done():
+			waiting = false
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i, other := range p.waiters {
+		if other == w {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			p.timeouts++
+			return none, ctx.Err()
+		}
+	}
+	// a unit was handed to the barista just as the context was done
+	return <-w.item, nil
+}
+
+// free returns the index of the first available unit that accepts the coffee of the given ticket, -1 if there is none
+// The reservations ahead in the line are served first, each keeps the first available unit that accepts its coffee
+// It must be called with the mutex held
+func (p *Pool[T]) free(coffee *types.Coffee, ticket uint64) int {
+	kept := make([]bool, len(p.available))
+	for _, r := range p.reservations {
+		if r.ticket >= ticket {
+			break
+		}
+		for i, item := range p.available {
+			if !kept[i] && accepts(item, r.coffee) {
+				kept[i] = true
+				break
+			}
+		}
+	}
+	for i, item := range p.available {
+		if !kept[i] && accepts(item, coffee) {
+			return i
+		}
+	}
+	return -1
+}
+
+// take takes the available unit at the index for a barista who started waiting at start
+// It must be called with the mutex held
+func (p *Pool[T]) take(i int, start time.Time) T {
+	item := p.available[i]
+	p.available = append(p.available[:i], p.available[i+1:]...)
+	p.acquired(item, start)
+	return item
+}
+
+// acquired records the acquisition of the unit by a barista who started waiting at start
+// It must be called with the mutex held
+func (p *Pool[T]) acquired(item T, start time.Time) {
+	now := time.Now()
+	p.acquisitions++
+	p.waitTime += now.Sub(start)
+	stats := p.stats[item.Tag()]
+	stats.Acquisitions++
+	stats.Busy = true
+	stats.acquiredAt = now
+}
+
+// Release returns the unit to the pool
+// It is handed to the first waiting barista whose coffee it accepts, unless a reservation ahead of the barista in the line accepts it,
+// otherwise it is available again, and the reservations waiting for the pool are woken up
+// A unit out of service stays out of the pool until the end of its outage
+func (p *Pool[T]) Release(item T) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := p.stats[item.Tag()]
+	stats.BusyTime += time.Since(stats.acquiredAt)
+	stats.Busy = false
+	if stats.OutOfService {
+		p.withdrawn[item.Tag()] = item
+		return
+	}
+	p.putBack(item)
+}
+
+// putBack makes the unit available and serves the line
+// It must be called with the mutex held
+func (p *Pool[T]) putBack(item T) {
+	p.available = append(p.available, item)
+	p.serve()
+}
+
+// serve hands the available units to the waiting baristas in the order of their tickets, each unit kept for a reservation ahead is skipped,
+// and wakes the reservations in the line up
+// It must be called with the mutex held
+func (p *Pool[T]) serve() {
+	for i := 0; i < len(p.waiters); {
+		w := p.waiters[i]
+		j := p.free(w.coffee, w.ticket)
+		if j < 0 {
+			i++
+			continue
+		}
+		p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+		w.item <- p.take(j, w.start)
+	}
+	for _, r := range p.reservations {
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// AcquireEquipment takes a unit that accepts the coffee from the pool, see Acquire
+func (p *Pool[T]) AcquireEquipment(coffee *types.Coffee) Equipment {
+	return p.Acquire(coffee)
+}
+
+// AcquireEquipmentContext takes a unit that accepts the coffee from the pool, see AcquireContext
+func (p *Pool[T]) AcquireEquipmentContext(ctx context.Context, coffee *types.Coffee) (Equipment, error) {
+	item, err := p.AcquireContext(ctx, coffee)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// acquirePatiently takes a unit that accepts the coffee from the pool, see acquire
+func (p *Pool[T]) acquirePatiently(ctx context.Context, coffee *types.Coffee, patience <-chan time.Time, impatient func()) (Equipment, error) {
+	item, err := p.acquire(ctx, coffee, patience, impatient)
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ReleaseEquipment returns a unit acquired with AcquireEquipment to the pool
+func (p *Pool[T]) ReleaseEquipment(equipment Equipment) {
+	p.Release(equipment.(T))
+}
+
+// lock locks the pool for a reservation
+func (p *Pool[T]) lock() {
+	p.mutex.Lock()
+}
+
+// unlock unlocks the pool locked for a reservation
+func (p *Pool[T]) unlock() {
+	p.mutex.Unlock()
+}
+
+// reservable returns whether a unit that accepts the coffee is available for the reservation with the ticket,
+// if not the reservation joins the line of the pool, where it stays until it leaves, and the wake channel is woken up by the releases
+// It must be called with the pool locked
+func (p *Pool[T]) reservable(coffee *types.Coffee, ticket uint64, wake chan<- struct{}) bool {
+	if p.free(coffee, ticket) >= 0 {
+		return true
+	}
+	i := sort.Search(len(p.reservations), func(i int) bool {
+		return p.reservations[i].ticket >= ticket
+	})
+	if i == len(p.reservations) || p.reservations[i].ticket != ticket {
+		p.reservations = append(p.reservations, nil)
+		copy(p.reservations[i+1:], p.reservations[i:])
+		p.reservations[i] = &pending{ticket: ticket, coffee: coffee, wake: wake}
+	}
+	return false
+}
+
+// reserved takes the unit found by reservable for the reservation with the ticket started at start, it must be called with the pool locked
+func (p *Pool[T]) reserved(coffee *types.Coffee, ticket uint64, start time.Time) Equipment {
+	return p.take(p.free(coffee, ticket), start)
+}
+
+// leave takes the reservation with the ticket out of the line of the pool, if it is in it,
+// the units kept for it go to the baristas behind it
+func (p *Pool[T]) leave(ticket uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i, r := range p.reservations {
+		if r.ticket == ticket {
+			p.reservations = append(p.reservations[:i], p.reservations[i+1:]...)
+			p.serve()
+			return
+		}
+	}
+}
+
+// timedOut counts a reservation or an acquisition that gave up or waited longer than the patience of the barista
+func (p *Pool[T]) timedOut() {
+	p.mutex.Lock()
+	p.timeouts++
+	p.mutex.Unlock()
+}
+
+// Capacity returns the number of units in the pool, busy or not
+func (p *Pool[T]) Capacity() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.items)
+}
+
+// Busy returns the number of units currently taken from the pool
+func (p *Pool[T]) Busy() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.items) - len(p.available) - len(p.withdrawn)
+}
+
+// OutOfService returns the number of units out of service, in use or not
+func (p *Pool[T]) OutOfService() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.outOfService()
+}
+
+// outOfService counts the units out of service, it must be called with the mutex held
+func (p *Pool[T]) outOfService() int {
+	count := 0
+	for _, stats := range p.stats {
+		if stats.OutOfService {
+			count++
+		}
+	}
+	return count
+}
+
+// Available returns the number of units waiting in the pool
+func (p *Pool[T]) Available() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.available)
+}
+
+// Waiting returns the number of baristas waiting for a unit, the reservations in the line included
+func (p *Pool[T]) Waiting() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return len(p.waiters) + len(p.reservations)
+}
+
+// Stats returns the statistics of the pool and of its units, in the order they were added
+func (p *Pool[T]) Stats() PoolStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	stats := PoolStats{
+		Kind:         p.kind,
+		Capacity:     len(p.items),
+		Busy:         len(p.items) - len(p.available) - len(p.withdrawn),
+		Available:    len(p.available),
+		OutOfService: p.outOfService(),
+		Waiting:      len(p.waiters) + len(p.reservations),
+		Acquisitions: p.acquisitions,
+		Timeouts:     p.timeouts,
+		WaitTime:     p.waitTime,
+		Items:        make([]ItemStats, 0, len(p.items)),
+	}
+	for _, item := range p.items {
+		stats.Items = append(stats.Items, *p.stats[item.Tag()])
+	}
+	return stats
+}
@@ -0,0 +1,144 @@
+package equipment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// oven is equipment that bakes the coffees named after its food, or any coffee if it has none
+type oven struct {
+	tag     string
+	food    string
+	started bool
+	stopped bool
+}
+
+func (o *oven) Tag() string { return o.tag }
+func (o *oven) Rate() int   { return 1 }
+func (o *oven) Start()      { o.started = true }
+func (o *oven) Stop()       { o.stopped = true }
+func (o *oven) Process(_ *types.Coffee, duration time.Duration) error {
+	time.Sleep(duration)
+	return nil
+}
+func (o *oven) Accepts(coffee *types.Coffee) bool {
+	return o.food == "" || coffee.CoffeeType().Name == o.food
+}
+
+func newOvenPool(ovens ...*oven) *Pool[*oven] {
+	pool := NewPool[*oven]("oven", len(ovens))
+	for _, o := range ovens {
+		pool.Add(o)
+	}
+	return pool
+}
+
+func coffeeNamed(name string) *types.Coffee {
+	return types.NewCoffee(types.CoffeeType{Name: name}, types.Standard, nil)
+}
+
+func TestPoolStartAndStop(t *testing.T) {
+	first, second := &oven{tag: "oven1"}, &oven{tag: "oven2"}
+	pool := newOvenPool(first, second)
+
+	pool.Start()
+	assert.True(t, first.started && second.started)
+	pool.Stop()
+	assert.True(t, first.stopped && second.stopped)
+}
+
+func TestPoolAcquireAcceptedEquipment(t *testing.T) {
+	pool := newOvenPool(&oven{tag: "croissant oven", food: "Croissant"}, &oven{tag: "any oven"})
+
+	assert.Equal(t, "any oven", pool.Acquire(coffeeNamed("Muffin")).Tag(), "The croissant oven should be skipped")
+	assert.Equal(t, "croissant oven", pool.Acquire(coffeeNamed("Croissant")).Tag())
+	assert.Equal(t, 2, pool.Capacity())
+	assert.Equal(t, 2, pool.Busy())
+	assert.Equal(t, 0, pool.Available())
+}
+
+func TestPoolAcquireTimeout(t *testing.T) {
+	pool := newOvenPool(&oven{tag: "oven1"})
+	held := pool.Acquire(nil)
+
+	_, err := pool.AcquireTimeout(nil, 0)
+	assert.ErrorIs(t, err, ErrTimeout, "No oven is available right away")
+	_, err = pool.AcquireTimeout(nil, 20*time.Millisecond)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, 0, pool.Waiting(), "The barista who timed out should not wait anymore")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pool.Release(held)
+	}()
+	acquired, err := pool.AcquireTimeout(nil, time.Second)
+	assert.NoError(t, err)
+	assert.Same(t, held, acquired)
+	assert.Equal(t, 2, pool.Stats().Timeouts)
+}
+
+func TestPoolReleaseToWaitingBarista(t *testing.T) {
+	croissantOven, anyOven := &oven{tag: "croissant oven", food: "Croissant"}, &oven{tag: "any oven"}
+	pool := newOvenPool(croissantOven, anyOven)
+	pool.Acquire(nil)
+	pool.Acquire(nil)
+
+	acquired := make(chan *oven)
+	go func() {
+		acquired <- pool.Acquire(coffeeNamed("Muffin"))
+	}()
+	assert.Eventually(t, func() bool {
+		return pool.Waiting() == 1
+	}, time.Second, time.Millisecond)
+
+	pool.Release(croissantOven)
+	assert.Equal(t, 1, pool.Available(), "The croissant oven should not be handed to the barista baking a muffin")
+	pool.Release(anyOven)
+	assert.Same(t, anyOven, <-acquired)
+}
+
+func TestPoolStats(t *testing.T) {
+	pool := newOvenPool(&oven{tag: "oven1"}, &oven{tag: "oven2"})
+
+	first := pool.Acquire(nil)
+	time.Sleep(10 * time.Millisecond)
+	pool.Release(first)
+	pool.Acquire(nil)
+
+	stats := pool.Stats()
+	assert.Equal(t, "oven", stats.Kind)
+	assert.Equal(t, 2, stats.Capacity)
+	assert.Equal(t, 1, stats.Busy)
+	assert.Equal(t, 2, stats.Acquisitions)
+	if assert.Len(t, stats.Items, 2) {
+		assert.Equal(t, "oven1", stats.Items[0].Tag)
+		assert.Equal(t, 1, stats.Items[0].Acquisitions)
+		assert.GreaterOrEqual(t, stats.Items[0].BusyTime, 10*time.Millisecond)
+		assert.False(t, stats.Items[0].Busy)
+		assert.Equal(t, 1, stats.Items[1].Acquisitions, "The oven released last goes back at the end of the pool")
+		assert.True(t, stats.Items[1].Busy)
+	}
+}
+
+func TestPoolAcquireContext(t *testing.T) {
+	pool := newOvenPool(&oven{tag: "oven1"})
+	held := pool.Acquire(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := pool.AcquireContext(ctx, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, pool.Waiting())
+
+	pool.Release(held)
+	acquired, err := pool.AcquireContext(ctx, nil)
+	assert.NoError(t, err, "An available oven is taken even if the context is done")
+	assert.Same(t, held, acquired)
+}
@@ -0,0 +1,206 @@
+package equipment
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+)
+
+// ErrBroken is returned when a unit breaks down while it processes a coffee and throws the coffee away
+var ErrBroken = errors.New("equipment broke down")
+
+// Reliable is implemented by the equipment that can break down or be maintained, the pool takes it out of service meanwhile
+// A unit without a reliability model returns nil and never stops
+type Reliable interface {
+	Reliability() *Reliability
+}
+
+// Reliability is the reliability model of a unit of equipment
+// The unit breaks down after a random operating time, the MTBF, and is out of its pool until it is repaired, after the MTTR
+// It is also taken out of its pool during its scheduled maintenance windows
+// The methods of a nil Reliability do nothing, so a unit without a reliability model never stops
+type Reliability struct {
+	kind     string
+	tag      string
+	settings config.ReliabilitySettings
+	// untilBreakdown is the operating time left before the next breakdown
+	untilBreakdown time.Duration
+	// withdraw takes the unit out of its pool until the channel is closed, it is set when the unit is added to a pool
+	withdraw    func(until <-chan struct{})
+	eventSystem monitor.EventSystemer
+	stop        chan struct{}
+	stopOnce    sync.Once
+	mutex       sync.Mutex
+}
+
+// Breakdown is a breakdown of a unit while it processes a coffee
+// After is the processing time before it breaks down, Repair the time to repair it,
+// and Abort whether the coffee is thrown away instead of waiting for the repair
+type Breakdown struct {
+	After  time.Duration
+	Repair time.Duration
+	Abort  bool
+}
+
+// NewReliability creates the reliability model of the unit of the given kind and tag
+func NewReliability(kind string, tag string, settings config.ReliabilitySettings, eventSystem monitor.EventSystemer) *Reliability {
+	reliability := &Reliability{
+		kind:        kind,
+		tag:         tag,
+		settings:    settings,
+		eventSystem: eventSystem,
+		stop:        make(chan struct{}),
+	}
+	reliability.untilBreakdown = reliability.sample(settings.MTBF)
+	return reliability
+}
+
+// attach sets how the unit is taken out of its pool
+func (r *Reliability) attach(withdraw func(until <-chan struct{})) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.withdraw = withdraw
+	r.mutex.Unlock()
+}
+
+// Operate runs the unit for the processing time of a coffee and returns the breakdown that happens meanwhile, if any
+// The unit never breaks down if the mean of its MTBF is zero
+func (r *Reliability) Operate(duration time.Duration) (Breakdown, bool) {
+	if r == nil || r.settings.MTBF.MeanSeconds <= 0 {
+		return Breakdown{}, false
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if duration < r.untilBreakdown {
+		r.untilBreakdown -= duration
+		return Breakdown{}, false
+	}
+	breakdown := Breakdown{
+		After:  r.untilBreakdown,
+		Repair: r.sample(r.settings.MTTR),
+		Abort:  r.settings.OnFailure == config.AbortOnFailure,
+	}
+	r.untilBreakdown = r.sample(r.settings.MTBF)
+	return breakdown, true
+}
+
+// Run processes a coffee on the unit for the duration and returns the time it took
+// If the unit breaks down meanwhile, the coffee waits for the repair, unless the breakdown throws it away and Run returns false
+func (r *Reliability) Run(duration time.Duration) (time.Duration, bool) {
+	breakdown, ok := r.Operate(duration)
+	if !ok {
+		time.Sleep(duration)
+		return duration, true
+	}
+	start := time.Now()
+	time.Sleep(breakdown.After)
+	repaired := r.BreakDown(breakdown)
+	if breakdown.Abort {
+		return time.Since(start), false
+	}
+	select {
+	case <-repaired:
+	case <-r.stop:
+	}
+	time.Sleep(duration - breakdown.After)
+	return time.Since(start), true
+}
+
+// BreakDown takes the unit out of its pool until it is repaired and returns a channel closed once it is repaired
+func (r *Reliability) BreakDown(breakdown Breakdown) <-chan struct{} {
+	utils.Logger().WithFields(utils.LogFields{r.kind: r.tag, "repairTime": breakdown.Repair, "aborted": breakdown.Abort}).Info("Equipment broke down")
+	return r.outage(monitor.BreakdownOutage, breakdown.Repair, breakdown.Abort)
+}
+
+// outage takes the unit out of its pool for the duration, for the reason, and returns a channel closed once it is back
+// The unit stays out if the reliability model is stopped first, the shop is closed then
+func (r *Reliability) outage(reason string, duration time.Duration, aborted bool) <-chan struct{} {
+	back := make(chan struct{})
+	r.mutex.Lock()
+	withdraw := r.withdraw
+	r.mutex.Unlock()
+	if withdraw != nil {
+		withdraw(back)
+	}
+	r.eventSystem.SendEvent(monitor.NewEvent(&monitor.EquipmentOutOfServicePayload{Kind: r.kind, Equipment: r.tag, Reason: reason, Duration: duration, Aborted: aborted}))
+
+	go func() {
+		start := time.Now()
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.stop:
+			return
+		}
+		close(back)
+		r.eventSystem.SendEvent(monitor.NewEvent(&monitor.EquipmentBackInServicePayload{Kind: r.kind, Equipment: r.tag, Reason: reason, Downtime: time.Since(start)}))
+		utils.Logger().WithFields(utils.LogFields{r.kind: r.tag, "reason": reason}).Info("Equipment is back in service")
+	}()
+	return back
+}
+
+// Start schedules the maintenance windows of the unit from now
+func (r *Reliability) Start() {
+	if r == nil {
+		return
+	}
+	for _, window := range r.settings.Maintenance {
+		go r.maintain(window)
+	}
+}
+
+// maintain takes the unit out of its pool during each occurrence of the maintenance window until the reliability model is stopped
+func (r *Reliability) maintain(window config.MaintenanceSettings) {
+	wait := seconds(window.StartSeconds)
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-r.stop:
+			timer.Stop()
+			return
+		}
+		utils.Logger().WithFields(utils.LogFields{r.kind: r.tag, "duration": seconds(window.DurationSeconds)}).Info("Equipment maintenance started")
+		r.outage(monitor.MaintenanceOutage, seconds(window.DurationSeconds), false)
+		if window.EverySeconds <= 0 {
+			return
+		}
+		wait = seconds(window.EverySeconds)
+	}
+}
+
+// Stop stops the maintenance windows and the repairs in progress
+func (r *Reliability) Stop() {
+	if r == nil {
+		return
+	}
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// sample returns a random duration of the distribution, never negative
+func (r *Reliability) sample(distribution config.DistributionSettings) time.Duration {
+	mean := distribution.MeanSeconds
+	switch distribution.Type {
+	case config.ExponentialDistribution:
+		return seconds(rand.ExpFloat64() * mean)
+	case config.NormalDistribution:
+		return seconds(math.Max(0, mean+rand.NormFloat64()*distribution.StdDevSeconds))
+	default:
+		return seconds(mean)
+	}
+}
+
+// seconds converts seconds, as written in the config file, to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,114 @@
+package equipment
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/stretchr/testify/assert"
+)
+
+// eventRecorder records the events sent to it
+type eventRecorder struct {
+	events []monitor.Event
+	mutex  sync.Mutex
+}
+
+func (r *eventRecorder) SendEvent(event monitor.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) types() []monitor.EventType {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	types := make([]monitor.EventType, 0, len(r.events))
+	for _, event := range r.events {
+		types = append(types, event.Payload.EventType())
+	}
+	return types
+}
+
+// reliableOven is an oven with a reliability model
+type reliableOven struct {
+	oven
+	reliability *Reliability
+}
+
+func (o *reliableOven) Reliability() *Reliability { return o.reliability }
+func (o *reliableOven) Start()                    { o.reliability.Start() }
+func (o *reliableOven) Stop()                     { o.reliability.Stop() }
+
+func TestReliabilityOperate(t *testing.T) {
+	reliability := NewReliability("oven", "oven1", config.ReliabilitySettings{
+		MTBF:      config.DistributionSettings{MeanSeconds: 5},
+		MTTR:      config.DistributionSettings{MeanSeconds: 2},
+		OnFailure: config.AbortOnFailure,
+	}, &eventRecorder{})
+
+	_, ok := reliability.Operate(3 * time.Second)
+	assert.False(t, ok)
+	breakdown, ok := reliability.Operate(3 * time.Second)
+	assert.True(t, ok, "The oven breaks down after 5 seconds of operation")
+	assert.Equal(t, Breakdown{After: 2 * time.Second, Repair: 2 * time.Second, Abort: true}, breakdown)
+	_, ok = reliability.Operate(4 * time.Second)
+	assert.False(t, ok, "The operating time before the next breakdown starts over")
+
+	var never *Reliability
+	_, ok = never.Operate(time.Hour)
+	assert.False(t, ok, "A unit without a reliability model never breaks down")
+}
+
+func TestReliabilityBreakdownTakesTheUnitOutOfItsPool(t *testing.T) {
+	events := &eventRecorder{}
+	broken := &reliableOven{oven: oven{tag: "oven1"}}
+	broken.reliability = NewReliability("oven", "oven1", config.ReliabilitySettings{
+		MTBF: config.DistributionSettings{MeanSeconds: 0.01},
+		MTTR: config.DistributionSettings{MeanSeconds: 0.05},
+	}, events)
+	pool := NewPool[*reliableOven]("oven", 1)
+	pool.Add(broken)
+
+	unit := pool.Acquire(nil)
+	took, ok := broken.reliability.Run(20 * time.Millisecond)
+	assert.True(t, ok, "The coffee waits for the repair")
+	assert.GreaterOrEqual(t, took, 60*time.Millisecond)
+	pool.Release(unit)
+	assert.Equal(t, 1, pool.Available(), "The oven is repaired before it is released")
+
+	pool.Acquire(nil)
+	broken.reliability.BreakDown(Breakdown{Repair: 30 * time.Millisecond})
+	pool.Release(unit)
+	assert.Equal(t, 0, pool.Available(), "The broken oven stays out of the pool")
+	assert.Equal(t, 1, pool.OutOfService())
+	_, err := pool.AcquireTimeout(nil, time.Second)
+	assert.NoError(t, err, "The oven is back once it is repaired")
+
+	stats := pool.Stats()
+	assert.Equal(t, 2, stats.Items[0].Outages)
+	assert.False(t, stats.Items[0].OutOfService)
+	assert.Equal(t, []monitor.EventType{monitor.EquipmentOutOfService, monitor.EquipmentBackInService, monitor.EquipmentOutOfService, monitor.EquipmentBackInService}, events.types())
+}
+
+func TestReliabilityMaintenance(t *testing.T) {
+	maintained := &reliableOven{oven: oven{tag: "oven1"}}
+	maintained.reliability = NewReliability("oven", "oven1", config.ReliabilitySettings{
+		Maintenance: []config.MaintenanceSettings{{StartSeconds: 0.01, DurationSeconds: 0.03}},
+	}, &eventRecorder{})
+	pool := NewPool[*reliableOven]("oven", 1)
+	pool.Add(maintained)
+	pool.Start()
+	defer pool.Stop()
+
+	assert.Eventually(t, func() bool {
+		return pool.OutOfService() == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 0, pool.Available())
+	assert.Eventually(t, func() bool {
+		return pool.Available() == 1
+	}, time.Second, time.Millisecond, "The oven is back at the end of the maintenance window")
+	assert.Equal(t, 1, pool.Stats().Items[0].Outages)
+}
@@ -0,0 +1,114 @@
+package equipment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+)
+
+// Reserve takes a unit that accepts the coffee from each of the pools at once, in the order of the pools
+// It never holds a unit while it waits for another one: the units are only taken when all the pools have one available,
+// so baristas reserving the same pools, like a brewer and a steamer, cannot deadlock each other
+// The pools are locked in the order of their kind, so the reservations always lock them in the same order
+// It blocks until all the units are available and returns the error of the context if it is done first
+// The reservation has a ticket in the order of all the waits: it joins the line of each pool that has no unit for it,
+// and the units released there go to whoever is first in the line, the reservation or a barista waiting for that pool only,
+// so the baristas who came later cannot starve it
+// A single pool is acquired like AcquireContext, with its place in the line of the waiting baristas
+func Reserve(ctx context.Context, coffee *types.Coffee, pools ...Acquirer) ([]Equipment, error) {
+	return ReservePatiently(ctx, coffee, 0, nil, pools...)
+}
+
+// ReservePatiently takes the units like Reserve, and each time it waited the patience without getting them,
+// it counts a timeout in the pools that had no unit for the coffee and calls impatient with their indexes and the time waited so far
+// The barista keeps waiting with its place in the lines of the pools, so reporting a long wait never sends it to the back
+// impatient is never called if the patience is not positive
+func ReservePatiently(ctx context.Context, coffee *types.Coffee, patience time.Duration, impatient func(waiting []int, waitTime time.Duration), pools ...Acquirer) ([]Equipment, error) {
+	start := time.Now()
+	var ticks <-chan time.Time
+	if patience > 0 && impatient != nil {
+		ticker := time.NewTicker(patience)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	if len(pools) == 1 {
+		unit, err := pools[0].acquirePatiently(ctx, coffee, ticks, func() {
+			impatient([]int{0}, time.Since(start))
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []Equipment{unit}, nil
+	}
+	locked := append([]Acquirer{}, pools...)
+	sort.Slice(locked, func(i, j int) bool {
+		return locked[i].Kind() < locked[j].Kind()
+	})
+	for i := 1; i < len(locked); i++ {
+		if locked[i].Kind() == locked[i-1].Kind() {
+			return nil, fmt.Errorf("pool %s reserved twice", locked[i].Kind())
+		}
+	}
+
+	ticket := tickets.Add(1)
+	defer func() {
+		for _, pool := range pools {
+			pool.leave(ticket)
+		}
+	}()
+	// wake is woken up by the releases of units in the pools whose line the reservation joined
+	wake := make(chan struct{}, 1)
+	for {
+		units, waiting := reserveAll(coffee, pools, locked, ticket, start, wake)
+		if units != nil {
+			return units, nil
+		}
+		select {
+		case <-wake:
+		case <-ticks:
+			for _, i := range waiting {
+				pools[i].timedOut()
+			}
+			impatient(waiting, time.Since(start))
+		case <-ctx.Done():
+			for _, i := range waiting {
+				pools[i].timedOut()
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// reserveAll takes a unit from each pool if they all have one that accepts the coffee,
+// otherwise it takes none and returns the indexes of the pools that have none
+// The reservation joins the line of the pools that have none under their locks, so no release is missed
+func reserveAll(coffee *types.Coffee, pools []Acquirer, locked []Acquirer, ticket uint64, start time.Time, wake chan<- struct{}) ([]Equipment, []int) {
+	for _, pool := range locked {
+		pool.lock()
+	}
+	defer func() {
+		for i := len(locked) - 1; i >= 0; i-- {
+			locked[i].unlock()
+		}
+	}()
+
+	var waiting []int
+	for i, pool := range pools {
+		// the reservation joins the line of every pool without a unit, so all of them are checked
+		if !pool.reservable(coffee, ticket, wake) {
+			waiting = append(waiting, i)
+		}
+	}
+	if len(waiting) > 0 {
+		return nil, waiting
+	}
+	units := make([]Equipment, len(pools))
+	for i, pool := range pools {
+		units[i] = pool.reserved(coffee, ticket, start)
+	}
+	return units, nil
+}
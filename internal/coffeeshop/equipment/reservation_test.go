@@ -0,0 +1,147 @@
+package equipment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReserveTakesAllUnitsAtOnce(t *testing.T) {
+	brewer, steamer := &oven{tag: "brewer1"}, &oven{tag: "steamer1"}
+	brewers, steamers := NewPool[*oven]("brewer", 1), NewPool[*oven]("steamer", 1)
+	brewers.Add(brewer)
+	steamers.Add(steamer)
+	held := steamers.Acquire(nil)
+
+	reserved := make(chan []Equipment)
+	go func() {
+		units, err := Reserve(context.Background(), coffeeNamed("Latte"), steamers, brewers)
+		assert.NoError(t, err)
+		reserved <- units
+	}()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 1, brewers.Available(), "The brewer should not be held while the steamer is busy")
+	other, err := brewers.AcquireTimeout(nil, 0)
+	assert.NoError(t, err)
+	brewers.Release(other)
+
+	steamers.Release(held)
+	units := <-reserved
+	if assert.Len(t, units, 2) {
+		assert.Same(t, steamer, units[0], "The units are in the order of the pools")
+		assert.Same(t, brewer, units[1])
+	}
+	assert.Equal(t, 0, brewers.Available())
+	assert.Equal(t, 0, steamers.Available())
+	assert.Equal(t, 2, steamers.Stats().Acquisitions)
+}
+
+func TestReserveTimeout(t *testing.T) {
+	brewers, steamers := newOvenPool(&oven{tag: "brewer1"}), newOvenPool(&oven{tag: "steamer1"})
+	brewers.kind, steamers.kind = "brewer", "steamer"
+	steamers.Acquire(nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := Reserve(ctx, nil, brewers, steamers)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, brewers.Available())
+	assert.Equal(t, 0, brewers.Stats().Timeouts, "The brewer was available, only the steamers timed out")
+	assert.Equal(t, 1, steamers.Stats().Timeouts)
+	assert.Equal(t, 0, brewers.Stats().Acquisitions)
+}
+
+func TestReserveSamePoolTwice(t *testing.T) {
+	pool := newOvenPool(&oven{tag: "oven1"}, &oven{tag: "oven2"})
+
+	_, err := Reserve(context.Background(), nil, pool, pool)
+	assert.EqualError(t, err, "pool oven reserved twice")
+	assert.Equal(t, 2, pool.Available())
+}
+
+func TestReservePatientlyKeepsItsPlaceInLine(t *testing.T) {
+	pool := newOvenPool(&oven{tag: "oven1"})
+	held := pool.Acquire(nil)
+
+	var waits []time.Duration
+	reserved := make(chan []Equipment)
+	go func() {
+		units, err := ReservePatiently(context.Background(), nil, 10*time.Millisecond, func(waiting []int, waitTime time.Duration) {
+			assert.Equal(t, []int{0}, waiting)
+			waits = append(waits, waitTime)
+		}, pool)
+		assert.NoError(t, err)
+		reserved <- units
+	}()
+	time.Sleep(5 * time.Millisecond)
+	go pool.Acquire(nil)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, 2, pool.Waiting(), "The impatient barista is still waiting")
+
+	pool.Release(held)
+	units := <-reserved
+	if assert.Len(t, units, 1) {
+		assert.Same(t, held, units[0], "The impatient barista is served first, it kept its place")
+	}
+	assert.GreaterOrEqual(t, len(waits), 2)
+	assert.GreaterOrEqual(t, waits[0], 10*time.Millisecond)
+	assert.Equal(t, len(waits), pool.Stats().Timeouts)
+}
+
+func TestReservePatientlyReportsEachLongWait(t *testing.T) {
+	brewers, steamers := newOvenPool(&oven{tag: "brewer1"}), newOvenPool(&oven{tag: "steamer1"})
+	brewers.kind, steamers.kind = "brewer", "steamer"
+	held := steamers.Acquire(nil)
+
+	reports := make(chan []int, 10)
+	reserved := make(chan []Equipment)
+	go func() {
+		units, err := ReservePatiently(context.Background(), nil, 10*time.Millisecond, func(waiting []int, _ time.Duration) {
+			reports <- waiting
+		}, brewers, steamers)
+		assert.NoError(t, err)
+		reserved <- units
+	}()
+	assert.Equal(t, []int{1}, <-reports, "Only the steamers had no unit")
+	<-reports
+	assert.Equal(t, 0, brewers.Stats().Timeouts, "The timeouts are only counted on the pools that had no unit")
+	assert.GreaterOrEqual(t, steamers.Stats().Timeouts, 2)
+	steamers.Release(held)
+	assert.Len(t, <-reserved, 2, "The reservation keeps waiting after reporting the long waits")
+}
+
+func TestReserveKeepsItsPlaceInTheLineOfAPool(t *testing.T) {
+	brewers, steamers := newOvenPool(&oven{tag: "brewer1"}), newOvenPool(&oven{tag: "steamer1"})
+	brewers.kind, steamers.kind = "brewer", "steamer"
+	held := brewers.Acquire(nil)
+
+	before := make(chan *oven)
+	go func() { before <- brewers.Acquire(nil) }()
+	time.Sleep(5 * time.Millisecond)
+	reserved := make(chan []Equipment)
+	go func() {
+		units, err := Reserve(context.Background(), coffeeNamed("Latte"), brewers, steamers)
+		assert.NoError(t, err)
+		reserved <- units
+	}()
+	time.Sleep(5 * time.Millisecond)
+	after := make(chan *oven)
+	go func() { after <- brewers.Acquire(nil) }()
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, 3, brewers.Waiting(), "The reservation waits in the line of the brewers")
+	assert.Equal(t, 0, steamers.Waiting(), "The reservation only joins the line of the pools without a unit")
+
+	brewers.Release(held)
+	brewer := <-before
+	assert.Same(t, held, brewer, "The barista who came before the reservation is served first")
+	brewers.Release(brewer)
+	units := <-reserved
+	assert.Len(t, units, 2, "The reservation is served before the barista who came after it")
+	assert.Equal(t, 1, brewers.Waiting())
+	assert.Equal(t, 0, steamers.Waiting(), "The reservation left the lines")
+
+	brewers.Release(units[0].(*oven))
+	assert.Same(t, held, <-after)
+}
@@ -0,0 +1,131 @@
+package equipment
+
+import (
+	"sync"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+)
+
+// Work is how a kind of equipment processes a coffee, the hooks of its Worker
+// Kind names the unit in the log lines, like grinder, and Starting, Done and Aborted are the log messages of the processing
+// Fields returns the log fields describing what the unit processes for the coffee, like the grams of beans
+// Record adds the processing time to the coffee, SetReady signals the coffee whether it is ready or thrown away
+// and Ready is the channel of the coffee it is signalled on, like (*types.Coffee).BeansReady
+type Work struct {
+	Kind     string
+	Starting string
+	Done     string
+	Aborted  string
+	Fields   func(coffee *types.Coffee) utils.LogFields
+	Record   func(coffee *types.Coffee, duration time.Duration)
+	SetReady func(coffee *types.Coffee, ready bool)
+	Ready    func(coffee *types.Coffee) chan bool
+}
+
+// Worker processes the coffees handed to a unit of equipment one at a time, each kind of unit embeds one with its Work
+// The channel of the jobs is unbuffered, so the unit blocks until the coffee it processes is ready
+// reliability is how the unit breaks down and is maintained, it never stops if it is nil
+// cleaning is how the unit slows down with use until it is cleaned, it never slows down if it is nil
+type Worker struct {
+	tag         string
+	work        Work
+	jobs        chan job
+	reliability *Reliability
+	cleaning    *Cleaning
+	stopOnce    sync.Once
+}
+
+// NewWorker creates the worker of the unit with the given tag
+func NewWorker(tag string, work Work) *Worker {
+	return &Worker{
+		tag:  tag,
+		work: work,
+		jobs: make(chan job),
+	}
+}
+
+// Tag returns the name of the unit
+func (w *Worker) Tag() string {
+	return w.tag
+}
+
+// SetReliability sets the reliability model of the unit, before it is added to its pool
+func (w *Worker) SetReliability(reliability *Reliability) {
+	w.reliability = reliability
+}
+
+// Reliability returns the reliability model of the unit, nil if it never stops
+func (w *Worker) Reliability() *Reliability {
+	return w.reliability
+}
+
+// SetCleaning sets the cleaning cycle of the unit, before it is added to its pool
+func (w *Worker) SetCleaning(cleaning *Cleaning) {
+	w.cleaning = cleaning
+}
+
+// Cleaning returns the cleaning cycle of the unit, nil if it never slows down
+func (w *Worker) Cleaning() *Cleaning {
+	return w.cleaning
+}
+
+// Start starts processing the coffees handed to the unit and schedules its maintenance windows
+func (w *Worker) Start() {
+	w.reliability.Start()
+	go func() {
+		for job := range w.jobs {
+			coffee := job.coffee
+			// the span context of the coffee ties the log lines to the order's trace
+			logger := utils.Logger().WithFields(coffee.SpanContext().LogFields()).WithFields(utils.LogFields{
+				w.work.Kind: w.tag,
+				"coffee":    coffee.CoffeeType().Name,
+				"size":      coffee.Size(),
+			})
+			if w.work.Fields != nil {
+				logger = logger.WithFields(w.work.Fields(coffee))
+			}
+			logger.Info(w.work.Starting)
+
+			// the unit may break down meanwhile, and it is slower the more it was used since it was cleaned
+			took, ok := w.reliability.Run(w.cleaning.Use(job.duration))
+			if !ok {
+				w.work.SetReady(coffee, false)
+				logger.Info(w.work.Aborted)
+				continue
+			}
+			w.work.Record(coffee, took)
+			w.work.SetReady(coffee, true)
+			logger.Info(w.work.Done)
+		}
+	}()
+}
+
+// Stop stops the unit once it has processed the last coffee handed to it, and its maintenance windows
+func (w *Worker) Stop() {
+	w.stopOnce.Do(func() { close(w.jobs) })
+	w.reliability.Stop()
+}
+
+// Submit hands the coffee to the unit, processing it takes the given duration at the full rate of the unit
+// The coffee is signalled on its ready channel once it is processed
+func (w *Worker) Submit(coffee *types.Coffee, duration time.Duration) {
+	w.jobs <- job{coffee: coffee, duration: duration}
+}
+
+// Process processes the coffee in the given duration and waits for it to be ready
+// It returns ErrBroken if the unit broke down and threw the coffee away
+func (w *Worker) Process(coffee *types.Coffee, duration time.Duration) error {
+	w.Submit(coffee, duration)
+	if !<-w.work.Ready(coffee) {
+		return ErrBroken
+	}
+	return nil
+}
+
+// job is a coffee to process and how long it takes
+type job struct {
+	coffee   *types.Coffee
+	duration time.Duration
+}
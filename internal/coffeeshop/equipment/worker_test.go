@@ -0,0 +1,62 @@
+package equipment
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// newOvenWorker creates the worker of an oven that bakes the beans of the coffees
+func newOvenWorker(tag string) *Worker {
+	return NewWorker(tag, Work{
+		Kind:     "oven",
+		Starting: "Baking coffee beans",
+		Done:     "Coffee beans are baked",
+		Aborted:  "Coffee beans are burnt",
+		Record:   (*types.Coffee).AddGrindTime,
+		SetReady: (*types.Coffee).SetBeansReady,
+		Ready:    (*types.Coffee).BeansReady,
+	})
+}
+
+func TestWorkerProcess(t *testing.T) {
+	worker := newOvenWorker("oven1")
+	worker.Start()
+	defer worker.Stop()
+
+	coffee := coffeeNamed("TestCoffee")
+	assert.NoError(t, worker.Process(coffee, 10*time.Millisecond))
+	assert.Equal(t, 10*time.Millisecond, coffee.GrindTime())
+	assert.Equal(t, "oven1", worker.Tag())
+}
+
+func TestWorkerBreakdownThrowsTheCoffeeAway(t *testing.T) {
+	worker := newOvenWorker("oven1")
+	worker.SetReliability(NewReliability("oven", "oven1", config.ReliabilitySettings{
+		MTBF:      config.DistributionSettings{MeanSeconds: 0.01},
+		MTTR:      config.DistributionSettings{MeanSeconds: 0.01},
+		OnFailure: config.AbortOnFailure,
+	}, &eventRecorder{}))
+	worker.Start()
+	defer worker.Stop()
+
+	coffee := coffeeNamed("TestCoffee")
+	assert.ErrorIs(t, worker.Process(coffee, 50*time.Millisecond), ErrBroken)
+	assert.Zero(t, coffee.GrindTime(), "The thrown away coffee is not counted as processed")
+}
+
+func TestWorkerSlowsDownWithUse(t *testing.T) {
+	worker := newOvenWorker("oven1")
+	worker.SetCleaning(NewCleaning("oven", "oven1", config.CleaningSettings{DegradationPerUse: 1}, &eventRecorder{}))
+	worker.Start()
+	defer worker.Stop()
+
+	first, second := coffeeNamed("TestCoffee"), coffeeNamed("TestCoffee")
+	assert.NoError(t, worker.Process(first, 10*time.Millisecond))
+	assert.NoError(t, worker.Process(second, 10*time.Millisecond))
+	assert.Equal(t, 10*time.Millisecond, first.GrindTime())
+	assert.Equal(t, 20*time.Millisecond, second.GrindTime(), "The second use takes twice as long")
+}
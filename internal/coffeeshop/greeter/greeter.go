@@ -4,6 +4,8 @@ import (
 	"container/heap"
 
 	cashier2 "github.com/s3ndd/coffeeshop/internal/coffeeshop/cashier"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/tracing"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 )
@@ -12,28 +14,44 @@ import (
 type Greeter struct {
 	id          int
 	cashierPool *cashier2.CashierPool
+	eventSystem monitor.EventSystemer
 }
 
 // NewGreeter creates a new greeter
 // cashierPool is a shared resource between all greeters
-func NewGreeter(id int, cashierPool *cashier2.CashierPool) *Greeter {
+func NewGreeter(id int, cashierPool *cashier2.CashierPool, eventSystem monitor.EventSystemer) *Greeter {
 	return &Greeter{
 		id:          id,
 		cashierPool: cashierPool,
+		eventSystem: eventSystem,
 	}
 }
 
 // Greet assigns the customer to the cashier with the shortest queue and logs the assignment
 func (g *Greeter) Greet(customer *types.Customer) {
+	span := tracing.GlobalTracer().StartSpan(customer.SpanContext(), "greet")
+	span.SetAttribute("greeter", g.id)
+	defer span.End()
+
+	customerRef := monitor.NewCustomerRef(customer)
+	// send event to monitor
+	g.eventSystem.SendEvent(monitor.NewEvent(&monitor.CustomerGreetedPayload{CustomerRef: customerRef, Greeter: g.id}))
+
 	// Assign the customer to the cashier with the shortest queue
 	cashier := heap.Pop(g.cashierPool).(*cashier2.Cashier)
 	cashier.ServeCustomer(customer)
 	// Return the cashier to the pool
 	heap.Push(g.cashierPool, cashier)
 
-	utils.Logger().WithFields(utils.LogFields{
+	g.eventSystem.SendEvent(monitor.NewEvent(&monitor.CustomerAssignedToCashierPayload{
+		CustomerRef: customerRef,
+		Greeter:     g.id,
+		Cashier:     cashier.ID(),
+		QueueSize:   cashier.CustomerQueueSize(),
+	}))
+
+	customer.Logger().WithFields(utils.LogFields{
 		"greeter":   g.id,
-		"customer":  customer.Name(),
 		"cashier":   cashier.ID(),
 		"queueSize": cashier.CustomerQueueSize(),
 	}).Info("Greeter assigned customer to cashier")
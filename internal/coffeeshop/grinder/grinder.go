@@ -3,54 +3,109 @@ package grinder
 import (
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 	"github.com/shopspring/decimal"
 )
 
 // Grinder represents a coffee grinder
-// tag is the name of the grinder
+// Its worker grinds one coffee at a time, the grinder blocks until the coffee is ground
 // gramsPerSecond is the number of grams that can be ground per second
-// grindingChannel is the channel that receives the coffee to be ground
-// the grinderChannel is a unbuffered channel, so the grinder will block until the coffee is ground
+// beans are the beans the grinder handles, by name, it handles any beans if there are none
+// The grinder may break down and slow down with use, see equipment.Worker
 type Grinder struct {
-	tag             string
-	gramsPerSecond  int
-	grindingChannel chan *types.Coffee
+	*equipment.Worker
+	gramsPerSecond int
+	beans          map[string]config.BeanSettings
 }
 
 // NewGrinder creates a new coffee grinder
-func NewGrinder(tag string, gramsPerSecond int) *Grinder {
-	return &Grinder{
-		tag:             tag,
-		gramsPerSecond:  gramsPerSecond,
-		grindingChannel: make(chan *types.Coffee),
+// The grinder only handles the given beans, or any beans if none are given
+func NewGrinder(tag string, gramsPerSecond int, beans ...config.BeanSettings) *Grinder {
+	grinder := &Grinder{
+		gramsPerSecond: gramsPerSecond,
+		beans:          make(map[string]config.BeanSettings, len(beans)),
 	}
+	grinder.Worker = equipment.NewWorker(tag, equipment.Work{
+		Kind:     "grinder",
+		Starting: "Grinding coffee beans",
+		Done:     "Coffee beans are ground",
+		Aborted:  "Coffee beans are thrown away, the grinder broke down",
+		Fields:   grinder.fields,
+		Record:   (*types.Coffee).AddGrindTime,
+		SetReady: (*types.Coffee).SetBeansReady,
+		Ready:    (*types.Coffee).BeansReady,
+	})
+	for _, bean := range beans {
+		grinder.beans[bean.Name] = bean
+	}
+	return grinder
+}
+
+// fields returns the log fields of the beans of the coffee and the rate the grinder grinds them at
+func (g *Grinder) fields(coffee *types.Coffee) utils.LogFields {
+	fields := utils.LogFields{"beans": coffee.BeansNeeded(), "rate": g.EffectiveRate()}
+	if bean, ok := g.beans[coffee.CoffeeType().Bean]; ok {
+		fields["bean"] = bean.Name
+		fields["grindSetting"] = bean.GrindSetting
+	}
+	return fields
+}
+
+// Handles returns whether the grinder can grind the given beans
+// Any grinder can grind a coffee that does not name its beans
+func (g *Grinder) Handles(bean string) bool {
+	if bean == "" || len(g.beans) == 0 {
+		return true
+	}
+	_, ok := g.beans[bean]
+	return ok
+}
+
+// Accepts returns whether the grinder can grind the beans of the coffee
+func (g *Grinder) Accepts(coffee *types.Coffee) bool {
+	return g.Handles(coffee.CoffeeType().Bean)
 }
 
-// Start starts the grinder
-func (g *Grinder) Start() {
-	logger := utils.Logger().WithField("grinder", g.tag)
-	go func() {
-		for coffee := range g.grindingChannel {
-			logger = logger.WithFields(utils.LogFields{
-				"coffee": coffee.CoffeeType().Name,
-				"size":   coffee.Size(),
-				"beans":  coffee.BeansNeeded(),
-			})
-			logger.Info("Grinding coffee beans")
-
-			grindingTime := time.Duration(coffee.BeansNeeded().Div(decimal.NewFromInt(int64(g.gramsPerSecond))).IntPart()) * time.Second
-			// Simulate the grinding process
-			time.Sleep(grindingTime)
-			coffee.SetGrindTime(grindingTime)
-			coffee.SetBeansReady(true)
-			logger.Info("Coffee beans are ground")
-		}
-	}()
-}
-
-// Grind adds the coffee to the grinder's grinding channel
+// Rate returns the grams of beans the grinder grinds per second
+func (g *Grinder) Rate() int {
+	return g.gramsPerSecond
+}
+
+// EffectiveRate returns the grams of beans the grinder grinds per second now, slowed down by its uses since it was cleaned
+func (g *Grinder) EffectiveRate() float64 {
+	return g.Cleaning().Rate(g.gramsPerSecond)
+}
+
+// Grind hands the coffee to the grinder, it takes the time to grind its beans at the rate of the grinder
 func (g *Grinder) Grind(coffee *types.Coffee) {
-	g.grindingChannel <- coffee
+	g.GrindFor(coffee, time.Duration(coffee.BeansNeeded().Div(decimal.NewFromInt(int64(g.gramsPerSecond))).IntPart())*time.Second)
+}
+
+// GrindFor hands the coffee to the grinder, grinding it takes the given duration, like a step of a recipe
+func (g *Grinder) GrindFor(coffee *types.Coffee, duration time.Duration) {
+	g.Submit(coffee, duration)
+}
+
+// Acquired returns the event of a barista getting the grinder for the order
+func (g *Grinder) Acquired(order *types.Order, barista int, waitTime time.Duration) monitor.Payload {
+	return &monitor.GrinderAcquiredPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Grinder: g.Tag(), Bean: order.Coffee().CoffeeType().Bean, WaitTime: waitTime}
+}
+
+// Started returns the event of the grinder starting to grind the beans of the order
+func (g *Grinder) Started(order *types.Order, barista int) monitor.Payload {
+	return &monitor.GrindStartedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Grinder: g.Tag(), Beans: order.Coffee().BeansNeeded()}
+}
+
+// Finished returns the event of the beans of the order being ground
+func (g *Grinder) Finished(order *types.Order, barista int) monitor.Payload {
+	return &monitor.GrindFinishedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Grinder: g.Tag(), GrindTime: order.Coffee().GrindTime()}
+}
+
+// Released returns the event of a barista returning the grinder to the pool
+func (g *Grinder) Released(order *types.Order, barista int) monitor.Payload {
+	return &monitor.GrinderReleasedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Grinder: g.Tag()}
 }
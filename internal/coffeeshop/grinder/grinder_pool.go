@@ -1,39 +1,15 @@
 package grinder
 
 import (
-	"sync"
-
-	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
 )
 
 // GrinderPool is a pool of grinders
-type GrinderPool chan *Grinder
+// A grinder is acquired for a coffee, only a grinder that handles its beans is handed out
+type GrinderPool = equipment.Pool[*Grinder]
 
 // NewGrinderPool creates a new grinder pool
-func NewGrinderPool(size int) GrinderPool {
-	return make(GrinderPool, size)
-}
-
-// AddGrinder adds a grinder to the pool
-func (gp GrinderPool) AddGrinder(grinder *Grinder) {
-	gp <- grinder
-}
-
-// Start starts all grinders in the pool
-// It waits for all grinders to be started
-func (gp GrinderPool) Start() {
-	wg := &sync.WaitGroup{}
-	for i := 0; i < len(gp); i++ {
-		grinder := <-gp
-		wg.Add(1)
-		go func(g *Grinder) {
-			defer wg.Done()
-			g.Start()
-		}(grinder)
-		gp <- grinder
-	}
-	// Wait for all grinders to be started
-	wg.Wait()
-
-	utils.Logger().Info("All grinders are started")
+// size is the number of grinders expected in the pool
+func NewGrinderPool(size int) *GrinderPool {
+	return equipment.NewPool[*Grinder]("grinder", size)
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/config"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 	"github.com/stretchr/testify/assert"
@@ -11,16 +12,18 @@ import (
 
 func TestGrinderPoolCreation(t *testing.T) {
 	grinderPool := NewGrinderPool(2)
-	assert.Equal(t, 2, cap(grinderPool), "Grinder pool should have a capacity of 2")
+	grinderPool.Add(NewGrinder("testGrinder1", 10))
+	grinderPool.Add(NewGrinder("testGrinder2", 12))
+	assert.Equal(t, 2, grinderPool.Capacity(), "Grinder pool should have a capacity of 2")
 }
 
 func TestGrinderPoolAddGrinder(t *testing.T) {
 	grinderPool := NewGrinderPool(1)
 	grinder := NewGrinder("testGrinder", 10)
 
-	grinderPool.AddGrinder(grinder)
+	grinderPool.Add(grinder)
 
-	assert.Equal(t, 1, len(grinderPool), "Grinder pool should have 1 grinder")
+	assert.Equal(t, 1, grinderPool.Available(), "Grinder pool should have 1 grinder")
 }
 
 func TestGrinderPoolStart(t *testing.T) {
@@ -28,8 +31,8 @@ func TestGrinderPoolStart(t *testing.T) {
 	grinder1 := NewGrinder("testGrinder1", 10)
 	grinder2 := NewGrinder("testGrinder2", 12)
 
-	grinderPool.AddGrinder(grinder1)
-	grinderPool.AddGrinder(grinder2)
+	grinderPool.Add(grinder1)
+	grinderPool.Add(grinder2)
 
 	grinderPool.Start()
 
@@ -47,13 +50,13 @@ func TestGrinderPoolStart(t *testing.T) {
 		SizeInOunces:      12,
 	}, types.Standard, []string{})
 
-	grinder1ToTest := <-grinderPool
+	grinder1ToTest := grinderPool.Acquire(coffee1)
 	grinder1ToTest.Grind(coffee1)
-	grinderPool <- grinder1ToTest
+	grinderPool.Release(grinder1ToTest)
 
-	grinder2ToTest := <-grinderPool
+	grinder2ToTest := grinderPool.Acquire(coffee2)
 	grinder2ToTest.Grind(coffee2)
-	grinderPool <- grinder2ToTest
+	grinderPool.Release(grinder2ToTest)
 
 	// Give some time for grinding
 	time.Sleep(2 * time.Second)
@@ -64,3 +67,53 @@ func TestGrinderPoolStart(t *testing.T) {
 	isBeansReady2 := <-coffee2.BeansReady()
 	assert.True(t, isBeansReady2, "The coffee beans for coffee2 should be ground")
 }
+
+func TestGrinderPoolBusy(t *testing.T) {
+	grinderPool := NewGrinderPool(2)
+	grinderPool.Add(NewGrinder("testGrinder1", 10))
+	grinderPool.Add(NewGrinder("testGrinder2", 12))
+	assert.Equal(t, 0, grinderPool.Busy(), "No grinder should be busy")
+
+	grinder := grinderPool.Acquire(nil)
+	assert.Equal(t, 1, grinderPool.Busy(), "The grinder taken from the pool should be busy")
+
+	grinderPool.Release(grinder)
+	assert.Equal(t, 0, grinderPool.Busy(), "No grinder should be busy once returned")
+}
+
+func TestGrinderPoolAcquireCompatibleGrinder(t *testing.T) {
+	grinderPool := NewGrinderPool(2)
+	grinderPool.Add(NewGrinder("espressoGrinder", 10, config.BeanSettings{Name: "espresso", GrindSetting: "fine"}))
+	grinderPool.Add(NewGrinder("decafGrinder", 10, config.BeanSettings{Name: "decaf", GrindSetting: "medium"}))
+
+	assert.Equal(t, "decafGrinder", grinderPool.Acquire(coffeeWithBeans("decaf")).Tag(), "The espresso grinder should be skipped")
+	assert.Equal(t, "espressoGrinder", grinderPool.Acquire(coffeeWithBeans("")).Tag(), "Any grinder should do for a coffee without beans")
+}
+
+func TestGrinderPoolReleaseToWaitingBarista(t *testing.T) {
+	grinderPool := NewGrinderPool(2)
+	grinderPool.Add(NewGrinder("espressoGrinder", 10, config.BeanSettings{Name: "espresso"}))
+	grinderPool.Add(NewGrinder("decafGrinder", 10, config.BeanSettings{Name: "decaf"}))
+	espressoGrinder := grinderPool.Acquire(coffeeWithBeans("espresso"))
+	decafGrinder := grinderPool.Acquire(coffeeWithBeans("decaf"))
+
+	acquired := make(chan *Grinder)
+	go func() {
+		acquired <- grinderPool.Acquire(coffeeWithBeans("espresso"))
+	}()
+	// wait for the barista to queue for a grinder
+	assert.Eventually(t, func() bool {
+		return grinderPool.Waiting() == 1
+	}, time.Second, time.Millisecond)
+
+	grinderPool.Release(decafGrinder)
+	assert.Equal(t, 1, grinderPool.Available(), "The decaf grinder should not be handed to the barista waiting for espresso")
+	grinderPool.Release(espressoGrinder)
+	assert.Same(t, espressoGrinder, <-acquired)
+	assert.Equal(t, 1, grinderPool.Busy())
+}
+
+// coffeeWithBeans returns a coffee made with the beans of the given name
+func coffeeWithBeans(bean string) *types.Coffee {
+	return types.NewCoffee(types.CoffeeType{Name: "TestCoffee", Bean: bean, SizeInOunces: 12}, types.Standard, nil)
+}
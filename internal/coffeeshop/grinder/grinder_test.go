@@ -4,6 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
 	"github.com/s3ndd/coffeeshop/internal/types"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 	"github.com/stretchr/testify/assert"
@@ -111,3 +114,49 @@ func TestGrindingMultipleCoffees(t *testing.T) {
 	isBeansReady2 := <-coffee2.BeansReady()
 	assert.True(t, isBeansReady2, "The coffee beans for coffee2 should be ground")
 }
+
+// discardEvents drops the events sent to it
+type discardEvents struct{}
+
+func (discardEvents) SendEvent(monitor.Event) {}
+
+func TestGrinderBreakdownThrowsTheBeansAway(t *testing.T) {
+	grinder := NewGrinder("testGrinder", 100)
+	grinder.SetReliability(equipment.NewReliability("grinder", "testGrinder", config.ReliabilitySettings{
+		MTBF:      config.DistributionSettings{MeanSeconds: 0.01},
+		MTTR:      config.DistributionSettings{MeanSeconds: 0.01},
+		OnFailure: config.AbortOnFailure,
+	}, discardEvents{}))
+	grinder.Start()
+	defer grinder.Stop()
+
+	coffee := types.NewCoffee(types.CoffeeType{
+		Name:              "TestCoffee",
+		BeansToWaterRatio: utils.FloatToDecimal(0.5),
+		Price:             utils.FloatToDecimal(1.0),
+		SizeInOunces:      12,
+	}, types.Standard, []string{})
+
+	err := grinder.Process(coffee, 50*time.Millisecond)
+	assert.ErrorIs(t, err, equipment.ErrBroken)
+	assert.Zero(t, coffee.GrindTime(), "The thrown away beans are not counted as ground")
+}
+
+func TestGrinderSlowsDownUntilItIsCleaned(t *testing.T) {
+	grinder := NewGrinder("testGrinder", 10)
+	grinder.SetCleaning(equipment.NewCleaning("grinder", "testGrinder", config.CleaningSettings{DegradationPerUse: 1}, discardEvents{}))
+	grinder.Start()
+	defer grinder.Stop()
+
+	coffee := types.NewCoffee(types.CoffeeType{
+		Name:              "TestCoffee",
+		BeansToWaterRatio: utils.FloatToDecimal(0.5),
+		Price:             utils.FloatToDecimal(1.0),
+		SizeInOunces:      12,
+	}, types.Standard, []string{})
+
+	assert.NoError(t, grinder.Process(coffee, 20*time.Millisecond))
+	assert.Equal(t, 5.0, grinder.EffectiveRate(), "The grinder runs at half its rate after a use")
+	assert.NoError(t, grinder.Process(coffee, 20*time.Millisecond))
+	assert.GreaterOrEqual(t, coffee.GrindTime(), 60*time.Millisecond, "The second grind takes twice as long")
+}
@@ -0,0 +1,73 @@
+package icedispenser
+
+import (
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/shopspring/decimal"
+)
+
+// IceDispenser represents an ice dispenser
+// Its worker dispenses the ice of one iced coffee at a time, the ice dispenser blocks until the ice is dispensed
+// ouncesIcePerSecond is the number of ounces of ice that can be dispensed per second
+type IceDispenser struct {
+	*equipment.Worker
+	ouncesIcePerSecond int
+}
+
+// NewIceDispenser creates a new ice dispenser
+func NewIceDispenser(tag string, ouncesIcePerSecond int) *IceDispenser {
+	return &IceDispenser{
+		Worker: equipment.NewWorker(tag, equipment.Work{
+			Kind:     "iceDispenser",
+			Starting: "Dispensing ice",
+			Done:     "Ice is dispensed",
+			Aborted:  "Ice is thrown away, the ice dispenser broke down",
+			Fields: func(coffee *types.Coffee) utils.LogFields {
+				return utils.LogFields{"ice": coffee.IceNeeded()}
+			},
+			Record:   (*types.Coffee).AddIceTime,
+			SetReady: (*types.Coffee).SetIceReady,
+			Ready:    (*types.Coffee).IceReady,
+		}),
+		ouncesIcePerSecond: ouncesIcePerSecond,
+	}
+}
+
+// Rate returns the ounces of ice the ice dispenser dispenses per second
+func (d *IceDispenser) Rate() int {
+	return d.ouncesIcePerSecond
+}
+
+// Dispense hands the coffee to the ice dispenser, it takes the time to dispense its ice at the rate of the ice dispenser
+func (d *IceDispenser) Dispense(coffee *types.Coffee) {
+	d.DispenseFor(coffee, time.Duration(coffee.IceNeeded().Div(decimal.NewFromInt(int64(d.ouncesIcePerSecond))).IntPart())*time.Second)
+}
+
+// DispenseFor hands the coffee to the ice dispenser, dispensing its ice takes the given duration, like a step of a recipe
+func (d *IceDispenser) DispenseFor(coffee *types.Coffee, duration time.Duration) {
+	d.Submit(coffee, duration)
+}
+
+// Acquired returns the event of a barista getting the ice dispenser for the order
+func (d *IceDispenser) Acquired(order *types.Order, barista int, waitTime time.Duration) monitor.Payload {
+	return &monitor.IceDispenserAcquiredPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, IceDispenser: d.Tag(), WaitTime: waitTime}
+}
+
+// Started returns nil, the ice dispenser only reports the ice once it is dispensed
+func (d *IceDispenser) Started(*types.Order, int) monitor.Payload {
+	return nil
+}
+
+// Finished returns the event of the ice of the order being dispensed
+func (d *IceDispenser) Finished(order *types.Order, barista int) monitor.Payload {
+	return &monitor.IceDispensedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, IceDispenser: d.Tag(), Ice: order.Coffee().IceNeeded(), IceTime: order.Coffee().IceTime()}
+}
+
+// Released returns the event of a barista returning the ice dispenser to the pool
+func (d *IceDispenser) Released(order *types.Order, barista int) monitor.Payload {
+	return &monitor.IceDispenserReleasedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, IceDispenser: d.Tag()}
+}
@@ -0,0 +1,14 @@
+package icedispenser
+
+import (
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
+)
+
+// IceDispenserPool is a pool of ice dispensers
+type IceDispenserPool = equipment.Pool[*IceDispenser]
+
+// NewIceDispenserPool creates a new ice dispenser pool
+// size is the number of ice dispensers expected in the pool
+func NewIceDispenserPool(size int) *IceDispenserPool {
+	return equipment.NewPool[*IceDispenser]("iceDispenser", size)
+}
@@ -0,0 +1,21 @@
+package icedispenser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIceDispenserPoolBusy(t *testing.T) {
+	dispenserPool := NewIceDispenserPool(2)
+	dispenserPool.Add(NewIceDispenser("testIceDispenser1", 4))
+	dispenserPool.Add(NewIceDispenser("testIceDispenser2", 3))
+	assert.Equal(t, 2, dispenserPool.Available(), "Ice dispenser pool should have 2 ice dispensers")
+	assert.Equal(t, 0, dispenserPool.Busy(), "No ice dispenser should be busy")
+
+	dispenser := dispenserPool.Acquire(nil)
+	assert.Equal(t, 1, dispenserPool.Busy(), "The ice dispenser taken from the pool should be busy")
+
+	dispenserPool.Release(dispenser)
+	assert.Equal(t, 0, dispenserPool.Busy(), "No ice dispenser should be busy once returned")
+}
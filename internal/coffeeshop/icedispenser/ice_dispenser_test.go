@@ -0,0 +1,55 @@
+package icedispenser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIceDispenserCreation(t *testing.T) {
+	dispenser := NewIceDispenser("testIceDispenser", 4)
+	assert.NotNil(t, dispenser, "Ice dispenser should not be nil")
+}
+
+func TestIceDispenserStart(t *testing.T) {
+	dispenser := NewIceDispenser("testIceDispenser", 4)
+	dispenser.Start()
+
+	coffee := types.NewCoffee(types.CoffeeType{
+		Name:              "TestIcedLatte",
+		BeansToWaterRatio: utils.FloatToDecimal(0.16),
+		Price:             utils.FloatToDecimal(4),
+		SizeInOunces:      12,
+		Iced:              true,
+		IceInOunces:       utils.FloatToDecimal(8),
+	}, types.Large, nil)
+
+	dispenser.Dispense(coffee)
+
+	isIceReady := <-coffee.IceReady()
+	assert.True(t, isIceReady, "The ice should be dispensed")
+	assert.Equal(t, 2*time.Second, coffee.IceTime(), "10oz of ice should take 2 whole seconds at 4oz per second")
+}
+
+func TestIceDispenserOnlyReportsTheDispensedIce(t *testing.T) {
+	dispenser := NewIceDispenser("testIceDispenser", 4)
+	assert.Nil(t, dispenser.Started(nil, 1), "Dispensing the ice has no start event")
+}
+
+func TestIceDispenserDispenseForAStep(t *testing.T) {
+	dispenser := NewIceDispenser("testIceDispenser", 4)
+	dispenser.Start()
+	defer dispenser.Stop()
+
+	coffee := types.NewCoffee(types.CoffeeType{
+		Name:        "TestColdBrew",
+		Iced:        true,
+		IceInOunces: utils.FloatToDecimal(6),
+	}, types.Standard, nil)
+
+	assert.NoError(t, dispenser.Process(coffee, 10*time.Millisecond))
+	assert.Equal(t, 10*time.Millisecond, coffee.IceTime(), "A step of a recipe takes its own duration, whatever the ice")
+}
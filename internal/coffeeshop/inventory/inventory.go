@@ -0,0 +1,368 @@
+package inventory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/shopspring/decimal"
+)
+
+// The items used by every coffee, the other items are used by the extras of the same name
+const (
+	// Beans are the coffee beans, in grams
+	Beans = "beans"
+	// Cups are the cups the coffees are served in
+	Cups = "cups"
+)
+
+// Inventoryer reserves the ingredients and supplies of an order when it is taken, consumes them as it is prepared,
+// and charges the ones thrown away when equipment breaks down
+type Inventoryer interface {
+	Reserve(order *types.Order) error
+	Consume(order *types.Order, item string)
+	Waste(order *types.Order, item string)
+	Release(order *types.Order)
+}
+
+// OutOfStockError is returned when an order needs an item that is out of stock and has no substitute
+type OutOfStockError struct {
+	Item string
+}
+
+// Error returns the item out of stock
+func (e *OutOfStockError) Error() string {
+	return fmt.Sprintf("%s out of stock", e.Item)
+}
+
+// item is an item of the inventory
+// stock is what is in the shop, reserved is the part of it set aside for the orders taken but not prepared yet
+// portion is the amount used by an extra or a cup, one unit if it is not configured
+type item struct {
+	settings   config.InventoryItemSettings
+	portion    decimal.Decimal
+	stock      decimal.Decimal
+	reserved   decimal.Decimal
+	outOfStock bool
+}
+
+// available returns the stock that is not reserved
+func (it *item) available() decimal.Decimal {
+	return it.stock.Sub(it.reserved)
+}
+
+// withWaste returns the amount taken from the stock to put the given amount in an order
+func (it *item) withWaste(amount decimal.Decimal) decimal.Decimal {
+	return amount.Add(amount.Mul(it.settings.WasteRatio))
+}
+
+// Inventory is the stock of ingredients and supplies of the coffee shop
+// The items are reserved when an order is taken, so an order that is accepted never runs out while it is prepared,
+// and consumed by the baristas as they prepare it
+// When the stock left of an item falls to its reorder point, a purchase order is placed with the supplier,
+// and the delivery restocks the item when it is received
+// The items that are not configured are never out of stock
+type Inventory struct {
+	items map[string]*item
+	// reservations are the amounts set aside for each order, without the waste, by order ID and item
+	reservations map[int64]map[string]decimal.Decimal
+	// purchaseOrders are the purchase orders not delivered yet, by item, there is at most one per item
+	purchaseOrders map[string]PurchaseOrder
+	supplier       *Supplier
+	closed         bool
+	eventSystem    monitor.EventSystemer
+	mutex          sync.Mutex
+}
+
+// NewInventory creates the inventory with the starting stock of each item
+func NewInventory(settings []config.InventoryItemSettings, eventSystem monitor.EventSystemer) *Inventory {
+	inventory := &Inventory{
+		items:          make(map[string]*item, len(settings)),
+		reservations:   make(map[int64]map[string]decimal.Decimal),
+		purchaseOrders: make(map[string]PurchaseOrder),
+		supplier:       NewSupplier(),
+		eventSystem:    eventSystem,
+	}
+	for _, s := range settings {
+		portion := s.Portion
+		if portion.IsZero() {
+			portion = decimal.NewFromInt(1)
+		}
+		inventory.items[s.Name] = &item{
+			settings: s,
+			portion:  portion,
+			stock:    s.StartingStock,
+		}
+	}
+	return inventory
+}
+
+// Reserve sets aside the ingredients and supplies of the order
+// An extra out of stock is replaced by its substitute, the coffee of the order is updated accordingly
+// It returns an OutOfStockError if an item is out of stock and has no substitute, nothing is reserved then
+func (inv *Inventory) Reserve(order *types.Order) error {
+	inv.mutex.Lock()
+	events, err := inv.reserve(order)
+	inv.mutex.Unlock()
+
+	inv.send(events)
+	return err
+}
+
+// reserve is Reserve without locking, it returns the events to send once the lock is released
+func (inv *Inventory) reserve(order *types.Order) ([]monitor.Event, error) {
+	var events []monitor.Event
+	logger := order.Logger()
+	coffee := order.Coffee()
+	needs := inv.needs(coffee)
+	for _, name := range []string{Beans, Cups} {
+		if it, ok := inv.items[name]; ok && it.available().LessThan(needs[name]) {
+			events = append(events, inv.runOut(it, logger)...)
+			return events, &OutOfStockError{Item: name}
+		}
+	}
+
+	for _, extra := range coffee.Extras() {
+		it, ok := inv.items[extra]
+		if !ok || it.available().GreaterThanOrEqual(needs[extra]) {
+			continue
+		}
+		events = append(events, inv.runOut(it, logger)...)
+		substitute := it.settings.Substitute
+		if substitute == "" {
+			return events, &OutOfStockError{Item: extra}
+		}
+		if substituteItem, ok := inv.items[substitute]; ok {
+			if substituteItem.available().LessThan(needs[substitute].Add(substituteItem.withWaste(substituteItem.portion))) {
+				events = append(events, inv.runOut(substituteItem, logger)...)
+				return events, &OutOfStockError{Item: extra}
+			}
+		}
+		coffee.SubstituteExtra(extra, substitute)
+		needs = inv.needs(coffee)
+		events = append(events, monitor.NewEvent(&monitor.IngredientSubstitutedPayload{
+			OrderRef:   monitor.NewOrderRef(order),
+			Item:       extra,
+			Substitute: substitute,
+		}))
+		order.Logger().WithFields(utils.LogFields{"item": extra, "substitute": substitute}).Info("Extra substituted")
+	}
+
+	amounts := make(map[string]decimal.Decimal, len(needs))
+	for name, need := range needs {
+		it := inv.items[name]
+		it.reserved = it.reserved.Add(need)
+		amounts[name] = inv.amount(name, coffee)
+		if it.available().LessThanOrEqual(it.settings.ReorderPoint) {
+			events = append(events, inv.reorder(it, logger)...)
+		}
+	}
+	inv.reservations[order.ID()] = amounts
+	return events, nil
+}
+
+// needs returns the amount of each configured item taken from the stock to prepare the coffee, including the waste
+func (inv *Inventory) needs(coffee *types.Coffee) map[string]decimal.Decimal {
+	needs := make(map[string]decimal.Decimal)
+	for _, name := range append([]string{Beans, Cups}, coffee.Extras()...) {
+		if it, ok := inv.items[name]; ok {
+			// amount counts every extra of the same name at once
+			needs[name] = it.withWaste(inv.amount(name, coffee))
+		}
+	}
+	return needs
+}
+
+// amount returns the amount of the item that goes into the coffee, without the waste
+func (inv *Inventory) amount(name string, coffee *types.Coffee) decimal.Decimal {
+	if name == Beans {
+		return coffee.BeansNeeded()
+	}
+	count := 0
+	if name == Cups {
+		count = 1
+	}
+	for _, extra := range coffee.Extras() {
+		if extra == name {
+			count++
+		}
+	}
+	return inv.items[name].portion.Mul(decimal.NewFromInt(int64(count)))
+}
+
+// Consume takes the item reserved for the order out of the stock
+// It does nothing if nothing was reserved, for example if the item is not configured or was already consumed
+func (inv *Inventory) Consume(order *types.Order, name string) {
+	inv.mutex.Lock()
+	amounts := inv.reservations[order.ID()]
+	amount, ok := amounts[name]
+	if !ok {
+		inv.mutex.Unlock()
+		return
+	}
+	delete(amounts, name)
+	if len(amounts) == 0 {
+		delete(inv.reservations, order.ID())
+	}
+	it := inv.items[name]
+	waste := amount.Mul(it.settings.WasteRatio)
+	it.stock = it.stock.Sub(amount).Sub(waste)
+	it.reserved = it.reserved.Sub(amount).Sub(waste)
+	stock := it.stock
+	inv.mutex.Unlock()
+
+	inv.send([]monitor.Event{monitor.NewEvent(&monitor.IngredientConsumedPayload{
+		OrderRef: monitor.NewOrderRef(order),
+		Item:     name,
+		Amount:   amount,
+		Waste:    waste,
+		Stock:    stock,
+	})})
+}
+
+// Waste takes another portion of the item for the order out of the stock, as waste, when the portion used was thrown away,
+// for example the beans of a coffee thrown away by a brewer that broke down, the order is prepared again with a new portion
+// The new portion is not reserved, so it is taken from what is available, never from the stock reserved for other orders
+// It does nothing if the item is not configured
+func (inv *Inventory) Waste(order *types.Order, name string) {
+	inv.mutex.Lock()
+	it, ok := inv.items[name]
+	if !ok {
+		inv.mutex.Unlock()
+		return
+	}
+	waste := decimal.Min(it.withWaste(inv.amount(name, order.Coffee())), decimal.Max(it.available(), decimal.Zero))
+	it.stock = it.stock.Sub(waste)
+	stock := it.stock
+	events := []monitor.Event{monitor.NewEvent(&monitor.IngredientConsumedPayload{
+		OrderRef: monitor.NewOrderRef(order),
+		Item:     name,
+		Waste:    waste,
+		Stock:    stock,
+	})}
+	if it.available().LessThanOrEqual(it.settings.ReorderPoint) {
+		events = append(events, inv.reorder(it, order.Logger())...)
+	}
+	inv.mutex.Unlock()
+
+	order.Logger().WithFields(utils.LogFields{"item": name, "waste": waste, "stock": stock}).Info("Item thrown away")
+	inv.send(events)
+}
+
+// Release gives back the items reserved for an order that is given up and not consumed yet, so other orders can have them
+func (inv *Inventory) Release(order *types.Order) {
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	for name, amount := range inv.reservations[order.ID()] {
+		it := inv.items[name]
+		it.reserved = it.reserved.Sub(it.withWaste(amount))
+	}
+	delete(inv.reservations, order.ID())
+}
+
+// Stock returns the stock of the item and whether it is configured
+func (inv *Inventory) Stock(name string) (decimal.Decimal, bool) {
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+
+	it, ok := inv.items[name]
+	if !ok {
+		return decimal.Zero, false
+	}
+	return it.stock, true
+}
+
+// Close cancels the deliveries on their way, so none is received after the shop is closed
+func (inv *Inventory) Close() {
+	inv.mutex.Lock()
+	inv.closed = true
+	inv.mutex.Unlock()
+
+	inv.supplier.Close()
+}
+
+// runOut marks the item as out of stock and makes sure a delivery is on its way
+// It returns the StockOut event the first time the item runs out since its last delivery, after the ReorderPlaced event if any
+// logger is the logger of the order that ran out of the item, so the log lines carry its trace
+func (inv *Inventory) runOut(it *item, logger utils.LoggerInterface) []monitor.Event {
+	events := inv.reorder(it, logger)
+	if it.outOfStock {
+		return events
+	}
+	it.outOfStock = true
+	logger.WithFields(utils.LogFields{"item": it.settings.Name, "stock": it.available()}).Info("Item out of stock")
+	return append(events, monitor.NewEvent(&monitor.StockOutPayload{Item: it.settings.Name, Stock: it.available()}))
+}
+
+// reorder places a purchase order of the item with the supplier, unless one is already on its way
+// It returns the ReorderPlaced event if an order is placed
+// logger is the logger of the order that brought the item to its reorder point, or the global one for a delivery
+func (inv *Inventory) reorder(it *item, logger utils.LoggerInterface) []monitor.Event {
+	name := it.settings.Name
+	if _, ok := inv.purchaseOrders[name]; ok || inv.closed || !it.settings.ReorderQuantity.IsPositive() {
+		return nil
+	}
+	purchaseOrder := inv.supplier.Order(it.settings, it.settings.ReorderQuantity, inv.receive)
+	inv.purchaseOrders[name] = purchaseOrder
+	logger.WithFields(utils.LogFields{
+		"purchaseOrderId": purchaseOrder.ID,
+		"item":            name,
+		"quantity":        purchaseOrder.Quantity,
+		"stock":           it.available(),
+	}).Info("Reorder placed")
+	return []monitor.Event{monitor.NewEvent(&monitor.ReorderPlacedPayload{
+		PurchaseOrderID: purchaseOrder.ID,
+		Item:            name,
+		Quantity:        purchaseOrder.Quantity,
+		Stock:           it.available(),
+	})}
+}
+
+// receive is the receiving step, it adds the delivery to the stock of its item
+// The item is reordered right away if the delivery, partial or not, leaves it at or below its reorder point
+func (inv *Inventory) receive(delivery Delivery) {
+	name := delivery.PurchaseOrder.Item
+	inv.mutex.Lock()
+	it, ok := inv.items[name]
+	if inv.closed || !ok {
+		inv.mutex.Unlock()
+		return
+	}
+	delete(inv.purchaseOrders, name)
+	it.stock = it.stock.Add(delivery.Quantity)
+	it.outOfStock = false
+	stock := it.stock
+	events := []monitor.Event{monitor.NewEvent(&monitor.DeliveryReceivedPayload{
+		PurchaseOrderID: delivery.PurchaseOrder.ID,
+		Item:            name,
+		Ordered:         delivery.PurchaseOrder.Quantity,
+		Delivered:       delivery.Quantity,
+		Stock:           stock,
+		LeadTime:        delivery.LeadTime(),
+	})}
+	// a delivery belongs to no order, its log lines carry no trace
+	if it.available().LessThanOrEqual(it.settings.ReorderPoint) {
+		events = append(events, inv.reorder(it, utils.Logger())...)
+	}
+	inv.mutex.Unlock()
+
+	utils.Logger().WithFields(utils.LogFields{
+		"purchaseOrderId": delivery.PurchaseOrder.ID,
+		"item":            name,
+		"ordered":         delivery.PurchaseOrder.Quantity,
+		"delivered":       delivery.Quantity,
+		"stock":           stock,
+	}).Info("Delivery received")
+	inv.send(events)
+}
+
+// send sends the events to the monitor
+func (inv *Inventory) send(events []monitor.Event) {
+	for _, event := range events {
+		inv.eventSystem.SendEvent(event)
+	}
+}
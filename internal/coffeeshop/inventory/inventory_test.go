@@ -0,0 +1,261 @@
+package inventory
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder records the events sent by the inventory
+type eventRecorder struct {
+	events []monitor.Event
+	mutex  sync.Mutex
+}
+
+func (r *eventRecorder) SendEvent(event monitor.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = append(r.events, event)
+}
+
+// payloads returns the payloads of the events of the given type
+func (r *eventRecorder) payloads(eventType monitor.EventType) []monitor.Payload {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var payloads []monitor.Payload
+	for _, event := range r.events {
+		if event.Type == eventType {
+			payloads = append(payloads, event.Payload)
+		}
+	}
+	return payloads
+}
+
+// testCoffeeType needs 28.35g of beans for a standard coffee
+var testCoffeeType = types.CoffeeType{
+	Name:              "Americano",
+	BeansToWaterRatio: utils.FloatToDecimal(0.1),
+	Price:             utils.FloatToDecimal(2.75),
+	SizeInOunces:      10,
+}
+
+func newTestOrder(extras ...string) *types.Order {
+	return types.NewOrder(types.NewCustomer("alice", nil), testCoffeeType, types.Standard, extras)
+}
+
+func TestReserveAndConsume(t *testing.T) {
+	events := &eventRecorder{}
+	inventory := NewInventory([]config.InventoryItemSettings{
+		{Name: Beans, StartingStock: decimal.NewFromInt(100), WasteRatio: utils.FloatToDecimal(0.1)},
+		{Name: Cups, StartingStock: decimal.NewFromInt(5)},
+		{Name: "milk", Portion: decimal.NewFromInt(4), StartingStock: decimal.NewFromInt(20)},
+	}, events)
+	order := newTestOrder("milk", "sugar")
+
+	require.NoError(t, inventory.Reserve(order))
+	stock, _ := inventory.Stock(Beans)
+	assert.True(t, decimal.NewFromInt(100).Equal(stock), "the stock is only taken when it is consumed")
+
+	inventory.Consume(order, Beans)
+	inventory.Consume(order, "milk")
+	inventory.Consume(order, "sugar")
+	inventory.Consume(order, Cups)
+	inventory.Consume(order, Cups)
+
+	stock, _ = inventory.Stock(Beans)
+	assert.Equal(t, "68.815", stock.String(), "28.35g of beans and 2.835g of waste are used")
+	stock, _ = inventory.Stock("milk")
+	assert.Equal(t, "16", stock.String())
+	stock, _ = inventory.Stock(Cups)
+	assert.Equal(t, "4", stock.String(), "a reservation is only consumed once")
+	_, ok := inventory.Stock("sugar")
+	assert.False(t, ok, "sugar is not configured, it is never out of stock")
+
+	consumed := events.payloads(monitor.IngredientConsumed)
+	require.Len(t, consumed, 3)
+	beans := consumed[0].(*monitor.IngredientConsumedPayload)
+	assert.Equal(t, Beans, beans.Item)
+	assert.Equal(t, order.ID(), beans.OrderID)
+	assert.Equal(t, "28.35", beans.Amount.String())
+	assert.Equal(t, "2.835", beans.Waste.String())
+}
+
+func TestWasteChargesAnotherPortion(t *testing.T) {
+	events := &eventRecorder{}
+	inventory := NewInventory([]config.InventoryItemSettings{
+		{Name: Beans, StartingStock: decimal.NewFromInt(100), WasteRatio: utils.FloatToDecimal(0.1)},
+		{Name: "milk", Portion: decimal.NewFromInt(4), StartingStock: decimal.NewFromInt(6)},
+	}, events)
+	order := newTestOrder("milk")
+	require.NoError(t, inventory.Reserve(order))
+
+	inventory.Consume(order, Beans)
+	inventory.Waste(order, Beans)
+	inventory.Consume(order, Beans)
+	stock, _ := inventory.Stock(Beans)
+	assert.Equal(t, "37.63", stock.String(), "the beans thrown away are charged once more, with their waste")
+
+	inventory.Waste(order, "milk")
+	inventory.Waste(order, "milk")
+	stock, _ = inventory.Stock("milk")
+	assert.Equal(t, "4", stock.String(), "no more than the stock that is not reserved is thrown away")
+	inventory.Waste(order, "sugar")
+
+	consumed := events.payloads(monitor.IngredientConsumed)
+	require.Len(t, consumed, 4)
+	wasted := consumed[1].(*monitor.IngredientConsumedPayload)
+	assert.Equal(t, order.ID(), wasted.OrderID)
+	assert.True(t, wasted.Amount.IsZero(), "nothing thrown away goes into the order")
+	assert.Equal(t, "31.185", wasted.Waste.String())
+	assert.Equal(t, "2", consumed[2].(*monitor.IngredientConsumedPayload).Waste.String())
+	assert.True(t, consumed[3].(*monitor.IngredientConsumedPayload).Waste.IsZero())
+}
+
+func TestWasteLeavesTheStockReservedForOtherOrders(t *testing.T) {
+	events := &eventRecorder{}
+	inventory := NewInventory([]config.InventoryItemSettings{
+		{Name: "milk", Portion: decimal.NewFromInt(4), StartingStock: decimal.NewFromInt(10),
+			ReorderPoint: decimal.NewFromInt(1), ReorderQuantity: decimal.NewFromInt(10), DeliveryLeadTimeSeconds: 10},
+	}, events)
+	defer inventory.Close()
+	order, other := newTestOrder("milk"), newTestOrder("milk")
+	require.NoError(t, inventory.Reserve(order))
+	require.NoError(t, inventory.Reserve(other))
+
+	inventory.Consume(order, "milk")
+	inventory.Waste(order, "milk")
+	stock, _ := inventory.Stock("milk")
+	assert.Equal(t, "4", stock.String(), "only the milk that is not reserved is thrown away")
+	require.Len(t, events.payloads(monitor.ReorderPlaced), 1, "the milk left is all reserved")
+
+	inventory.Consume(other, "milk")
+	stock, _ = inventory.Stock("milk")
+	assert.True(t, stock.IsZero(), "the other order gets the milk reserved for it")
+}
+
+func TestReleaseGivesTheReservationBack(t *testing.T) {
+	inventory := NewInventory([]config.InventoryItemSettings{
+		{Name: "milk", Portion: decimal.NewFromInt(4), StartingStock: decimal.NewFromInt(6), WasteRatio: utils.FloatToDecimal(0.5)},
+	}, &eventRecorder{})
+	order := newTestOrder("milk")
+	require.NoError(t, inventory.Reserve(order))
+	require.Error(t, inventory.Reserve(newTestOrder("milk")), "the milk is all reserved")
+
+	inventory.Release(order)
+	require.NoError(t, inventory.Reserve(newTestOrder("milk")), "the milk of the order given up is available again")
+	inventory.Consume(order, "milk")
+	stock, _ := inventory.Stock("milk")
+	assert.Equal(t, "6", stock.String(), "nothing is consumed for the order given up")
+}
+
+func TestReserveSubstitutesExtrasOutOfStock(t *testing.T) {
+	events := &eventRecorder{}
+	inventory := NewInventory([]config.InventoryItemSettings{
+		{Name: "milk", Portion: decimal.NewFromInt(4), StartingStock: decimal.NewFromInt(2), Substitute: "oat milk"},
+		{Name: "oat milk", Portion: decimal.NewFromInt(4), StartingStock: decimal.NewFromInt(4)},
+	}, events)
+
+	order := newTestOrder("milk")
+	require.NoError(t, inventory.Reserve(order))
+	assert.Equal(t, []string{"oat milk"}, order.Coffee().Extras())
+	require.Len(t, events.payloads(monitor.StockOut), 1)
+	assert.Equal(t, "milk", events.payloads(monitor.StockOut)[0].(*monitor.StockOutPayload).Item)
+	substituted := events.payloads(monitor.IngredientSubstituted)
+	require.Len(t, substituted, 1)
+	assert.Equal(t, &monitor.IngredientSubstitutedPayload{OrderRef: monitor.NewOrderRef(order), Item: "milk", Substitute: "oat milk"}, substituted[0])
+
+	// the oat milk is reserved by the first order, so the second one is rejected
+	err := inventory.Reserve(newTestOrder("milk"))
+	var outOfStock *OutOfStockError
+	require.ErrorAs(t, err, &outOfStock)
+	assert.Equal(t, "milk", outOfStock.Item)
+	assert.Len(t, events.payloads(monitor.StockOut), 2, "the milk is already out of stock, the oat milk runs out")
+}
+
+func TestReserveRejectsOrdersWithoutBeans(t *testing.T) {
+	events := &eventRecorder{}
+	inventory := NewInventory([]config.InventoryItemSettings{
+		{Name: Beans, StartingStock: decimal.NewFromInt(20)},
+		{Name: Cups, StartingStock: decimal.NewFromInt(5)},
+	}, events)
+
+	order := newTestOrder()
+	err := inventory.Reserve(order)
+	assert.EqualError(t, err, "beans out of stock")
+	inventory.Consume(order, Cups)
+	stock, _ := inventory.Stock(Cups)
+	assert.Equal(t, "5", stock.String(), "nothing is reserved for a rejected order")
+	assert.Len(t, events.payloads(monitor.StockOut), 1)
+}
+
+func TestInventoryRestocksAtReorderPoint(t *testing.T) {
+	events := &eventRecorder{}
+	inventory := NewInventory([]config.InventoryItemSettings{{
+		Name:                    Cups,
+		StartingStock:           decimal.NewFromInt(2),
+		ReorderPoint:            decimal.NewFromInt(1),
+		ReorderQuantity:         decimal.NewFromInt(10),
+		DeliveryLeadTimeSeconds: 0.01,
+	}}, events)
+
+	require.NoError(t, inventory.Reserve(newTestOrder()))
+	require.NoError(t, inventory.Reserve(newTestOrder()), "the delivery ordered by the first order is not there yet")
+	assert.Error(t, inventory.Reserve(newTestOrder()))
+
+	reorders := events.payloads(monitor.ReorderPlaced)
+	require.Len(t, reorders, 1)
+	reorder := reorders[0].(*monitor.ReorderPlacedPayload)
+	assert.Equal(t, "10", reorder.Quantity.String())
+	assert.Equal(t, "1", reorder.Stock.String())
+
+	require.Eventually(t, func() bool { return len(events.payloads(monitor.DeliveryReceived)) == 1 }, time.Second, time.Millisecond)
+	received := events.payloads(monitor.DeliveryReceived)[0].(*monitor.DeliveryReceivedPayload)
+	assert.Equal(t, reorder.PurchaseOrderID, received.PurchaseOrderID)
+	assert.Equal(t, "10", received.Delivered.String())
+	assert.Equal(t, "12", received.Stock.String())
+	assert.GreaterOrEqual(t, received.LeadTime, 10*time.Millisecond)
+	assert.NoError(t, inventory.Reserve(newTestOrder()))
+}
+
+func TestCloseCancelsDeliveries(t *testing.T) {
+	events := &eventRecorder{}
+	inventory := NewInventory([]config.InventoryItemSettings{{
+		Name:                    Cups,
+		StartingStock:           decimal.NewFromInt(1),
+		ReorderQuantity:         decimal.NewFromInt(10),
+		DeliveryLeadTimeSeconds: 0.01,
+	}}, events)
+
+	require.NoError(t, inventory.Reserve(newTestOrder()))
+	inventory.Close()
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, events.payloads(monitor.ReorderPlaced), 1)
+	assert.Empty(t, events.payloads(monitor.DeliveryReceived))
+}
+
+func TestPartialDeliveryIsReordered(t *testing.T) {
+	events := &eventRecorder{}
+	inventory := NewInventory([]config.InventoryItemSettings{{
+		Name:                       Cups,
+		StartingStock:              decimal.NewFromInt(1),
+		ReorderPoint:               decimal.NewFromInt(5),
+		ReorderQuantity:            decimal.NewFromInt(4),
+		DeliveryLeadTimeSeconds:    0.01,
+		PartialDeliveryProbability: 1,
+	}}, events)
+	defer inventory.Close()
+
+	require.NoError(t, inventory.Reserve(newTestOrder()))
+	require.Eventually(t, func() bool { return len(events.payloads(monitor.DeliveryReceived)) >= 1 }, time.Second, time.Millisecond)
+	received := events.payloads(monitor.DeliveryReceived)[0].(*monitor.DeliveryReceivedPayload)
+	assert.True(t, received.Delivered.LessThan(received.Ordered))
+	assert.GreaterOrEqual(t, len(events.payloads(monitor.ReorderPlaced)), 2, "the stock is still below the reorder point after a partial delivery")
+}
@@ -0,0 +1,107 @@
+package inventory
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+// A partial delivery brings between minimumPartialDelivery and maximumPartialDelivery of the quantity ordered
+const (
+	minimumPartialDelivery = 0.25
+	maximumPartialDelivery = 0.75
+)
+
+// PurchaseOrder is an order of an item placed with the supplier
+type PurchaseOrder struct {
+	ID         int64
+	Item       string
+	Quantity   decimal.Decimal
+	PlacedTime time.Time
+}
+
+// Delivery is what the supplier delivers for a purchase order
+// Quantity is less than the quantity ordered for a partial delivery
+type Delivery struct {
+	PurchaseOrder PurchaseOrder
+	Quantity      decimal.Decimal
+	DeliveredTime time.Time
+}
+
+// LeadTime returns the time between the purchase order and its delivery
+func (d Delivery) LeadTime() time.Duration {
+	return d.DeliveredTime.Sub(d.PurchaseOrder.PlacedTime)
+}
+
+// Partial returns whether only part of the quantity ordered was delivered
+func (d Delivery) Partial() bool {
+	return d.Quantity.LessThan(d.PurchaseOrder.Quantity)
+}
+
+// Supplier simulates the suppliers of the coffee shop
+// A purchase order is delivered after the lead time of its item, plus a random delay up to the jitter of the item,
+// and with the partial delivery probability of the item only part of the quantity ordered is delivered
+type Supplier struct {
+	lastPurchaseOrderID int64
+	// deliveries are the deliveries on their way, by purchase order ID
+	deliveries map[int64]*time.Timer
+	closed     bool
+	mutex      sync.Mutex
+}
+
+// NewSupplier creates a supplier without purchase orders
+func NewSupplier() *Supplier {
+	return &Supplier{
+		deliveries: make(map[int64]*time.Timer),
+	}
+}
+
+// Order places a purchase order of the item, receive is called with the delivery when it arrives
+// Nothing is delivered once the supplier is closed
+func (s *Supplier) Order(settings config.InventoryItemSettings, quantity decimal.Decimal, receive func(Delivery)) PurchaseOrder {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastPurchaseOrderID++
+	purchaseOrder := PurchaseOrder{
+		ID:         s.lastPurchaseOrderID,
+		Item:       settings.Name,
+		Quantity:   quantity,
+		PlacedTime: time.Now(),
+	}
+	if s.closed {
+		return purchaseOrder
+	}
+
+	leadTime := settings.DeliveryLeadTimeSeconds + rand.Float64()*settings.DeliveryLeadTimeJitterSeconds
+	delivered := quantity
+	if rand.Float64() < settings.PartialDeliveryProbability {
+		part := minimumPartialDelivery + rand.Float64()*(maximumPartialDelivery-minimumPartialDelivery)
+		delivered = quantity.Mul(decimal.NewFromFloat(part)).Round(2)
+	}
+	s.deliveries[purchaseOrder.ID] = time.AfterFunc(time.Duration(leadTime*float64(time.Second)), func() {
+		s.mutex.Lock()
+		_, ok := s.deliveries[purchaseOrder.ID]
+		delete(s.deliveries, purchaseOrder.ID)
+		s.mutex.Unlock()
+		if ok {
+			receive(Delivery{PurchaseOrder: purchaseOrder, Quantity: delivered, DeliveredTime: time.Now()})
+		}
+	})
+	return purchaseOrder
+}
+
+// Close cancels the deliveries on their way
+func (s *Supplier) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	for id, delivery := range s.deliveries {
+		delivery.Stop()
+		delete(s.deliveries, id)
+	}
+}
@@ -0,0 +1,52 @@
+package inventory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupplierDelivers(t *testing.T) {
+	supplier := NewSupplier()
+	defer supplier.Close()
+	deliveries := make(chan Delivery, 2)
+
+	first := supplier.Order(config.InventoryItemSettings{Name: Beans, DeliveryLeadTimeSeconds: 0.01}, decimal.NewFromInt(1000), func(delivery Delivery) {
+		deliveries <- delivery
+	})
+	second := supplier.Order(config.InventoryItemSettings{Name: Cups, PartialDeliveryProbability: 1}, decimal.NewFromInt(100), func(delivery Delivery) {
+		deliveries <- delivery
+	})
+	assert.Equal(t, int64(1), first.ID)
+	assert.Equal(t, int64(2), second.ID)
+
+	partial := <-deliveries
+	require.Equal(t, second, partial.PurchaseOrder, "the cups have no lead time")
+	assert.True(t, partial.Partial())
+	assert.True(t, partial.Quantity.GreaterThanOrEqual(decimal.NewFromInt(25)) && partial.Quantity.LessThanOrEqual(decimal.NewFromInt(75)), partial.Quantity.String())
+
+	full := <-deliveries
+	require.Equal(t, first, full.PurchaseOrder)
+	assert.False(t, full.Partial())
+	assert.Equal(t, "1000", full.Quantity.String())
+	assert.GreaterOrEqual(t, full.LeadTime(), 10*time.Millisecond)
+}
+
+func TestSupplierCloseCancelsDeliveries(t *testing.T) {
+	supplier := NewSupplier()
+	delivered := make(chan Delivery, 1)
+	supplier.Order(config.InventoryItemSettings{Name: Cups, DeliveryLeadTimeSeconds: 0.01}, decimal.NewFromInt(10), func(delivery Delivery) {
+		delivered <- delivery
+	})
+	supplier.Close()
+
+	select {
+	case <-delivered:
+		t.Fatal("a delivery arrived after the supplier was closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -9,7 +9,7 @@ type MockBrewerPool struct {
 	mock.Mock
 }
 
-func (m *MockBrewerPool) BrewerPool() chan *Brewer {
+func (m *MockBrewerPool) BrewerPool() *BrewerPool {
 	args := m.Called()
-	return args.Get(0).(chan *Brewer)
+	return args.Get(0).(*BrewerPool)
 }
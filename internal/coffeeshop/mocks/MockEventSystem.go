@@ -16,7 +16,3 @@ func NewMockEventSystem() *MockEventSystem {
 func (m *MockEventSystem) SendEvent(event monitor.Event) {
 	m.Called(event)
 }
-
-func (m *MockEventSystem) StartEventListener() {
-	m.Called()
-}
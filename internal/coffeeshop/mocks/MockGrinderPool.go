@@ -9,7 +9,7 @@ type MockGrinderPool struct {
 	mock.Mock
 }
 
-func (m *MockGrinderPool) GrinderPool() chan *Grinder {
+func (m *MockGrinderPool) GrinderPool() *GrinderPool {
 	args := m.Called()
-	return args.Get(0).(chan *Grinder)
+	return args.Get(0).(*GrinderPool)
 }
@@ -0,0 +1,73 @@
+package steamer
+
+import (
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
+	"github.com/s3ndd/coffeeshop/internal/monitor"
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/shopspring/decimal"
+)
+
+// Steamer represents a milk steamer
+// Its worker steams the milk of one coffee at a time, the steamer blocks until the milk is steamed
+// ouncesMilkPerSecond is the number of ounces of milk that can be steamed per second
+type Steamer struct {
+	*equipment.Worker
+	ouncesMilkPerSecond int
+}
+
+// NewSteamer creates a new milk steamer
+func NewSteamer(tag string, ouncesMilkPerSecond int) *Steamer {
+	return &Steamer{
+		Worker: equipment.NewWorker(tag, equipment.Work{
+			Kind:     "steamer",
+			Starting: "Steaming milk",
+			Done:     "Milk is steamed",
+			Aborted:  "Milk is thrown away, the steamer broke down",
+			Fields: func(coffee *types.Coffee) utils.LogFields {
+				return utils.LogFields{"milk": coffee.MilkNeeded()}
+			},
+			Record:   (*types.Coffee).AddSteamTime,
+			SetReady: (*types.Coffee).SetMilkReady,
+			Ready:    (*types.Coffee).MilkReady,
+		}),
+		ouncesMilkPerSecond: ouncesMilkPerSecond,
+	}
+}
+
+// Rate returns the ounces of milk the steamer steams per second
+func (s *Steamer) Rate() int {
+	return s.ouncesMilkPerSecond
+}
+
+// Steam hands the coffee to the steamer, it takes the time to steam its milk at the rate of the steamer
+func (s *Steamer) Steam(coffee *types.Coffee) {
+	s.SteamFor(coffee, time.Duration(coffee.MilkNeeded().Div(decimal.NewFromInt(int64(s.ouncesMilkPerSecond))).IntPart())*time.Second)
+}
+
+// SteamFor hands the coffee to the steamer, steaming its milk takes the given duration, like a step of a recipe
+func (s *Steamer) SteamFor(coffee *types.Coffee, duration time.Duration) {
+	s.Submit(coffee, duration)
+}
+
+// Acquired returns the event of a barista getting the steamer for the order
+func (s *Steamer) Acquired(order *types.Order, barista int, waitTime time.Duration) monitor.Payload {
+	return &monitor.SteamerAcquiredPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Steamer: s.Tag(), WaitTime: waitTime}
+}
+
+// Started returns the event of the steamer starting to steam the milk of the order
+func (s *Steamer) Started(order *types.Order, barista int) monitor.Payload {
+	return &monitor.SteamStartedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Steamer: s.Tag(), Milk: order.Coffee().MilkNeeded()}
+}
+
+// Finished returns the event of the milk of the order being steamed
+func (s *Steamer) Finished(order *types.Order, barista int) monitor.Payload {
+	return &monitor.SteamFinishedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Steamer: s.Tag(), SteamTime: order.Coffee().SteamTime()}
+}
+
+// Released returns the event of a barista returning the steamer to the pool
+func (s *Steamer) Released(order *types.Order, barista int) monitor.Payload {
+	return &monitor.SteamerReleasedPayload{OrderRef: monitor.NewOrderRef(order), Barista: barista, Steamer: s.Tag()}
+}
@@ -0,0 +1,14 @@
+package steamer
+
+import (
+	"github.com/s3ndd/coffeeshop/internal/coffeeshop/equipment"
+)
+
+// SteamerPool is a pool of milk steamers
+type SteamerPool = equipment.Pool[*Steamer]
+
+// NewSteamerPool creates a new steamer pool
+// size is the number of steamers expected in the pool
+func NewSteamerPool(size int) *SteamerPool {
+	return equipment.NewPool[*Steamer]("steamer", size)
+}
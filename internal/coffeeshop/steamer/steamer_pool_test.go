@@ -0,0 +1,26 @@
+package steamer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSteamerPoolAddSteamer(t *testing.T) {
+	steamerPool := NewSteamerPool(2)
+	steamerPool.Add(NewSteamer("testSteamer1", 4))
+	assert.Equal(t, 1, steamerPool.Available(), "Steamer pool should have 1 steamer")
+}
+
+func TestSteamerPoolBusy(t *testing.T) {
+	steamerPool := NewSteamerPool(2)
+	steamerPool.Add(NewSteamer("testSteamer1", 4))
+	steamerPool.Add(NewSteamer("testSteamer2", 3))
+	assert.Equal(t, 0, steamerPool.Busy(), "No steamer should be busy")
+
+	steamer := steamerPool.Acquire(nil)
+	assert.Equal(t, 1, steamerPool.Busy(), "The steamer taken from the pool should be busy")
+
+	steamerPool.Release(steamer)
+	assert.Equal(t, 0, steamerPool.Busy(), "No steamer should be busy once returned")
+}
@@ -0,0 +1,34 @@
+package steamer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSteamerCreation(t *testing.T) {
+	steamer := NewSteamer("testSteamer", 4)
+	assert.NotNil(t, steamer, "Steamer should not be nil")
+}
+
+func TestSteamerStart(t *testing.T) {
+	steamer := NewSteamer("testSteamer", 4)
+	steamer.Start()
+
+	coffee := types.NewCoffee(types.CoffeeType{
+		Name:              "TestLatte",
+		BeansToWaterRatio: utils.FloatToDecimal(0.16),
+		Price:             utils.FloatToDecimal(3.5),
+		SizeInOunces:      12,
+		MilkInOunces:      utils.FloatToDecimal(8),
+	}, types.Large, []string{"milk"})
+
+	steamer.Steam(coffee)
+
+	isMilkReady := <-coffee.MilkReady()
+	assert.True(t, isMilkReady, "The milk should be steamed")
+	assert.Equal(t, 2*time.Second, coffee.SteamTime(), "10oz of milk should take 2 whole seconds at 4oz per second")
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"sync"
 
+	"github.com/s3ndd/coffeeshop/internal/recipe"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 	"github.com/shopspring/decimal"
 	"gopkg.in/yaml.v3"
@@ -22,61 +25,313 @@ var (
 )
 
 // BrewerSettings is a struct that contains the settings for a coffee brewer.
+// Reliability is how often the brewer breaks down and when it is maintained, it never stops if it is empty
+// Cleaning is how the brewer slows down with use and when a barista cleans it, it never gets dirty if it is empty
 type BrewerSettings struct {
-	Tag                  string `yaml:"tag"`
-	OuncesWaterPerSecond int    `yaml:"ouncesWaterPerSecond"`
+	Tag                  string              `yaml:"tag"`
+	OuncesWaterPerSecond int                 `yaml:"ouncesWaterPerSecond"`
+	Reliability          ReliabilitySettings `yaml:"reliability"`
+	Cleaning             CleaningSettings    `yaml:"cleaning"`
+}
+
+// The failure modes of a unit of equipment, what happens to the coffee it is processing when it breaks down
+const (
+	// DelayOnFailure keeps the coffee in the unit until it is repaired, then the unit finishes it
+	DelayOnFailure = "delay"
+	// AbortOnFailure throws the coffee away, the barista starts over from the first step whose output was lost, on other units
+	AbortOnFailure = "abort"
+)
+
+// The types of the distributions of random durations
+const (
+	FixedDistribution       = "fixed"
+	ExponentialDistribution = "exponential"
+	NormalDistribution      = "normal"
+)
+
+// DistributionSettings is a struct that contains the distribution of a random duration, like the time between two breakdowns.
+// Type is fixed, exponential or normal, fixed if it is empty, MeanSeconds is the mean of the duration,
+// StdDevSeconds is the standard deviation of a normal duration, which is never negative
+type DistributionSettings struct {
+	Type          string  `yaml:"type"`
+	MeanSeconds   float64 `yaml:"meanSeconds"`
+	StdDevSeconds float64 `yaml:"stdDevSeconds"`
+}
+
+// MaintenanceSettings is a struct that contains a scheduled maintenance window of a unit of equipment.
+// The unit is taken out of service StartSeconds after the shop opens for DurationSeconds, every EverySeconds or once if it is zero
+// A unit in use at the start of the window is taken out once the barista returns it
+type MaintenanceSettings struct {
+	StartSeconds    float64 `yaml:"startSeconds"`
+	DurationSeconds float64 `yaml:"durationSeconds"`
+	EverySeconds    float64 `yaml:"everySeconds"`
+}
+
+// ReliabilitySettings is a struct that contains the reliability model of a unit of equipment.
+// MTBF is the operating time between two breakdowns, the unit never breaks down if its mean is zero,
+// MTTR is the time to repair it, the unit is out of its pool meanwhile
+// OnFailure is what happens to the coffee in the unit when it breaks down: delay, the default, or abort
+type ReliabilitySettings struct {
+	MTBF        DistributionSettings  `yaml:"mtbf"`
+	MTTR        DistributionSettings  `yaml:"mttr"`
+	OnFailure   string                `yaml:"onFailure"`
+	Maintenance []MaintenanceSettings `yaml:"maintenance"`
+}
+
+// Validate checks the distributions, the failure mode and the maintenance windows
+func (r *ReliabilitySettings) Validate() error {
+	names := []string{"mtbf", "mttr"}
+	for i, distribution := range []DistributionSettings{r.MTBF, r.MTTR} {
+		switch distribution.Type {
+		case "", FixedDistribution, ExponentialDistribution, NormalDistribution:
+		default:
+			return fmt.Errorf("%s: unknown distribution %q", names[i], distribution.Type)
+		}
+		if distribution.MeanSeconds < 0 || distribution.StdDevSeconds < 0 {
+			return fmt.Errorf("%s: negative duration", names[i])
+		}
+	}
+	switch r.OnFailure {
+	case "", DelayOnFailure, AbortOnFailure:
+	default:
+		return fmt.Errorf("unknown failure mode %q, it is delay or abort", r.OnFailure)
+	}
+	for i, window := range r.Maintenance {
+		if window.StartSeconds < 0 || window.DurationSeconds <= 0 {
+			return fmt.Errorf("maintenance window %d: the start must not be negative and the duration must be positive", i+1)
+		}
+		if window.EverySeconds != 0 && window.EverySeconds < window.DurationSeconds {
+			return fmt.Errorf("maintenance window %d: repeated before it is over", i+1)
+		}
+	}
+	return nil
+}
+
+// CleaningSettings is a struct that contains the cleaning cycle of a unit of equipment.
+// The unit slows down with each use since its last cleaning, its rate is divided by 1 + DegradationPerUse per use,
+// so 0.1 halves it after 10 uses, and it is cleaned by a barista after EveryUses uses, which takes DurationSeconds
+// The unit is never cleaned if EveryUses is zero
+type CleaningSettings struct {
+	EveryUses         int     `yaml:"everyUses"`
+	DurationSeconds   float64 `yaml:"durationSeconds"`
+	DegradationPerUse float64 `yaml:"degradationPerUse"`
+}
+
+// Validate checks the cleaning cycle
+func (c *CleaningSettings) Validate() error {
+	if c.EveryUses < 0 || c.DurationSeconds < 0 || c.DegradationPerUse < 0 {
+		return errors.New("negative everyUses, durationSeconds or degradationPerUse")
+	}
+	if c.EveryUses > 0 && c.DurationSeconds == 0 {
+		return errors.New("cleaned every few uses in no time, the durationSeconds must be positive")
+	}
+	return nil
+}
+
+// SteamerSettings is a struct that contains the settings for a milk steamer.
+type SteamerSettings struct {
+	Tag                 string `yaml:"tag"`
+	OuncesMilkPerSecond int    `yaml:"ouncesMilkPerSecond"`
+}
+
+// IceDispenserSettings is a struct that contains the settings for an ice dispenser.
+type IceDispenserSettings struct {
+	Tag                string `yaml:"tag"`
+	OuncesIcePerSecond int    `yaml:"ouncesIcePerSecond"`
+}
+
+// ColdBrewSettings is a struct that contains the settings for the cold brew, prepared in batches ahead of the orders.
+// The orders of cold brew draw from the stock, when it falls to ReplenishPoint a barista prepares a batch of BatchOunces,
+// which takes BatchSeconds, and an order that finds the stock short waits for the batch
+type ColdBrewSettings struct {
+	StartingStock  decimal.Decimal `yaml:"startingStock"`
+	ReplenishPoint decimal.Decimal `yaml:"replenishPoint"`
+	BatchOunces    decimal.Decimal `yaml:"batchOunces"`
+	BatchSeconds   float64         `yaml:"batchSeconds"`
+}
+
+// ExtraSettings is a struct that contains how an extra is added to a coffee.
+// A steamed extra, like milk, is steamed by a steamer, the milk of the coffee type is steamed for it
+// The other extras, like sugar, are added at the condiment station, it takes Seconds per extra
+type ExtraSettings struct {
+	Name    string  `yaml:"name"`
+	Steamed bool    `yaml:"steamed"`
+	Seconds float64 `yaml:"seconds"`
 }
 
 // GrinderSettings is a struct that contains the settings for a coffee grinder.
+// Beans are the names of the beans the grinder handles, it handles any beans if it is empty
+// Reliability is how often the grinder breaks down and when it is maintained, it never stops if it is empty
+// Cleaning is how the grinder slows down with use and when a barista cleans it, it never gets dirty if it is empty
 type GrinderSettings struct {
-	Tag            string `yaml:"tag"`
-	GramsPerSecond int    `yaml:"gramsPerSecond"`
+	Tag            string              `yaml:"tag"`
+	GramsPerSecond int                 `yaml:"gramsPerSecond"`
+	Beans          []string            `yaml:"beans"`
+	Reliability    ReliabilitySettings `yaml:"reliability"`
+	Cleaning       CleaningSettings    `yaml:"cleaning"`
+}
+
+// BeanSettings is a struct that contains the settings for a type of coffee beans.
+// GrindSetting is how the grinders are set for the beans, for example fine for an espresso roast
+type BeanSettings struct {
+	Name         string `yaml:"name"`
+	Origin       string `yaml:"origin"`
+	Roast        string `yaml:"roast"`
+	GrindSetting string `yaml:"grindSetting"`
+}
+
+// RecipeStep is a step of the recipe of a coffee type.
+// Action is what the step does: grind, brew, shot, steam, ice, coldbrew, condiments or pour
+// Equipment is the class of equipment it needs: grinder, brewer, steamer, ice dispenser or none, the default of the action if it is empty
+// Duration is the formula of the seconds it takes, for example floor(beans / rate), the default of the action if it is empty
+// Together performs the step together with the previous one, the equipment of both is reserved at once and held until both are done
+type RecipeStep struct {
+	Action    string `yaml:"action"`
+	Equipment string `yaml:"equipment"`
+	Duration  string `yaml:"duration"`
+	Together  bool   `yaml:"together"`
 }
 
 // CoffeeType represents the type of a coffee
+// Bean is the name of the beans it is made with, it can be ground by any grinder if it is empty
+// MilkInOunces is the milk steamed for a standard size when the coffee is ordered with a steamed extra,
+// the larger sizes get more milk in the same proportion as water
+// An iced coffee is poured over IceInOunces of ice from an ice dispenser, its milk is not steamed
+// A cold brew coffee is drawn from the cold brew stock instead of being ground and brewed
+// Recipe is the ordered steps of the preparation, the coffee types without one follow the default recipe
 type CoffeeType struct {
 	Name              string          `yaml:"name"`
 	BeansToWaterRatio decimal.Decimal `yaml:"beansToWaterRatio"`
 	Price             decimal.Decimal `yaml:"price"`
 	SizeInOunces      int             `yaml:"sizeInOunces"`
+	Bean              string          `yaml:"bean"`
+	MilkInOunces      decimal.Decimal `yaml:"milkInOunces"`
+	Iced              bool            `yaml:"iced"`
+	IceInOunces       decimal.Decimal `yaml:"iceInOunces"`
+	ColdBrew          bool            `yaml:"coldBrew"`
+	Recipe            []RecipeStep    `yaml:"recipe"`
+}
+
+// ParseRecipe parses the recipe of the coffee type, it is the default recipe if the coffee type declares none
+func (c *CoffeeType) ParseRecipe() (recipe.Recipe, error) {
+	if len(c.Recipe) == 0 {
+		return recipe.Default(c.Iced, c.ColdBrew), nil
+	}
+	steps := make(recipe.Recipe, 0, len(c.Recipe))
+	for i, step := range c.Recipe {
+		parsed, err := recipe.NewStep(step.Action, step.Equipment, step.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("recipe step %d: %w", i+1, err)
+		}
+		if step.Together && i == 0 {
+			return nil, fmt.Errorf("recipe step %d: together with no previous step", i+1)
+		}
+		parsed.Together = step.Together
+		steps = append(steps, parsed)
+	}
+	return steps, nil
+}
+
+// InventoryItemSettings is a struct that contains the settings for an ingredient or supply of the coffee shop.
+// The beans and cups items are used by every coffee, the other items by the extras of the same name
+// Portion is the amount used by one extra or cup, the beans used by a coffee are computed from its type and size
+// WasteRatio is the part of each use that is lost, for example the beans retained in the grinder or the milk left in the pitcher
+// When the stock left falls to ReorderPoint, ReorderQuantity is ordered from the supplier,
+// it is delivered after DeliveryLeadTimeSeconds plus a random delay up to DeliveryLeadTimeJitterSeconds
+// PartialDeliveryProbability is the probability that only part of the quantity ordered is delivered
+// Substitute is the item used instead of an extra out of stock, the order is rejected if it is empty
+type InventoryItemSettings struct {
+	Name                          string          `yaml:"name"`
+	Unit                          string          `yaml:"unit"`
+	Portion                       decimal.Decimal `yaml:"portion"`
+	StartingStock                 decimal.Decimal `yaml:"startingStock"`
+	ReorderPoint                  decimal.Decimal `yaml:"reorderPoint"`
+	ReorderQuantity               decimal.Decimal `yaml:"reorderQuantity"`
+	DeliveryLeadTimeSeconds       float64         `yaml:"deliveryLeadTimeSeconds"`
+	DeliveryLeadTimeJitterSeconds float64         `yaml:"deliveryLeadTimeJitterSeconds"`
+	PartialDeliveryProbability    float64         `yaml:"partialDeliveryProbability"`
+	WasteRatio                    decimal.Decimal `yaml:"wasteRatio"`
+	Substitute                    string          `yaml:"substitute"`
 }
 
 // CoffeeShopSettings is a struct that contains the settings for a coffee shop.
+// The items missing from the inventory are never out of stock, the extras missing from Extras take no time to add
+// EquipmentTimeoutSeconds is how long a barista waits for equipment before reporting it, then it waits again,
+// it waits as long as it takes without reporting if it is zero
 type CoffeeShopSettings struct {
-	NumberOfBaristas int               `yaml:"numberOfBaristas"`
-	NumberOfCashiers int               `yaml:"numberOfCashiers"`
-	NumberOfGreeters int               `yaml:"numberOfGreeters"`
-	CashierQueueSize int               `yaml:"cashierQueueSize"`
-	OrderQueueSize   int               `yaml:"orderQueueSize"`
-	CoffeeTypes      []CoffeeType      `yaml:"coffeeTypes"`
-	GrinderSettings  []GrinderSettings `yaml:"grinders"`
-	BrewerSettings   []BrewerSettings  `yaml:"brewers"`
+	NumberOfBaristas        int                     `yaml:"numberOfBaristas"`
+	NumberOfCashiers        int                     `yaml:"numberOfCashiers"`
+	NumberOfGreeters        int                     `yaml:"numberOfGreeters"`
+	CashierQueueSize        int                     `yaml:"cashierQueueSize"`
+	OrderQueueSize          int                     `yaml:"orderQueueSize"`
+	EquipmentTimeoutSeconds float64                 `yaml:"equipmentTimeoutSeconds"`
+	Beans                   []BeanSettings          `yaml:"beans"`
+	CoffeeTypes             []CoffeeType            `yaml:"coffeeTypes"`
+	GrinderSettings         []GrinderSettings       `yaml:"grinders"`
+	BrewerSettings          []BrewerSettings        `yaml:"brewers"`
+	SteamerSettings         []SteamerSettings       `yaml:"steamers"`
+	IceDispensers           []IceDispenserSettings  `yaml:"iceDispensers"`
+	ColdBrew                ColdBrewSettings        `yaml:"coldBrew"`
+	Extras                  []ExtraSettings         `yaml:"extras"`
+	Inventory               []InventoryItemSettings `yaml:"inventory"`
+}
+
+// QueueSamplingSettings is a struct that contains the settings for the sampling of the queue lengths.
+// IntervalSeconds is the time between two samples, no samples are taken if it is zero
+// CSVPath and JSONPath are the files the samples are exported to at shutdown, a format is skipped if its path is empty
+type QueueSamplingSettings struct {
+	IntervalSeconds float64 `yaml:"intervalSeconds"`
+	CSVPath         string  `yaml:"csvPath"`
+	JSONPath        string  `yaml:"jsonPath"`
+}
+
+// MonitorSettings is a struct that contains the settings for the monitoring of the coffee shop.
+// EventLogPath is the JSON Lines file the events are appended to, no events are persisted if it is empty
+// SubscriberBufferSize is the number of events buffered for each subscriber of the event system
+// OverflowPolicy is what happens when the buffer of a subscriber is full: block, drop-newest or drop-oldest
+// HistogramBuckets are the upper bounds in seconds of the buckets of the duration histograms
+// ServerAddress is the address the monitor HTTP server listens on, for example :9090, no server is started if it is empty
+// TracePath is the file a trace of the run in the Chrome Trace Event format is written to at shutdown, no trace is recorded if it is empty
+// SpansPath is the JSON file the spans of every order are written to at shutdown, the spans are only recorded if it is set
+// DrinkReportPath is the JSON file the timings by coffee type, size and extras are written to at shutdown, no report is written if it is empty
+type MonitorSettings struct {
+	ServerAddress        string                `yaml:"serverAddress"`
+	EventLogPath         string                `yaml:"eventLogPath"`
+	SubscriberBufferSize int                   `yaml:"subscriberBufferSize"`
+	OverflowPolicy       string                `yaml:"overflowPolicy"`
+	HistogramBuckets     []float64             `yaml:"histogramBuckets"`
+	QueueSampling        QueueSamplingSettings `yaml:"queueSampling"`
+	DrinkReportPath      string                `yaml:"drinkReportPath"`
+	TracePath            string                `yaml:"tracePath"`
+	SpansPath            string                `yaml:"spansPath"`
 }
 
 type Config struct {
 	CoffeeShopSettings CoffeeShopSettings `yaml:"coffeeShop"`
+	MonitorSettings    MonitorSettings    `yaml:"monitor"`
 }
 
 func (c *Config) CoffeeTypes() []*CoffeeType {
 	var coffeeTypes []*CoffeeType
-	for _, ct := range c.CoffeeShopSettings.CoffeeTypes {
-		coffeeTypes = append(coffeeTypes, &ct)
+	for i := range c.CoffeeShopSettings.CoffeeTypes {
+		// point to the slice element, not to the loop variable shared by every iteration
+		coffeeTypes = append(coffeeTypes, &c.CoffeeShopSettings.CoffeeTypes[i])
 	}
 	return coffeeTypes
 }
 
 func (c *Config) GrinderSettings() []*GrinderSettings {
 	var grinderSettings []*GrinderSettings
-	for _, gs := range c.CoffeeShopSettings.GrinderSettings {
-		grinderSettings = append(grinderSettings, &gs)
+	for i := range c.CoffeeShopSettings.GrinderSettings {
+		grinderSettings = append(grinderSettings, &c.CoffeeShopSettings.GrinderSettings[i])
 	}
 	return grinderSettings
 }
 
 func (c *Config) BrewerSettings() []*BrewerSettings {
 	var brewerSettings []*BrewerSettings
-	for _, bs := range c.CoffeeShopSettings.BrewerSettings {
-		brewerSettings = append(brewerSettings, &bs)
+	for i := range c.CoffeeShopSettings.BrewerSettings {
+		brewerSettings = append(brewerSettings, &c.CoffeeShopSettings.BrewerSettings[i])
 	}
 	return brewerSettings
 }
@@ -85,6 +340,118 @@ func (c *Config) CoffeeShop() *CoffeeShopSettings {
 	return &c.CoffeeShopSettings
 }
 
+// Bean returns the settings of the beans of the given name
+func (s *CoffeeShopSettings) Bean(name string) (BeanSettings, bool) {
+	for _, bean := range s.Beans {
+		if bean.Name == name {
+			return bean, true
+		}
+	}
+	return BeanSettings{}, false
+}
+
+// Validate checks that the beans referenced by the coffee types and grinders are configured,
+// that the beans of every coffee type can be ground by at least one grinder,
+// that there is a steamer if an extra is steamed, an ice dispenser if a coffee type is iced,
+// and cold brew batches if a coffee type is cold brew
+// It also checks that the recipes parse, that only the cold brew coffee types draw from the cold brew stock,
+// that the equipment named by a declared recipe is configured, and the reliability of the grinders and brewers
+func (s *CoffeeShopSettings) Validate() error {
+	for _, extra := range s.Extras {
+		if extra.Steamed && len(s.SteamerSettings) == 0 {
+			return fmt.Errorf("extra %s: steamed without steamers", extra.Name)
+		}
+	}
+	for _, grinder := range s.GrinderSettings {
+		for _, bean := range grinder.Beans {
+			if _, ok := s.Bean(bean); !ok {
+				return fmt.Errorf("grinder %s: unknown bean %q", grinder.Tag, bean)
+			}
+		}
+		if err := grinder.Reliability.Validate(); err != nil {
+			return fmt.Errorf("grinder %s: reliability: %w", grinder.Tag, err)
+		}
+		if err := grinder.Cleaning.Validate(); err != nil {
+			return fmt.Errorf("grinder %s: cleaning: %w", grinder.Tag, err)
+		}
+	}
+	for _, brewer := range s.BrewerSettings {
+		if err := brewer.Reliability.Validate(); err != nil {
+			return fmt.Errorf("brewer %s: reliability: %w", brewer.Tag, err)
+		}
+		if err := brewer.Cleaning.Validate(); err != nil {
+			return fmt.Errorf("brewer %s: cleaning: %w", brewer.Tag, err)
+		}
+	}
+	for _, coffeeType := range s.CoffeeTypes {
+		if coffeeType.Iced && len(s.IceDispensers) == 0 {
+			return fmt.Errorf("coffee type %s: iced without ice dispensers", coffeeType.Name)
+		}
+		if coffeeType.ColdBrew && !s.ColdBrew.BatchOunces.IsPositive() {
+			return fmt.Errorf("coffee type %s: cold brew without cold brew batches", coffeeType.Name)
+		}
+		steps, err := coffeeType.ParseRecipe()
+		if err != nil {
+			return fmt.Errorf("coffee type %s: %w", coffeeType.Name, err)
+		}
+		if coffeeType.ColdBrew != steps.Has(recipe.ColdBrew) {
+			return fmt.Errorf("coffee type %s: only a cold brew recipe has a coldbrew step", coffeeType.Name)
+		}
+		if len(coffeeType.Recipe) > 0 {
+			if err = s.equipped(steps); err != nil {
+				return fmt.Errorf("coffee type %s: %w", coffeeType.Name, err)
+			}
+		}
+		if coffeeType.Bean == "" {
+			continue
+		}
+		if _, ok := s.Bean(coffeeType.Bean); !ok {
+			return fmt.Errorf("coffee type %s: unknown bean %q", coffeeType.Name, coffeeType.Bean)
+		}
+		// the beans of the cold brew are prepared with the batches, not ground by the grinders
+		if steps.Uses(recipe.Grinder) && !s.ground(coffeeType.Bean) {
+			return fmt.Errorf("coffee type %s: no grinder handles bean %q", coffeeType.Name, coffeeType.Bean)
+		}
+	}
+	return nil
+}
+
+// equipped checks that there is equipment for each step of the recipe
+func (s *CoffeeShopSettings) equipped(steps recipe.Recipe) error {
+	configured := map[recipe.Equipment]bool{
+		recipe.Grinder:      len(s.GrinderSettings) > 0,
+		recipe.Brewer:       len(s.BrewerSettings) > 0,
+		recipe.Steamer:      len(s.SteamerSettings) > 0,
+		recipe.IceDispenser: len(s.IceDispensers) > 0,
+		recipe.None:         true,
+	}
+	for _, step := range steps {
+		if !configured[step.Equipment] {
+			return fmt.Errorf("recipe step %s: no %s configured", step, step.Equipment)
+		}
+	}
+	return nil
+}
+
+// ground returns whether a grinder handles the beans of the given name
+func (s *CoffeeShopSettings) ground(bean string) bool {
+	for _, grinder := range s.GrinderSettings {
+		if len(grinder.Beans) == 0 {
+			return true
+		}
+		for _, name := range grinder.Beans {
+			if name == bean {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Config) Monitor() *MonitorSettings {
+	return &c.MonitorSettings
+}
+
 func LoadConfig() *Config {
 	once.Do(func() {
 		logger := utils.Logger()
@@ -98,6 +465,9 @@ func LoadConfig() *Config {
 		if err = yaml.Unmarshal(data, &cfg); err != nil {
 			logger.WithError(err).Fatal("Error unmarshalling config file")
 		}
+		if err = cfg.CoffeeShopSettings.Validate(); err != nil {
+			logger.WithError(err).Fatal("Invalid config file")
+		}
 		config = cfg
 	})
 
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateBeans(t *testing.T) {
+	settings := CoffeeShopSettings{
+		Beans: []BeanSettings{{Name: "espresso"}, {Name: "decaf"}},
+		CoffeeTypes: []CoffeeType{
+			{Name: "Latte", Bean: "espresso"},
+			{Name: "Americano"},
+		},
+		GrinderSettings: []GrinderSettings{{Tag: "grinder1", Beans: []string{"espresso"}}},
+	}
+	assert.NoError(t, settings.Validate())
+
+	settings.CoffeeTypes = append(settings.CoffeeTypes, CoffeeType{Name: "Decaf Latte", Bean: "decaf"})
+	assert.EqualError(t, settings.Validate(), `coffee type Decaf Latte: no grinder handles bean "decaf"`)
+
+	settings.GrinderSettings = append(settings.GrinderSettings, GrinderSettings{Tag: "grinder2"})
+	assert.NoError(t, settings.Validate(), "a grinder without beans handles any beans")
+
+	settings.CoffeeTypes[0].Bean = "robusta"
+	assert.EqualError(t, settings.Validate(), `coffee type Latte: unknown bean "robusta"`)
+
+	settings.GrinderSettings[0].Beans = []string{"robusta"}
+	assert.EqualError(t, settings.Validate(), `grinder grinder1: unknown bean "robusta"`)
+}
+
+func TestValidateExtras(t *testing.T) {
+	settings := CoffeeShopSettings{
+		Extras: []ExtraSettings{{Name: "milk", Steamed: true}, {Name: "sugar", Seconds: 2}},
+	}
+	assert.EqualError(t, settings.Validate(), "extra milk: steamed without steamers")
+
+	settings.SteamerSettings = []SteamerSettings{{Tag: "steamer1"}}
+	assert.NoError(t, settings.Validate())
+}
+
+func TestValidateIcedAndColdBrew(t *testing.T) {
+	settings := CoffeeShopSettings{
+		CoffeeTypes: []CoffeeType{{Name: "Iced Latte", Iced: true}},
+	}
+	assert.EqualError(t, settings.Validate(), "coffee type Iced Latte: iced without ice dispensers")
+
+	settings.IceDispensers = []IceDispenserSettings{{Tag: "ice1"}}
+	assert.NoError(t, settings.Validate())
+
+	settings.CoffeeTypes = append(settings.CoffeeTypes, CoffeeType{Name: "Cold Brew", Bean: "single origin", Iced: true, ColdBrew: true})
+	settings.Beans = []BeanSettings{{Name: "single origin"}}
+	assert.EqualError(t, settings.Validate(), "coffee type Cold Brew: cold brew without cold brew batches")
+
+	settings.ColdBrew.BatchOunces = decimal.NewFromInt(64)
+	assert.NoError(t, settings.Validate(), "the beans of the cold brew need no grinder")
+}
+
+func TestValidateReliability(t *testing.T) {
+	settings := CoffeeShopSettings{
+		GrinderSettings: []GrinderSettings{{Tag: "grinder1", Reliability: ReliabilitySettings{
+			MTBF:        DistributionSettings{Type: "exponential", MeanSeconds: 600},
+			MTTR:        DistributionSettings{Type: "normal", MeanSeconds: 60, StdDevSeconds: 10},
+			OnFailure:   "abort",
+			Maintenance: []MaintenanceSettings{{StartSeconds: 30, DurationSeconds: 10, EverySeconds: 300}},
+		}}},
+		BrewerSettings: []BrewerSettings{{Tag: "brewer1"}},
+	}
+	assert.NoError(t, settings.Validate())
+
+	settings.BrewerSettings[0].Reliability.MTTR.Type = "weibull"
+	assert.EqualError(t, settings.Validate(), `brewer brewer1: reliability: mttr: unknown distribution "weibull"`)
+	settings.BrewerSettings[0].Reliability.MTTR.Type = ""
+
+	settings.GrinderSettings[0].Reliability.OnFailure = "explode"
+	assert.EqualError(t, settings.Validate(), `grinder grinder1: reliability: unknown failure mode "explode", it is delay or abort`)
+	settings.GrinderSettings[0].Reliability.OnFailure = "delay"
+
+	settings.GrinderSettings[0].Reliability.Maintenance[0].EverySeconds = 5
+	assert.EqualError(t, settings.Validate(), "grinder grinder1: reliability: maintenance window 1: repeated before it is over")
+}
+
+func TestValidateCleaning(t *testing.T) {
+	settings := CoffeeShopSettings{
+		GrinderSettings: []GrinderSettings{{Tag: "grinder1", Cleaning: CleaningSettings{EveryUses: 20, DurationSeconds: 30, DegradationPerUse: 0.02}}},
+		BrewerSettings:  []BrewerSettings{{Tag: "brewer1", Cleaning: CleaningSettings{DegradationPerUse: 0.01}}},
+	}
+	assert.NoError(t, settings.Validate(), "A brewer that is never cleaned only slows down")
+
+	settings.BrewerSettings[0].Cleaning.DegradationPerUse = -0.01
+	assert.EqualError(t, settings.Validate(), "brewer brewer1: cleaning: negative everyUses, durationSeconds or degradationPerUse")
+	settings.BrewerSettings[0].Cleaning.DegradationPerUse = 0
+
+	settings.GrinderSettings[0].Cleaning.DurationSeconds = 0
+	assert.EqualError(t, settings.Validate(), "grinder grinder1: cleaning: cleaned every few uses in no time, the durationSeconds must be positive")
+}
+
+func TestCoffeeTypes(t *testing.T) {
+	cfg := &Config{CoffeeShopSettings: CoffeeShopSettings{CoffeeTypes: []CoffeeType{{Name: "Latte"}, {Name: "Americano"}}}}
+
+	coffeeTypes := cfg.CoffeeTypes()
+	assert.Len(t, coffeeTypes, 2)
+	assert.Equal(t, "Latte", coffeeTypes[0].Name)
+	assert.Equal(t, "Americano", coffeeTypes[1].Name)
+}
+
+func TestValidateRecipes(t *testing.T) {
+	settings := CoffeeShopSettings{
+		CoffeeTypes: []CoffeeType{{Name: "Latte", Recipe: []RecipeStep{
+			{Action: "grind"},
+			{Action: "brew"},
+			{Action: "shot", Duration: "1 + size"},
+			{Action: "steam"},
+			{Action: "pour", Duration: "2"},
+		}}},
+		GrinderSettings: []GrinderSettings{{Tag: "grinder1"}},
+		BrewerSettings:  []BrewerSettings{{Tag: "brewer1"}},
+	}
+	assert.EqualError(t, settings.Validate(), "coffee type Latte: recipe step steam on steamer: no steamer configured")
+
+	settings.SteamerSettings = []SteamerSettings{{Tag: "steamer1"}}
+	assert.NoError(t, settings.Validate())
+
+	settings.CoffeeTypes[0].Recipe[2].Duration = "shots * 2"
+	assert.EqualError(t, settings.Validate(), `coffee type Latte: recipe step 3: shot: formula "shots * 2": unknown variable "shots", the variables are beans, condiments, ice, milk, rate, size, water`)
+
+	settings.CoffeeTypes[0].Recipe[0].Together = true
+	assert.EqualError(t, settings.Validate(), "coffee type Latte: recipe step 1: together with no previous step")
+	settings.CoffeeTypes[0].Recipe[0].Together = false
+
+	settings.CoffeeTypes[0].Recipe[2] = RecipeStep{Action: "coldbrew"}
+	assert.EqualError(t, settings.Validate(), "coffee type Latte: only a cold brew recipe has a coldbrew step")
+
+	recipe, err := settings.CoffeeTypes[0].ParseRecipe()
+	assert.NoError(t, err)
+	assert.Len(t, recipe, 5)
+}
@@ -0,0 +1,166 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// outageUsage accumulates the outages of a unit of equipment
+// outages is the number of outages in progress, they may overlap, like a breakdown during a maintenance, outSince is zero in service
+// lowestRate is the rate it slowed down to before its cleanings, zero if it was never cleaned
+type outageUsage struct {
+	breakdowns   int
+	maintenances int
+	cleanings    int
+	aborted      int
+	lowestRate   float64
+	downtime     time.Duration
+	outages      int
+	outSince     time.Time
+}
+
+// EquipmentAvailability is the availability of a unit of equipment that was out of service, the times are in seconds
+// Availability is the percent of the observed time it was in service, AbortedCoffees the coffees thrown away by its breakdowns
+// and LowestRate the rate it slowed down to before it was cleaned, in its own unit per second
+type EquipmentAvailability struct {
+	Name           string  `json:"name"`
+	Breakdowns     int     `json:"breakdowns"`
+	Maintenances   int     `json:"maintenances"`
+	Cleanings      int     `json:"cleanings"`
+	AbortedCoffees int     `json:"abortedCoffees"`
+	LowestRate     float64 `json:"lowestRate,omitempty"`
+	Downtime       float64 `json:"downtime"`
+	Availability   float64 `json:"availability"`
+	OutOfService   bool    `json:"outOfService"`
+}
+
+// PoolAvailability is the availability of the units of a kind of equipment, the times are in seconds
+// Units is the number of units registered when the shop opened, or else seen in use or out of service,
+// Availability is the percent of them in service on average,
+// so the capacity the shop actually had, and Equipment the units that were out of service
+type PoolAvailability struct {
+	Kind         ResourceKind            `json:"kind"`
+	Units        int                     `json:"units"`
+	Breakdowns   int                     `json:"breakdowns"`
+	Maintenances int                     `json:"maintenances"`
+	Cleanings    int                     `json:"cleanings"`
+	Downtime     float64                 `json:"downtime"`
+	Availability float64                 `json:"availability"`
+	Equipment    []EquipmentAvailability `json:"equipment"`
+}
+
+// consumeAvailabilityEvent updates the outages of the equipment taken out of service and back, and its cleanings
+func (m *Metrics) consumeAvailabilityEvent(event Event) {
+	switch payload := event.Payload.(type) {
+	case *EquipmentOutOfServicePayload:
+		usage := m.outageUsage(ResourceKind(payload.Kind), payload.Equipment)
+		if usage.outages == 0 {
+			usage.outSince = event.Timestamp
+		}
+		usage.outages++
+		switch payload.Reason {
+		case MaintenanceOutage:
+			usage.maintenances++
+		case CleaningOutage:
+			usage.cleanings++
+		default:
+			usage.breakdowns++
+		}
+		if payload.Aborted {
+			usage.aborted++
+		}
+	case *EquipmentBackInServicePayload:
+		usage := m.outageUsage(ResourceKind(payload.Kind), payload.Equipment)
+		if usage.outages == 0 {
+			return
+		}
+		usage.outages--
+		if usage.outages == 0 {
+			usage.downtime += event.Timestamp.Sub(usage.outSince)
+			usage.outSince = time.Time{}
+		}
+	case *EquipmentCleanedPayload:
+		usage := m.outageUsage(ResourceKind(payload.Kind), payload.Equipment)
+		if usage.lowestRate == 0 || payload.Rate < usage.lowestRate {
+			usage.lowestRate = payload.Rate
+		}
+	}
+}
+
+// outageUsage returns the outages of a unit of equipment, creating them on first use
+func (m *Metrics) outageUsage(kind ResourceKind, name string) *outageUsage {
+	units, ok := m.outages[kind]
+	if !ok {
+		units = make(map[string]*outageUsage)
+		m.outages[kind] = units
+	}
+	usage, ok := units[name]
+	if !ok {
+		usage = &outageUsage{}
+		units[name] = usage
+	}
+	return usage
+}
+
+// availability returns the availability of each kind of equipment that had outages over the observed period ending now, sorted by kind
+// A unit still out of service now counts as out until now, the units never out of service are fully available
+func (m *Metrics) availability(now time.Time, observed time.Duration) []PoolAvailability {
+	kinds := make([]string, 0, len(m.outages))
+	for kind := range m.outages {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+
+	pools := make([]PoolAvailability, 0, len(kinds))
+	for _, k := range kinds {
+		kind := ResourceKind(k)
+		names := make([]string, 0, len(m.outages[kind]))
+		for name := range m.outages[kind] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		// the units registered or used but never out of service count as available, like an idle spare
+		units := len(names)
+		for name := range m.utilization.resources[kind] {
+			if _, ok := m.outages[kind][name]; !ok {
+				units++
+			}
+		}
+		pool := PoolAvailability{Kind: kind, Units: units, Equipment: make([]EquipmentAvailability, 0, len(names))}
+		var totalDowntime time.Duration
+		for _, name := range names {
+			usage := m.outages[kind][name]
+			downtime := usage.downtime
+			if !usage.outSince.IsZero() {
+				downtime += now.Sub(usage.outSince)
+			}
+			unit := EquipmentAvailability{
+				Name:           name,
+				Breakdowns:     usage.breakdowns,
+				Maintenances:   usage.maintenances,
+				Cleanings:      usage.cleanings,
+				AbortedCoffees: usage.aborted,
+				LowestRate:     usage.lowestRate,
+				Downtime:       downtime.Seconds(),
+				Availability:   100,
+				OutOfService:   usage.outages > 0,
+			}
+			if observed > 0 {
+				unit.Availability = 100 * (1 - downtime.Seconds()/observed.Seconds())
+			}
+			pool.Equipment = append(pool.Equipment, unit)
+			pool.Breakdowns += usage.breakdowns
+			pool.Maintenances += usage.maintenances
+			pool.Cleanings += usage.cleanings
+			totalDowntime += downtime
+		}
+		pool.Downtime = totalDowntime.Seconds()
+		pool.Availability = 100
+		if observed > 0 {
+			pool.Availability = 100 * (1 - totalDowntime.Seconds()/(observed.Seconds()*float64(units)))
+		}
+		pools = append(pools, pool)
+	}
+	return pools
+}
@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsAvailability(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Timestamp: start, Payload: &ResourcesRegisteredPayload{Kind: GrinderResource, Names: []string{"grinder1", "grinder2", "grinder3"}}},
+		{Timestamp: start, Payload: &GrinderAcquiredPayload{Grinder: "grinder1"}},
+		{Timestamp: start, Payload: &GrinderAcquiredPayload{Grinder: "grinder2"}},
+		{Timestamp: start.Add(10 * time.Second), Payload: &EquipmentOutOfServicePayload{Kind: "grinder", Equipment: "grinder1", Reason: BreakdownOutage, Aborted: true}},
+		{Timestamp: start.Add(20 * time.Second), Payload: &EquipmentOutOfServicePayload{Kind: "grinder", Equipment: "grinder1", Reason: MaintenanceOutage}},
+		{Timestamp: start.Add(30 * time.Second), Payload: &EquipmentBackInServicePayload{Kind: "grinder", Equipment: "grinder1", Reason: BreakdownOutage}},
+		{Timestamp: start.Add(40 * time.Second), Payload: &EquipmentBackInServicePayload{Kind: "grinder", Equipment: "grinder1", Reason: MaintenanceOutage}},
+		{Timestamp: start.Add(50 * time.Second), Payload: &EquipmentOutOfServicePayload{Kind: "grinder", Equipment: "grinder2", Reason: CleaningOutage}},
+		{Timestamp: start.Add(60 * time.Second), Payload: &EquipmentBackInServicePayload{Kind: "grinder", Equipment: "grinder2", Reason: CleaningOutage}},
+		{Timestamp: start.Add(60 * time.Second), Payload: &EquipmentCleanedPayload{Kind: "grinder", Equipment: "grinder2", Barista: 1, Uses: 10, Rate: 7.5}},
+		{Timestamp: start.Add(80 * time.Second), Payload: &EquipmentOutOfServicePayload{Kind: "brewer", Equipment: "brewer1", Reason: BreakdownOutage}},
+		{Timestamp: start.Add(100 * time.Second), Payload: &GrinderReleasedPayload{Grinder: "grinder2"}},
+	}
+	metrics := NewMetrics()
+	for _, event := range events {
+		metrics.ConsumeEvent(event)
+	}
+
+	snapshot := metrics.Snapshot()
+	require.Len(t, snapshot.Availability, 2)
+	brewer := snapshot.Availability[0]
+	assert.Equal(t, BrewerResource, brewer.Kind)
+	assert.Equal(t, 1, brewer.Units)
+	assert.InDelta(t, 80.0, brewer.Availability, 0.001, "The brewer still broken counts as out until the last event")
+	assert.True(t, brewer.Equipment[0].OutOfService)
+
+	grinder := snapshot.Availability[1]
+	assert.Equal(t, 3, grinder.Units, "The spare grinder never used counts as available")
+	assert.Equal(t, 1, grinder.Breakdowns)
+	assert.Equal(t, 1, grinder.Maintenances)
+	assert.Equal(t, 1, grinder.Cleanings)
+	assert.Equal(t, 40.0, grinder.Downtime, "The overlapping outages are counted once")
+	assert.InDelta(t, 86.667, grinder.Availability, 0.001)
+	assert.Equal(t, EquipmentAvailability{Name: "grinder1", Breakdowns: 1, Maintenances: 1, AbortedCoffees: 1, Downtime: 30, Availability: 70}, grinder.Equipment[0])
+	assert.Equal(t, EquipmentAvailability{Name: "grinder2", Cleanings: 1, LowestRate: 7.5, Downtime: 10, Availability: 90}, grinder.Equipment[1])
+
+	var buffer bytes.Buffer
+	metrics.WritePrometheus(&buffer)
+	assert.Contains(t, buffer.String(), `coffeeshop_equipment_outages_total{kind="grinder",reason="maintenance"} 1`)
+	assert.Contains(t, buffer.String(), `coffeeshop_equipment_outages_total{kind="grinder",reason="cleaning"} 1`)
+	assert.Contains(t, buffer.String(), `coffeeshop_equipment_out_of_service{kind="brewer"} 1`)
+}
@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// beanUsage accumulates the grinder acquisitions for a type of beans
+type beanUsage struct {
+	acquisitions int
+	contended    int
+	wait         time.Duration
+	maxWait      time.Duration
+}
+
+// BeanContention is the contention for the grinders that handle a type of beans, the times are in seconds
+// ContendedAcquisitions is the number of acquisitions that had to wait for a compatible grinder to be available
+type BeanContention struct {
+	Bean                   string  `json:"bean"`
+	Acquisitions           int     `json:"acquisitions"`
+	ContendedAcquisitions  int     `json:"contendedAcquisitions"`
+	AverageAcquisitionWait float64 `json:"averageAcquisitionWait"`
+	MaxAcquisitionWait     float64 `json:"maxAcquisitionWait"`
+}
+
+// consumeBeanEvent updates the contention of the beans from the grinder acquisitions
+// The acquisitions of coffees that do not name their beans are not counted
+func (m *Metrics) consumeBeanEvent(event Event) {
+	payload, ok := event.Payload.(*GrinderAcquiredPayload)
+	if !ok || payload.Bean == "" {
+		return
+	}
+	usage, ok := m.beans[payload.Bean]
+	if !ok {
+		usage = &beanUsage{}
+		m.beans[payload.Bean] = usage
+	}
+	usage.acquisitions++
+	usage.wait += payload.WaitTime
+	if payload.WaitTime > usage.maxWait {
+		usage.maxWait = payload.WaitTime
+	}
+	if payload.WaitTime > contentionThreshold {
+		usage.contended++
+	}
+}
+
+// beanContention returns the contention of every type of beans seen, sorted by name
+func (m *Metrics) beanContention() []BeanContention {
+	contention := make([]BeanContention, 0, len(m.beans))
+	for bean, usage := range m.beans {
+		contention = append(contention, BeanContention{
+			Bean:                   bean,
+			Acquisitions:           usage.acquisitions,
+			ContendedAcquisitions:  usage.contended,
+			AverageAcquisitionWait: (usage.wait / time.Duration(usage.acquisitions)).Seconds(),
+			MaxAcquisitionWait:     usage.maxWait.Seconds(),
+		})
+	}
+	sort.Slice(contention, func(i, j int) bool {
+		return contention[i].Bean < contention[j].Bean
+	})
+	return contention
+}
@@ -0,0 +1,28 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsBeanContention(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Timestamp: start, Payload: &GrinderAcquiredPayload{Grinder: "grinder1", Bean: "espresso"}},
+		{Timestamp: start, Payload: &GrinderAcquiredPayload{Grinder: "grinder2", Bean: "decaf", WaitTime: 2 * time.Second}},
+		{Timestamp: start, Payload: &GrinderAcquiredPayload{Grinder: "grinder1", Bean: "espresso", WaitTime: 4 * time.Second}},
+		{Timestamp: start, Payload: &GrinderAcquiredPayload{Grinder: "grinder3"}},
+	}
+	metrics := NewMetrics()
+	for _, event := range events {
+		metrics.ConsumeEvent(event)
+	}
+
+	snapshot := metrics.Snapshot()
+	require.Len(t, snapshot.Beans, 2, "the coffees without beans are not counted")
+	assert.Equal(t, BeanContention{Bean: "decaf", Acquisitions: 1, ContendedAcquisitions: 1, AverageAcquisitionWait: 2, MaxAcquisitionWait: 2}, snapshot.Beans[0])
+	assert.Equal(t, BeanContention{Bean: "espresso", Acquisitions: 2, ContendedAcquisitions: 1, AverageAcquisitionWait: 2, MaxAcquisitionWait: 4}, snapshot.Beans[1])
+}
@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// coldBrewUsage accumulates the draws from the cold brew stock and the batches that replenished it
+type coldBrewUsage struct {
+	draws       int
+	drawn       decimal.Decimal
+	waitedDraws int
+	wait        time.Duration
+	maxWait     time.Duration
+	batches     int
+	prepareTime time.Duration
+}
+
+// ColdBrewUsage is how the cold brew stock was used, the times are in seconds
+// WaitedDraws is the number of orders that found the stock short and waited for a batch
+type ColdBrewUsage struct {
+	Draws              int     `json:"draws"`
+	Drawn              float64 `json:"drawn"`
+	WaitedDraws        int     `json:"waitedDraws"`
+	AverageDrawWait    float64 `json:"averageDrawWait"`
+	MaxDrawWait        float64 `json:"maxDrawWait"`
+	Batches            int     `json:"batches"`
+	AveragePrepareTime float64 `json:"averagePrepareTime"`
+}
+
+// consumeColdBrewEvent updates the usage of the cold brew from the draws and batches
+func (m *Metrics) consumeColdBrewEvent(event Event) {
+	switch payload := event.Payload.(type) {
+	case *ColdBrewDrawnPayload:
+		m.coldBrew.draws++
+		m.coldBrew.drawn = m.coldBrew.drawn.Add(payload.Ounces)
+		m.coldBrew.wait += payload.WaitTime
+		if payload.WaitTime > m.coldBrew.maxWait {
+			m.coldBrew.maxWait = payload.WaitTime
+		}
+		if payload.WaitTime > contentionThreshold {
+			m.coldBrew.waitedDraws++
+		}
+	case *ColdBrewReplenishedPayload:
+		m.coldBrew.batches++
+		m.coldBrew.prepareTime += payload.PrepareTime
+	}
+}
+
+// coldBrewUsage returns the usage of the cold brew, nil if no cold brew was drawn or prepared
+func (m *Metrics) coldBrewUsage() *ColdBrewUsage {
+	if m.coldBrew.draws == 0 && m.coldBrew.batches == 0 {
+		return nil
+	}
+	usage := &ColdBrewUsage{
+		Draws:       m.coldBrew.draws,
+		Drawn:       m.coldBrew.drawn.InexactFloat64(),
+		WaitedDraws: m.coldBrew.waitedDraws,
+		MaxDrawWait: m.coldBrew.maxWait.Seconds(),
+		Batches:     m.coldBrew.batches,
+	}
+	if m.coldBrew.draws > 0 {
+		usage.AverageDrawWait = (m.coldBrew.wait / time.Duration(m.coldBrew.draws)).Seconds()
+	}
+	if m.coldBrew.batches > 0 {
+		usage.AveragePrepareTime = (m.coldBrew.prepareTime / time.Duration(m.coldBrew.batches)).Seconds()
+	}
+	return usage
+}
@@ -0,0 +1,34 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsColdBrewUsage(t *testing.T) {
+	metrics := NewMetrics()
+	assert.Nil(t, metrics.Snapshot().ColdBrew, "no cold brew was drawn")
+
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Timestamp: start, Payload: &ColdBrewDrawnPayload{Barista: 1, Ounces: decimal.NewFromInt(10), Stock: decimal.NewFromInt(6)}},
+		{Timestamp: start.Add(3 * time.Second), Payload: &ColdBrewReplenishedPayload{Barista: 2, Batch: decimal.NewFromInt(32), Stock: decimal.NewFromInt(38), PrepareTime: 3 * time.Second}},
+		{Timestamp: start.Add(3 * time.Second), Payload: &ColdBrewDrawnPayload{Barista: 3, Ounces: decimal.NewFromFloat(12.5), Stock: decimal.NewFromFloat(25.5), WaitTime: 2 * time.Second}},
+	}
+	for _, event := range events {
+		metrics.ConsumeEvent(event)
+	}
+
+	assert.Equal(t, &ColdBrewUsage{
+		Draws:              2,
+		Drawn:              22.5,
+		WaitedDraws:        1,
+		AverageDrawWait:    1,
+		MaxDrawWait:        2,
+		Batches:            1,
+		AveragePrepareTime: 3,
+	}, metrics.Snapshot().ColdBrew)
+}
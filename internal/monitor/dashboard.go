@@ -0,0 +1,278 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The ANSI escape sequences used to redraw the terminal
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+)
+
+// dashboardBarWidth is the maximum width of the bars of the queues, one block per customer or order
+const dashboardBarWidth = 40
+
+// BaristaStage is what a barista is doing
+type BaristaStage string
+
+const (
+	// BaristaIdle is a barista waiting for an order
+	BaristaIdle BaristaStage = "idle"
+	// BaristaWaitingForGrinder is a barista waiting for a grinder to be available
+	BaristaWaitingForGrinder BaristaStage = "waiting for a grinder"
+	// BaristaGrinding is a barista grinding the beans of an order
+	BaristaGrinding BaristaStage = "grinding"
+	// BaristaWaitingForBrewer is a barista waiting for a brewer to be available
+	BaristaWaitingForBrewer BaristaStage = "waiting for a brewer"
+	// BaristaBrewing is a barista brewing an order
+	BaristaBrewing BaristaStage = "brewing"
+	// BaristaSteaming is a barista steaming the milk of an order
+	BaristaSteaming BaristaStage = "steaming"
+	// BaristaIcing is a barista pouring an iced order over the ice of an ice dispenser
+	BaristaIcing BaristaStage = "icing"
+	// BaristaServing is a barista adding the condiments and handing the coffee to the customer
+	BaristaServing BaristaStage = "serving"
+)
+
+// baristaStatus is the order a barista is working on and its stage
+// equipment is the grinder, brewer, steamer or ice dispenser used in the current stage
+type baristaStatus struct {
+	order     OrderSnapshot
+	stage     BaristaStage
+	equipment string
+}
+
+// Dashboard renders a live view of the coffee shop in the terminal
+// The queues and occupancy are read from the shop, the counters from the metrics,
+// and what each barista, grinder, brewer, steamer and ice dispenser is doing is followed from the lifecycle events
+type Dashboard struct {
+	shop     ShopStater
+	metrics  *Metrics
+	baristas map[int]*baristaStatus
+	// grinders, brewers, steamers and iceDispensers are the order each one is working on, zero when idle
+	grinders      map[string]int64
+	brewers       map[string]int64
+	steamers      map[string]int64
+	iceDispensers map[string]int64
+	// coldBrew is the cold brew in stock after the last draw or batch, nil until the first one
+	coldBrew *decimal.Decimal
+	mutex    sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewDashboard creates a dashboard of the shop, subscribe it to the event system to follow the baristas
+func NewDashboard(shop ShopStater, metrics *Metrics) *Dashboard {
+	return &Dashboard{
+		shop:          shop,
+		metrics:       metrics,
+		baristas:      make(map[int]*baristaStatus),
+		grinders:      make(map[string]int64),
+		brewers:       make(map[string]int64),
+		steamers:      make(map[string]int64),
+		iceDispensers: make(map[string]int64),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// ConsumeEvent follows the stage of each barista, the occupancy of each grinder, brewer, steamer and ice dispenser,
+// and the cold brew in stock
+func (d *Dashboard) ConsumeEvent(event Event) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	switch payload := event.Payload.(type) {
+	case *BaristaAssignedPayload:
+		d.baristas[payload.Barista] = &baristaStatus{order: payload.Order, stage: BaristaWaitingForGrinder}
+	case *GrinderAcquiredPayload:
+		d.grinders[payload.Grinder] = payload.OrderID
+		d.setStage(payload.Barista, BaristaGrinding, payload.Grinder)
+	case *GrinderReleasedPayload:
+		d.grinders[payload.Grinder] = 0
+		d.setStage(payload.Barista, BaristaWaitingForBrewer, "")
+	case *BrewerAcquiredPayload:
+		d.brewers[payload.Brewer] = payload.OrderID
+		d.setStage(payload.Barista, BaristaBrewing, payload.Brewer)
+	case *BrewerReleasedPayload:
+		d.brewers[payload.Brewer] = 0
+		d.setStage(payload.Barista, BaristaServing, "")
+	case *SteamerAcquiredPayload:
+		d.steamers[payload.Steamer] = payload.OrderID
+		d.setStage(payload.Barista, BaristaSteaming, payload.Steamer)
+	case *SteamerReleasedPayload:
+		d.steamers[payload.Steamer] = 0
+		d.setStage(payload.Barista, BaristaServing, "")
+	case *IceDispenserAcquiredPayload:
+		d.iceDispensers[payload.IceDispenser] = payload.OrderID
+		d.setStage(payload.Barista, BaristaIcing, payload.IceDispenser)
+	case *IceDispenserReleasedPayload:
+		d.iceDispensers[payload.IceDispenser] = 0
+		d.setStage(payload.Barista, BaristaServing, "")
+	case *ColdBrewDrawnPayload:
+		d.coldBrew = &payload.Stock
+		d.setStage(payload.Barista, BaristaServing, "")
+	case *ColdBrewReplenishedPayload:
+		d.coldBrew = &payload.Stock
+	case *OrderPickedUpPayload:
+		d.baristas[payload.Barista] = &baristaStatus{stage: BaristaIdle}
+	case *OrderCancelledPayload:
+		d.baristas[payload.Barista] = &baristaStatus{stage: BaristaIdle}
+	}
+}
+
+// setStage moves a barista to the next stage of its order
+func (d *Dashboard) setStage(barista int, stage BaristaStage, equipment string) {
+	status, ok := d.baristas[barista]
+	if !ok {
+		status = &baristaStatus{}
+		d.baristas[barista] = status
+	}
+	status.stage = stage
+	status.equipment = equipment
+}
+
+// Start redraws the dashboard on w at every interval until Stop is called
+func (d *Dashboard) Start(w io.Writer, interval time.Duration) {
+	fmt.Fprint(w, hideCursor)
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		d.Render(w)
+		for {
+			select {
+			case <-ticker.C:
+				d.Render(w)
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops redrawing, the last frame is drawn and left on the terminal
+func (d *Dashboard) Stop(w io.Writer) {
+	close(d.stop)
+	<-d.done
+	d.Render(w)
+	fmt.Fprint(w, showCursor)
+}
+
+// Render draws a frame of the dashboard
+// The frame is written at once, so the terminal does not flicker
+func (d *Dashboard) Render(w io.Writer) {
+	writer := bufio.NewWriter(w)
+	fmt.Fprint(writer, clearScreen)
+	d.WriteFrame(writer)
+	writer.Flush()
+}
+
+// WriteFrame writes the content of a frame, without the escape sequences
+func (d *Dashboard) WriteFrame(w io.Writer) {
+	snapshot := d.metrics.Snapshot()
+	fmt.Fprintf(w, "Coffee shop  %s\n\n", time.Now().Format("15:04:05"))
+	fmt.Fprintf(w, "Orders  received %d  processed %d  completed %d", snapshot.ReceivedOrders, snapshot.ProcessedOrders, snapshot.CompletedOrders)
+	var dropped int
+	for _, count := range snapshot.DroppedEvents {
+		dropped += count
+	}
+	if dropped > 0 {
+		fmt.Fprintf(w, "  dropped events %d", dropped)
+	}
+	fmt.Fprintln(w)
+	if snapshot.CompletedOrders > 0 {
+		fmt.Fprintf(w, "Wait    mean %.1fs  p95 %.1fs  max %.1fs\n", snapshot.WaitTime.Mean, snapshot.WaitTime.P95, snapshot.WaitTime.Max)
+	}
+
+	fmt.Fprintln(w, "\nCashiers")
+	queueSizes := d.shop.CashierQueueSizes()
+	cashiers := make([]int, 0, len(queueSizes))
+	for cashier := range queueSizes {
+		cashiers = append(cashiers, cashier)
+	}
+	sort.Ints(cashiers)
+	for _, cashier := range cashiers {
+		fmt.Fprintf(w, "  %-12s %s\n", fmt.Sprintf("cashier %d", cashier), queueBar(queueSizes[cashier]))
+	}
+	fmt.Fprintf(w, "  %-12s %s\n", "order queue", queueBar(d.shop.OrderQueueSize()))
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	fmt.Fprintf(w, "\nBaristas  %d busy\n", d.shop.BusyBaristas())
+	baristas := make([]int, 0, len(d.baristas))
+	for barista := range d.baristas {
+		baristas = append(baristas, barista)
+	}
+	sort.Ints(baristas)
+	for _, barista := range baristas {
+		fmt.Fprintf(w, "  %-12s %s\n", fmt.Sprintf("barista %d", barista), d.baristas[barista])
+	}
+
+	writeEquipment(w, "Grinders", d.shop.BusyGrinders(), d.shop.BusyGrinders()+d.shop.AvailableGrinders(), d.grinders)
+	writeEquipment(w, "Brewers", d.shop.BusyBrewers(), d.shop.BusyBrewers()+d.shop.AvailableBrewers(), d.brewers)
+	writeEquipment(w, "Steamers", d.shop.BusySteamers(), d.shop.BusySteamers()+d.shop.AvailableSteamers(), d.steamers)
+	writeEquipment(w, "Ice dispensers", d.shop.BusyIceDispensers(), d.shop.BusyIceDispensers()+d.shop.AvailableIceDispensers(), d.iceDispensers)
+	if d.coldBrew != nil {
+		fmt.Fprintf(w, "\nCold brew  %soz in stock\n", d.coldBrew)
+	}
+}
+
+// String describes the stage of the barista and the order it is working on
+func (s *baristaStatus) String() string {
+	if s.stage == BaristaIdle {
+		return string(BaristaIdle)
+	}
+	order := s.order
+	description := fmt.Sprintf("order %d  %s %s", order.OrderID, order.Coffee, order.Size)
+	if len(order.Extras) > 0 {
+		description += " with " + strings.Join(order.Extras, " and ")
+	}
+	if order.Customer != "" {
+		description += " for " + order.Customer
+	}
+	stage := string(s.stage)
+	if s.equipment != "" {
+		stage += " on " + s.equipment
+	}
+	return fmt.Sprintf("%-36s %s", description, stage)
+}
+
+// writeEquipment writes the occupancy of the grinders, brewers, steamers or ice dispensers and the order each one is working on
+func writeEquipment(w io.Writer, title string, busy int, total int, orders map[string]int64) {
+	fmt.Fprintf(w, "\n%s  %d/%d busy\n", title, busy, total)
+	names := make([]string, 0, len(orders))
+	for name := range orders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		state := "idle"
+		if orders[name] != 0 {
+			state = "order " + strconv.FormatInt(orders[name], 10)
+		}
+		fmt.Fprintf(w, "  %-12s %s\n", name, state)
+	}
+}
+
+// queueBar draws the length of a queue as a bar with one block per customer or order
+func queueBar(length int) string {
+	blocks := length
+	if blocks > dashboardBarWidth {
+		blocks = dashboardBarWidth
+	}
+	return fmt.Sprintf("%-*s %d", dashboardBarWidth, strings.Repeat("█", blocks), length)
+}
@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboardFollowsBaristaStages(t *testing.T) {
+	order := OrderSnapshot{OrderRef: OrderRef{OrderID: 7, CustomerRef: CustomerRef{CustomerID: 3, Customer: "alice"}}, Coffee: "Latte", Size: types.Large}
+	ref := order.OrderRef
+	dashboard := NewDashboard(fakeShop{}, NewMetrics())
+
+	steps := []struct {
+		payload   Payload
+		stage     BaristaStage
+		equipment string
+	}{
+		{&BaristaAssignedPayload{Barista: 1, Order: order}, BaristaWaitingForGrinder, ""},
+		{&GrinderAcquiredPayload{OrderRef: ref, Barista: 1, Grinder: "grinder1"}, BaristaGrinding, "grinder1"},
+		{&GrinderReleasedPayload{OrderRef: ref, Barista: 1, Grinder: "grinder1"}, BaristaWaitingForBrewer, ""},
+		{&BrewerAcquiredPayload{OrderRef: ref, Barista: 1, Brewer: "brewer2"}, BaristaBrewing, "brewer2"},
+		{&BrewerReleasedPayload{OrderRef: ref, Barista: 1, Brewer: "brewer2"}, BaristaServing, ""},
+		{&OrderPickedUpPayload{Barista: 1, Order: order}, BaristaIdle, ""},
+	}
+	for _, step := range steps {
+		dashboard.ConsumeEvent(Event{Timestamp: time.Now(), Payload: step.payload})
+		status := dashboard.baristas[1]
+		assert.Equal(t, step.stage, status.stage)
+		assert.Equal(t, step.equipment, status.equipment)
+	}
+	assert.Equal(t, int64(0), dashboard.grinders["grinder1"])
+	assert.Equal(t, int64(0), dashboard.brewers["brewer2"])
+}
+
+func TestDashboardFollowsColdBrew(t *testing.T) {
+	order := OrderSnapshot{OrderRef: OrderRef{OrderID: 8, CustomerRef: CustomerRef{CustomerID: 4, Customer: "bob"}}, Coffee: "Cold Brew", Size: types.Standard}
+	ref := order.OrderRef
+	dashboard := NewDashboard(fakeShop{}, NewMetrics())
+
+	dashboard.ConsumeEvent(Event{Timestamp: time.Now(), Payload: &BaristaAssignedPayload{Barista: 2, Order: order}})
+	dashboard.ConsumeEvent(Event{Timestamp: time.Now(), Payload: &ColdBrewDrawnPayload{OrderRef: ref, Barista: 2, Ounces: decimal.NewFromInt(10), Stock: decimal.NewFromInt(22)}})
+	assert.Equal(t, BaristaServing, dashboard.baristas[2].stage)
+	dashboard.ConsumeEvent(Event{Timestamp: time.Now(), Payload: &IceDispenserAcquiredPayload{OrderRef: ref, Barista: 2, IceDispenser: "ice1"}})
+	assert.Equal(t, BaristaIcing, dashboard.baristas[2].stage)
+	assert.Equal(t, "ice1", dashboard.baristas[2].equipment)
+	assert.Equal(t, int64(8), dashboard.iceDispensers["ice1"])
+
+	var buffer bytes.Buffer
+	dashboard.WriteFrame(&buffer)
+	frame := buffer.String()
+	assert.Contains(t, frame, "Ice dispensers  0/1 busy")
+	assert.Regexp(t, `ice1 +order 8`, frame)
+	assert.Contains(t, frame, "Cold brew  22oz in stock")
+
+	dashboard.ConsumeEvent(Event{Timestamp: time.Now(), Payload: &IceDispenserReleasedPayload{OrderRef: ref, Barista: 2, IceDispenser: "ice1"}})
+	assert.Equal(t, BaristaServing, dashboard.baristas[2].stage)
+	assert.Equal(t, int64(0), dashboard.iceDispensers["ice1"])
+}
+
+func TestDashboardWriteFrame(t *testing.T) {
+	order := OrderSnapshot{OrderRef: OrderRef{OrderID: 7, CustomerRef: CustomerRef{CustomerID: 3, Customer: "alice"}}, Coffee: "Latte", Size: types.Large, Extras: []string{"Milk"}}
+	metrics := NewMetrics()
+	metrics.IncrementReceivedOrders()
+	metrics.IncrementReceivedOrders()
+	metrics.IncrementProcessedOrders()
+	dashboard := NewDashboard(fakeShop{}, metrics)
+	dashboard.ConsumeEvent(Event{Timestamp: time.Now(), Payload: &BaristaAssignedPayload{Barista: 0, Order: order}})
+	dashboard.ConsumeEvent(Event{Timestamp: time.Now(), Payload: &GrinderAcquiredPayload{OrderRef: order.OrderRef, Barista: 0, Grinder: "grinder1"}})
+
+	var buffer bytes.Buffer
+	dashboard.WriteFrame(&buffer)
+	frame := buffer.String()
+
+	assert.Contains(t, frame, "received 2  processed 1  completed 0")
+	assert.Contains(t, frame, "cashier 0    ██ ")
+	assert.Contains(t, frame, "cashier 1    ████ ")
+	assert.Contains(t, frame, "order queue  ███ ")
+	assert.Contains(t, frame, "Baristas  5 busy")
+	assert.Regexp(t, `barista 0 +order 7  Latte large with Milk for alice +grinding on grinder1`, frame)
+	assert.Contains(t, frame, "Grinders  2/3 busy")
+	assert.Regexp(t, `grinder1 +order 7`, frame)
+	assert.Contains(t, frame, "Brewers  1/3 busy")
+	assert.NotContains(t, frame, clearScreen)
+}
+
+func TestQueueBar(t *testing.T) {
+	assert.Equal(t, strings.Repeat(" ", dashboardBarWidth)+" 0", queueBar(0))
+	assert.Equal(t, strings.Repeat("█", dashboardBarWidth)+" 55", queueBar(55))
+}
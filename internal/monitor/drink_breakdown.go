@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+)
+
+// drinkKey identifies a drink on the menu: a coffee type, a size and a combination of extras
+// The extras are sorted and joined, so the order they were asked in does not matter
+type drinkKey struct {
+	coffee string
+	size   types.CoffeeSize
+	extras string
+}
+
+// newDrinkKey creates the key of the drink of an order
+func newDrinkKey(order OrderSnapshot) drinkKey {
+	extras := append([]string{}, order.Extras...)
+	sort.Strings(extras)
+	return drinkKey{coffee: order.Coffee, size: order.Size, extras: strings.Join(extras, "+")}
+}
+
+// drinkTimings are the distributions of the durations of the orders of a drink
+// The steam, condiment and ice times are those of the orders that had the step
+type drinkTimings struct {
+	grindTimes     *Histogram
+	brewTimes      *Histogram
+	steamTimes     *Histogram
+	condimentTimes *Histogram
+	iceTimes       *Histogram
+	waitTimes      *Histogram
+	processTimes   *Histogram
+}
+
+// newDrinkTimings creates empty distributions with the given bucket upper bounds
+func newDrinkTimings(buckets []time.Duration) *drinkTimings {
+	return &drinkTimings{
+		grindTimes:     NewHistogram(buckets),
+		brewTimes:      NewHistogram(buckets),
+		steamTimes:     NewHistogram(buckets),
+		condimentTimes: NewHistogram(buckets),
+		iceTimes:       NewHistogram(buckets),
+		waitTimes:      NewHistogram(buckets),
+		processTimes:   NewHistogram(buckets),
+	}
+}
+
+// DrinkBreakdown are the timings of the completed orders of a drink, in seconds
+// Extras is empty for the drinks without extras
+type DrinkBreakdown struct {
+	Coffee          string           `json:"coffee"`
+	Size            string           `json:"size"`
+	Extras          []string         `json:"extras"`
+	CompletedOrders int              `json:"completedOrders"`
+	GrindTime       HistogramSummary `json:"grindTime"`
+	BrewTime        HistogramSummary `json:"brewTime"`
+	SteamTime       HistogramSummary `json:"steamTime"`
+	CondimentTime   HistogramSummary `json:"condimentTime"`
+	IceTime         HistogramSummary `json:"iceTime"`
+	WaitTime        HistogramSummary `json:"waitTime"`
+	ProcessTime     HistogramSummary `json:"processTime"`
+}
+
+// addDrinkTimings records the durations of a completed order under its drink
+func (m *Metrics) addDrinkTimings(order OrderSnapshot) {
+	key := newDrinkKey(order)
+	timings, ok := m.drinks[key]
+	if !ok {
+		timings = newDrinkTimings(m.buckets)
+		m.drinks[key] = timings
+	}
+	timings.grindTimes.Observe(order.GrindTime)
+	timings.brewTimes.Observe(order.BrewTime)
+	observeStep(timings.steamTimes, order.SteamTime)
+	observeStep(timings.condimentTimes, order.CondimentTime)
+	observeStep(timings.iceTimes, order.IceTime)
+	timings.waitTimes.Observe(order.WaitTime)
+	timings.processTimes.Observe(order.ProcessingTime)
+}
+
+// drinkBreakdowns returns the timings of each drink, the drinks with the longest average wait first
+func (m *Metrics) drinkBreakdowns() []DrinkBreakdown {
+	keys := make([]drinkKey, 0, len(m.drinks))
+	for key := range m.drinks {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		wi, wj := m.drinks[keys[i]].waitTimes.Mean(), m.drinks[keys[j]].waitTimes.Mean()
+		if wi != wj {
+			return wi > wj
+		}
+		if keys[i].coffee != keys[j].coffee {
+			return keys[i].coffee < keys[j].coffee
+		}
+		if keys[i].size != keys[j].size {
+			return keys[i].size < keys[j].size
+		}
+		return keys[i].extras < keys[j].extras
+	})
+
+	breakdowns := make([]DrinkBreakdown, 0, len(keys))
+	for _, key := range keys {
+		timings := m.drinks[key]
+		extras := []string{}
+		if key.extras != "" {
+			extras = strings.Split(key.extras, "+")
+		}
+		breakdowns = append(breakdowns, DrinkBreakdown{
+			Coffee:          key.coffee,
+			Size:            key.size.String(),
+			Extras:          extras,
+			CompletedOrders: timings.waitTimes.Count(),
+			GrindTime:       timings.grindTimes.Summary(),
+			BrewTime:        timings.brewTimes.Summary(),
+			SteamTime:       timings.steamTimes.Summary(),
+			CondimentTime:   timings.condimentTimes.Summary(),
+			IceTime:         timings.iceTimes.Summary(),
+			WaitTime:        timings.waitTimes.Summary(),
+			ProcessTime:     timings.processTimes.Summary(),
+		})
+	}
+	return breakdowns
+}
+
+// WriteDrinkReport writes the timings of each drink as JSON
+func (m *Metrics) WriteDrinkReport(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m.Snapshot().Drinks)
+}
+
+// ExportDrinkReport writes the timings of each drink as JSON to the file at the given path
+func (m *Metrics) ExportDrinkReport(path string) error {
+	return writeFile(path, m.WriteDrinkReport)
+}
@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// drinkCompletedEvent creates an order completed event for the given drink
+func drinkCompletedEvent(coffee string, size types.CoffeeSize, extras []string, waitTime time.Duration) Event {
+	return Event{
+		Type: OrderCompleted,
+		Payload: &OrderCompletedPayload{Order: OrderSnapshot{
+			Coffee:         coffee,
+			Size:           size,
+			Extras:         extras,
+			GrindTime:      time.Second,
+			BrewTime:       2 * time.Second,
+			SteamTime:      3 * time.Second,
+			WaitTime:       waitTime,
+			ProcessingTime: waitTime,
+		}},
+	}
+}
+
+func TestMetricsDrinkBreakdown(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.ConsumeEvent(drinkCompletedEvent("Macchiato", types.Standard, nil, 4*time.Second))
+	metrics.ConsumeEvent(drinkCompletedEvent("Latte", types.ExtraLarge, []string{"sugar", "milk"}, 10*time.Second))
+	// the order of the extras does not matter
+	metrics.ConsumeEvent(drinkCompletedEvent("Latte", types.ExtraLarge, []string{"milk", "sugar"}, 20*time.Second))
+	metrics.ConsumeEvent(drinkCompletedEvent("Latte", types.ExtraLarge, nil, 2*time.Second))
+
+	drinks := metrics.Snapshot().Drinks
+	require.Len(t, drinks, 3)
+
+	// the drinks with the longest average wait come first
+	assert.Equal(t, "Latte", drinks[0].Coffee)
+	assert.Equal(t, "extra-large", drinks[0].Size)
+	assert.Equal(t, []string{"milk", "sugar"}, drinks[0].Extras)
+	assert.Equal(t, 2, drinks[0].CompletedOrders)
+	assert.Equal(t, 15.0, drinks[0].WaitTime.Mean)
+	assert.Equal(t, 20.0, drinks[0].WaitTime.Max)
+	assert.Equal(t, 1.0, drinks[0].GrindTime.Mean)
+	assert.Equal(t, 2.0, drinks[0].BrewTime.Mean)
+	assert.Equal(t, 3.0, drinks[0].SteamTime.Mean)
+	assert.Equal(t, 2, drinks[0].SteamTime.Count)
+	assert.Zero(t, drinks[0].IceTime.Count, "the drink is not iced")
+
+	assert.Equal(t, "Macchiato", drinks[1].Coffee)
+	assert.Equal(t, "standard", drinks[1].Size)
+	assert.Empty(t, drinks[1].Extras)
+	assert.Equal(t, 4.0, drinks[1].WaitTime.Mean)
+
+	assert.Equal(t, "Latte", drinks[2].Coffee)
+	assert.Empty(t, drinks[2].Extras)
+	assert.Equal(t, 2.0, drinks[2].WaitTime.Mean)
+}
+
+func TestMetricsWriteDrinkReport(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.ConsumeEvent(drinkCompletedEvent("Espresso", types.Large, []string{"sugar"}, 3*time.Second))
+
+	var buffer bytes.Buffer
+	require.NoError(t, metrics.WriteDrinkReport(&buffer))
+
+	var drinks []DrinkBreakdown
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &drinks))
+	require.Len(t, drinks, 1)
+	assert.Equal(t, "Espresso", drinks[0].Coffee)
+	assert.Equal(t, "large", drinks[0].Size)
+	assert.Equal(t, []string{"sugar"}, drinks[0].Extras)
+	assert.Equal(t, 1, drinks[0].CompletedOrders)
+}
@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// equipmentWait accumulates the time the baristas were blocked waiting for a kind of equipment
+// waits are the waits of the acquisitions, from the first attempt, so the timed out attempts are part of them
+type equipmentWait struct {
+	acquisitions int
+	timeouts     int
+	wait         time.Duration
+	waits        *Histogram
+}
+
+// EquipmentWait is the time the baristas were blocked waiting for a kind of equipment, the times are in seconds
+// Timeouts is the number of times a barista waited longer than the equipment timeout, it kept waiting
+type EquipmentWait struct {
+	Kind         ResourceKind     `json:"kind"`
+	Acquisitions int              `json:"acquisitions"`
+	Timeouts     int              `json:"timeouts"`
+	TotalWait    float64          `json:"totalWait"`
+	WaitTime     HistogramSummary `json:"waitTime"`
+}
+
+// consumeEquipmentWaitEvent updates the waits for the equipment from the acquisitions and the timeouts, whatever the kind of equipment
+func (m *Metrics) consumeEquipmentWaitEvent(event Event) {
+	switch payload := event.Payload.(type) {
+	case *EquipmentAcquiredPayload:
+		m.addEquipmentWait(payload.Kind, payload.WaitTime)
+	case *EquipmentTimedOutPayload:
+		for _, kind := range payload.Equipment {
+			m.equipmentWaitOf(ResourceKind(kind)).timeouts++
+		}
+	}
+}
+
+// equipmentWaitOf returns the waits for a kind of equipment, creating them on first use
+func (m *Metrics) equipmentWaitOf(kind ResourceKind) *equipmentWait {
+	wait, ok := m.equipment[kind]
+	if !ok {
+		wait = &equipmentWait{waits: NewHistogram(m.buckets)}
+		m.equipment[kind] = wait
+	}
+	return wait
+}
+
+// addEquipmentWait adds the wait of an acquisition of a kind of equipment
+func (m *Metrics) addEquipmentWait(kind ResourceKind, duration time.Duration) {
+	wait := m.equipmentWaitOf(kind)
+	wait.acquisitions++
+	wait.wait += duration
+	wait.waits.Observe(duration)
+}
+
+// equipmentWaits returns the waits for every kind of equipment seen, sorted by kind
+func (m *Metrics) equipmentWaits() []EquipmentWait {
+	waits := make([]EquipmentWait, 0, len(m.equipment))
+	for kind, wait := range m.equipment {
+		waits = append(waits, EquipmentWait{
+			Kind:         kind,
+			Acquisitions: wait.acquisitions,
+			Timeouts:     wait.timeouts,
+			TotalWait:    wait.wait.Seconds(),
+			WaitTime:     wait.waits.Summary(),
+		})
+	}
+	sort.Slice(waits, func(i, j int) bool {
+		return waits[i].Kind < waits[j].Kind
+	})
+	return waits
+}
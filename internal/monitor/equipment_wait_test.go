@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsEquipmentWaits(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Timestamp: start, Payload: &EquipmentAcquiredPayload{Kind: BrewerResource, Equipment: "brewer1", WaitTime: 3 * time.Second}},
+		{Timestamp: start, Payload: &EquipmentTimedOutPayload{Equipment: []string{"brewer", "steamer"}, WaitTime: 5 * time.Second}},
+		{Timestamp: start, Payload: &EquipmentAcquiredPayload{Kind: BrewerResource, Equipment: "brewer2", WaitTime: 6 * time.Second}},
+		{Timestamp: start, Payload: &EquipmentAcquiredPayload{Kind: SteamerResource, Equipment: "steamer1", WaitTime: 6 * time.Second}},
+		{Timestamp: start, Payload: &EquipmentAcquiredPayload{Kind: GrinderResource, Equipment: "grinder1"}},
+		{Timestamp: start, Payload: &EquipmentAcquiredPayload{Kind: "toaster", Equipment: "toaster1", WaitTime: time.Second}},
+	}
+	metrics := NewMetrics()
+	for _, event := range events {
+		metrics.ConsumeEvent(event)
+	}
+
+	snapshot := metrics.Snapshot()
+	require.Len(t, snapshot.Equipment, 4)
+	brewer := snapshot.Equipment[0]
+	assert.Equal(t, BrewerResource, brewer.Kind)
+	assert.Equal(t, 2, brewer.Acquisitions)
+	assert.Equal(t, 1, brewer.Timeouts)
+	assert.Equal(t, 9.0, brewer.TotalWait, "The timed out attempt is part of the wait of the acquisition")
+	assert.Equal(t, 6.0, brewer.WaitTime.Max)
+	assert.Equal(t, GrinderResource, snapshot.Equipment[1].Kind)
+	assert.Zero(t, snapshot.Equipment[1].TotalWait)
+	assert.Equal(t, EquipmentWait{Kind: SteamerResource, Acquisitions: 1, Timeouts: 1, TotalWait: 6, WaitTime: snapshot.Equipment[2].WaitTime}, snapshot.Equipment[2])
+	assert.Equal(t, ResourceKind("toaster"), snapshot.Equipment[3].Kind, "A new kind of equipment is measured too")
+	assert.Equal(t, 1, snapshot.Equipment[3].Acquisitions)
+
+	var buffer bytes.Buffer
+	metrics.WritePrometheus(&buffer)
+	assert.Contains(t, buffer.String(), `coffeeshop_equipment_wait_seconds_bucket{kind="brewer",le="5"} 1`)
+	assert.Contains(t, buffer.String(), `coffeeshop_equipment_wait_seconds_count{kind="brewer"} 2`)
+	assert.Contains(t, buffer.String(), `coffeeshop_equipment_timeouts_total{kind="steamer"} 1`)
+}
@@ -1,5 +1,10 @@
 package monitor
 
+import (
+	"fmt"
+	"time"
+)
+
 // EventType is the type of event
 type EventType int
 
@@ -10,10 +15,184 @@ const (
 	OrderProcessed
 	// OrderCompleted is the event type for when an order is completed
 	OrderCompleted
+	// CustomerArrived is the event type for when a customer enters the coffee shop
+	CustomerArrived
+	// CustomerGreeted is the event type for when a greeter starts greeting a customer
+	CustomerGreeted
+	// CustomerAssignedToCashier is the event type for when a greeter puts a customer in the queue of a cashier
+	CustomerAssignedToCashier
+	// OrderQueued is the event type for when a cashier publishes an order to the order queue
+	OrderQueued
+	// BaristaAssigned is the event type for when a barista picks an order from the order queue
+	BaristaAssigned
+	// GrinderAcquired is the event type for when a barista gets a grinder from the grinder pool
+	GrinderAcquired
+	// GrindStarted is the event type for when a grinder starts grinding the beans of an order
+	GrindStarted
+	// GrindFinished is the event type for when the beans of an order are ground
+	GrindFinished
+	// GrinderReleased is the event type for when a barista returns a grinder to the grinder pool
+	GrinderReleased
+	// BrewerAcquired is the event type for when a barista gets a brewer from the brewer pool
+	BrewerAcquired
+	// BrewStarted is the event type for when a brewer starts brewing an order
+	BrewStarted
+	// BrewFinished is the event type for when the coffee of an order is brewed
+	BrewFinished
+	// BrewerReleased is the event type for when a barista returns a brewer to the brewer pool
+	BrewerReleased
+	// OrderPickedUp is the event type for when the customer picks up the coffee and leaves
+	OrderPickedUp
+	// OrderRejected is the event type for when a cashier turns down an order because an item is out of stock
+	OrderRejected
+	// IngredientSubstituted is the event type for when an extra out of stock is replaced by its substitute
+	IngredientSubstituted
+	// IngredientConsumed is the event type for when an ingredient or supply is used for an order
+	IngredientConsumed
+	// StockOut is the event type for when an item runs out of stock
+	StockOut
+	// ReorderPlaced is the event type for when a purchase order of an item is placed with the supplier
+	ReorderPlaced
+	// DeliveryReceived is the event type for when a delivery of the supplier is received and restocks an item
+	DeliveryReceived
+	// SteamerAcquired is the event type for when a barista gets a steamer from the steamer pool
+	SteamerAcquired
+	// SteamStarted is the event type for when a steamer starts steaming the milk of an order
+	SteamStarted
+	// SteamFinished is the event type for when the milk of an order is steamed
+	SteamFinished
+	// SteamerReleased is the event type for when a barista returns a steamer to the steamer pool
+	SteamerReleased
+	// CondimentsAdded is the event type for when a barista has added the condiments of an order, like sugar
+	CondimentsAdded
+	// IceDispenserAcquired is the event type for when a barista gets an ice dispenser from the ice dispenser pool
+	IceDispenserAcquired
+	// IceDispensed is the event type for when the ice of an iced order is dispensed
+	IceDispensed
+	// IceDispenserReleased is the event type for when a barista returns an ice dispenser to the ice dispenser pool
+	IceDispenserReleased
+	// ColdBrewDrawn is the event type for when a barista draws the cold brew of an order from the cold brew stock
+	ColdBrewDrawn
+	// ColdBrewReplenished is the event type for when a barista adds a batch of cold brew to the cold brew stock
+	ColdBrewReplenished
+	// StepPerformed is the event type for when a barista has performed a step of a recipe by hand, like pouring the coffee,
+	// or on equipment that has no events of its own
+	StepPerformed
+	// EquipmentTimedOut is the event type for when a barista has waited for equipment longer than the equipment timeout,
+	// the barista keeps waiting for it
+	EquipmentTimedOut
+	// EquipmentOutOfService is the event type for when a unit of equipment is taken out of its pool, because it broke down,
+	// for a maintenance or to be cleaned
+	EquipmentOutOfService
+	// EquipmentBackInService is the event type for when a unit of equipment is repaired or maintained and back in its pool
+	EquipmentBackInService
+	// StepAborted is the event type for when the equipment broke down during a step and threw the coffee away,
+	// the barista starts over from the first step whose output was lost, on other units
+	StepAborted
+	// EquipmentCleaned is the event type for when a barista has cleaned a unit of equipment that slowed down with use
+	EquipmentCleaned
+	// ResourcesRegistered is the event type for when the shop opens with its baristas or the units of a kind of equipment,
+	// so the resources that are never used are counted too
+	ResourcesRegistered
+	// EquipmentAcquired is the event type for when a barista gets a unit of any kind of equipment from its pool,
+	// it is sent along with the event of the kind, like GrinderAcquired, if it has one
+	EquipmentAcquired
+	// OrderCancelled is the event type for when a barista gives up an order because its equipment cannot be reserved
+	OrderCancelled
 )
 
+// eventTypeNames maps each event type to the name used in logs and persisted events
+var eventTypeNames = map[EventType]string{
+	OrderReceived:             "OrderReceived",
+	OrderProcessed:            "OrderProcessed",
+	OrderCompleted:            "OrderCompleted",
+	CustomerArrived:           "CustomerArrived",
+	CustomerGreeted:           "CustomerGreeted",
+	CustomerAssignedToCashier: "CustomerAssignedToCashier",
+	OrderQueued:               "OrderQueued",
+	BaristaAssigned:           "BaristaAssigned",
+	GrinderAcquired:           "GrinderAcquired",
+	GrindStarted:              "GrindStarted",
+	GrindFinished:             "GrindFinished",
+	GrinderReleased:           "GrinderReleased",
+	BrewerAcquired:            "BrewerAcquired",
+	BrewStarted:               "BrewStarted",
+	BrewFinished:              "BrewFinished",
+	BrewerReleased:            "BrewerReleased",
+	OrderPickedUp:             "OrderPickedUp",
+	OrderRejected:             "OrderRejected",
+	IngredientSubstituted:     "IngredientSubstituted",
+	IngredientConsumed:        "IngredientConsumed",
+	StockOut:                  "StockOut",
+	ReorderPlaced:             "ReorderPlaced",
+	DeliveryReceived:          "DeliveryReceived",
+	SteamerAcquired:           "SteamerAcquired",
+	SteamStarted:              "SteamStarted",
+	SteamFinished:             "SteamFinished",
+	SteamerReleased:           "SteamerReleased",
+	CondimentsAdded:           "CondimentsAdded",
+	IceDispenserAcquired:      "IceDispenserAcquired",
+	IceDispensed:              "IceDispensed",
+	IceDispenserReleased:      "IceDispenserReleased",
+	ColdBrewDrawn:             "ColdBrewDrawn",
+	ColdBrewReplenished:       "ColdBrewReplenished",
+	StepPerformed:             "StepPerformed",
+	EquipmentTimedOut:         "EquipmentTimedOut",
+	EquipmentOutOfService:     "EquipmentOutOfService",
+	EquipmentBackInService:    "EquipmentBackInService",
+	StepAborted:               "StepAborted",
+	EquipmentCleaned:          "EquipmentCleaned",
+	ResourcesRegistered:       "ResourcesRegistered",
+	EquipmentAcquired:         "EquipmentAcquired",
+	OrderCancelled:            "OrderCancelled",
+}
+
+// Payload is the typed data carried by an event
+// Each event type has its own payload struct, see payload.go
+type Payload interface {
+	EventType() EventType
+}
+
 // Event is the event struct
+// Sequence and Timestamp are assigned by the event system when the event is sent
 type Event struct {
-	Type EventType
-	Data interface{}
+	Type      EventType
+	Sequence  uint64
+	Timestamp time.Time
+	Payload   Payload
+}
+
+// NewEvent creates an event carrying the given payload
+func NewEvent(payload Payload) Event {
+	return Event{
+		Type:    payload.EventType(),
+		Payload: payload,
+	}
+}
+
+// String returns the name of the event type
+func (et EventType) String() string {
+	if name, ok := eventTypeNames[et]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventType(%d)", int(et))
+}
+
+// MarshalText encodes the event type as its name, so persisted events stay readable
+func (et EventType) MarshalText() ([]byte, error) {
+	if _, ok := eventTypeNames[et]; !ok {
+		return nil, fmt.Errorf("unknown event type %d", int(et))
+	}
+	return []byte(et.String()), nil
+}
+
+// UnmarshalText decodes the event type from its name
+func (et *EventType) UnmarshalText(text []byte) error {
+	for eventType, name := range eventTypeNames {
+		if name == string(text) {
+			*et = eventType
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown event type %q", string(text))
 }
@@ -0,0 +1,176 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+)
+
+// EventLogSchemaVersion is the version of the persisted event format
+// It must be bumped whenever a field of the persisted event changes its meaning
+// Version 1 carried the order at the top level, version 2 carries the typed payload
+const EventLogSchemaVersion = 2
+
+// EventConsumer consumes the events sent to the event system
+// Metrics and EventLog are consumers, and so can be anything that back-fills an aggregation from an event log
+type EventConsumer interface {
+	ConsumeEvent(event Event)
+}
+
+// eventRecord is the serializable form of an event
+type eventRecord struct {
+	SchemaVersion int             `json:"schemaVersion"`
+	Sequence      uint64          `json:"sequence"`
+	Timestamp     time.Time       `json:"timestamp"`
+	Type          EventType       `json:"type"`
+	Payload       json.RawMessage `json:"payload,omitempty"`
+	// Order is only set by schema version 1
+	Order *OrderSnapshot `json:"order,omitempty"`
+}
+
+// MarshalJSON encodes the event with the current schema version
+func (e Event) MarshalJSON() ([]byte, error) {
+	record := eventRecord{
+		SchemaVersion: EventLogSchemaVersion,
+		Sequence:      e.Sequence,
+		Timestamp:     e.Timestamp,
+		Type:          e.Type,
+	}
+	if e.Payload != nil {
+		payload, err := json.Marshal(e.Payload)
+		if err != nil {
+			return nil, err
+		}
+		record.Payload = payload
+	}
+	return json.Marshal(record)
+}
+
+// UnmarshalJSON decodes an event written by the current or an older schema version
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var record eventRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	if record.SchemaVersion > EventLogSchemaVersion {
+		return fmt.Errorf("unsupported schema version %d", record.SchemaVersion)
+	}
+
+	*e = Event{
+		Type:      record.Type,
+		Sequence:  record.Sequence,
+		Timestamp: record.Timestamp,
+	}
+	if record.SchemaVersion == 1 {
+		if record.Order != nil {
+			e.Payload = legacyPayload(record.Type, *record.Order)
+		}
+		return nil
+	}
+	if len(record.Payload) == 0 {
+		return nil
+	}
+	newPayload, ok := payloadFactories[record.Type]
+	if !ok {
+		return fmt.Errorf("no payload for event type %s", record.Type)
+	}
+	payload := newPayload()
+	if err := json.Unmarshal(record.Payload, payload); err != nil {
+		return err
+	}
+	e.Payload = payload
+	return nil
+}
+
+// legacyPayload converts the order of a schema version 1 event into the typed payload of its event type
+// Version 1 did not record the actor, so the cashier and barista IDs are left empty
+func legacyPayload(eventType EventType, order OrderSnapshot) Payload {
+	switch eventType {
+	case OrderReceived:
+		return &OrderReceivedPayload{Order: order}
+	case OrderProcessed:
+		return &OrderProcessedPayload{Order: order}
+	case OrderCompleted:
+		return &OrderCompletedPayload{Order: order}
+	}
+	return nil
+}
+
+// EventLog is an append-only JSON Lines file of events
+type EventLog struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	mutex   sync.Mutex
+}
+
+// NewEventLog opens the event log at the given path, creating it if it does not exist
+// Events are appended, so several runs can share the same file
+func NewEventLog(path string) (*EventLog, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open event log %s: %w", path, err)
+	}
+	writer := bufio.NewWriter(file)
+	return &EventLog{
+		file:    file,
+		writer:  writer,
+		encoder: json.NewEncoder(writer),
+	}, nil
+}
+
+// Append writes an event to the event log
+func (el *EventLog) Append(event Event) error {
+	el.mutex.Lock()
+	defer el.mutex.Unlock()
+	return el.encoder.Encode(event)
+}
+
+// ConsumeEvent appends the event to the event log, so the event log can subscribe to the event system
+func (el *EventLog) ConsumeEvent(event Event) {
+	if err := el.Append(event); err != nil {
+		utils.Logger().WithError(err).Error("Error writing event log")
+	}
+}
+
+// Close flushes the buffered events and closes the file
+func (el *EventLog) Close() error {
+	el.mutex.Lock()
+	defer el.mutex.Unlock()
+	if err := el.writer.Flush(); err != nil {
+		el.file.Close()
+		return err
+	}
+	return el.file.Close()
+}
+
+// ReplayEventLog reads the event log at the given path and feeds every event to the consumer in order
+// Events written by a newer schema version are rejected rather than misread
+func ReplayEventLog(path string, consumer EventConsumer) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open event log %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("decode event log %s line %d: %w", path, line, err)
+		}
+		consumer.ConsumeEvent(event)
+	}
+	return scanner.Err()
+}
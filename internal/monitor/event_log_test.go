@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLogReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	eventLog, err := NewEventLog(path)
+	require.NoError(t, err)
+
+	order := types.NewOrder(types.NewCustomer("Alice", nil), types.CoffeeType{Name: "Latte", Price: decimal.NewFromFloat(3.5)}, types.Large, []string{"milk"})
+	require.NoError(t, eventLog.Append(Event{Type: OrderReceived, Sequence: 1, Timestamp: time.Now(), Payload: &OrderReceivedPayload{Cashier: 1, Order: NewOrderSnapshot(order)}}))
+	require.NoError(t, eventLog.Append(Event{Type: OrderProcessed, Sequence: 2, Timestamp: time.Now(), Payload: &OrderProcessedPayload{Barista: 2, Order: NewOrderSnapshot(order)}}))
+
+	order.Coffee().SetGrindTime(2 * time.Second)
+	order.Coffee().SetBrewTime(3 * time.Second)
+	order.Complete()
+	require.NoError(t, eventLog.Append(Event{Type: OrderCompleted, Sequence: 3, Timestamp: time.Now(), Payload: &OrderCompletedPayload{Barista: 2, Order: NewOrderSnapshot(order)}}))
+	require.NoError(t, eventLog.Close())
+
+	// Replay the event log into a fresh metrics object
+	metrics := NewMetrics()
+	require.NoError(t, ReplayEventLog(path, metrics))
+
+	assert.Equal(t, 1, metrics.receivedOrders)
+	assert.Equal(t, 1, metrics.processedOrders)
+	assert.Equal(t, 1, metrics.completedOrders)
+	assert.Equal(t, 2*time.Second, metrics.totalGrindTime)
+	assert.Equal(t, 3*time.Second, metrics.totalBrewTime)
+	assert.Equal(t, order.ProcessingTime(), metrics.totalProcessTime)
+	assert.Equal(t, order.Customer().WaitTime(), metrics.totalWaitTime)
+
+	// The typed payloads survive the round trip
+	recorder := &recordingConsumer{}
+	require.NoError(t, ReplayEventLog(path, recorder))
+	require.Len(t, recorder.events, 3)
+	received := recorder.events[0].Payload.(*OrderReceivedPayload)
+	assert.Equal(t, 1, received.Cashier)
+	assert.Equal(t, "Alice", received.Order.Customer)
+	assert.Equal(t, types.Large, received.Order.Size)
+	assert.Equal(t, 2, recorder.events[2].Payload.(*OrderCompletedPayload).Barista)
+}
+
+func TestEventLogReplaySchemaVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	line := `{"schemaVersion":1,"sequence":7,"timestamp":"2023-04-21T16:03:56Z","type":"OrderCompleted","order":{"customer":"Bob","coffee":"Latte","grindTime":2000000000,"brewTime":3000000000}}`
+	require.NoError(t, os.WriteFile(path, []byte(line+"\n"), 0o644))
+
+	metrics := NewMetrics()
+	require.NoError(t, ReplayEventLog(path, metrics))
+	assert.Equal(t, 1, metrics.completedOrders)
+	assert.Equal(t, 2*time.Second, metrics.totalGrindTime)
+	assert.Equal(t, 3*time.Second, metrics.totalBrewTime)
+}
+
+func TestEventLogReplayRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"schemaVersion":99,"sequence":1,"type":"OrderReceived"}`+"\n"), 0o644))
+
+	assert.Error(t, ReplayEventLog(path, NewMetrics()))
+}
+
+// recordingConsumer keeps every consumed event
+type recordingConsumer struct {
+	events []Event
+}
+
+func (rc *recordingConsumer) ConsumeEvent(event Event) {
+	rc.events = append(rc.events, event)
+}
+
+func TestEventLogRoundTripsEveryEventType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	eventLog, err := NewEventLog(path)
+	require.NoError(t, err)
+
+	for eventType := range eventTypeNames {
+		newPayload, ok := payloadFactories[eventType]
+		require.True(t, ok, "event type %s should have a payload", eventType)
+		payload := newPayload()
+		assert.Equal(t, eventType, payload.EventType())
+		require.NoError(t, eventLog.Append(NewEvent(payload)))
+	}
+	require.NoError(t, eventLog.Close())
+
+	recorder := &recordingConsumer{}
+	require.NoError(t, ReplayEventLog(path, recorder))
+	require.Len(t, recorder.events, len(eventTypeNames))
+	for _, event := range recorder.events {
+		assert.Equal(t, event.Type, event.Payload.EventType())
+	}
+}
@@ -0,0 +1,267 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+)
+
+// eventStreamHistorySize is the number of recent events kept to resume the stream of a client that reconnects
+const eventStreamHistorySize = 1000
+
+// eventStreamClientBufferSize is the number of events buffered for a client before it is considered too slow
+const eventStreamClientBufferSize = 256
+
+// eventStreamKeepAlive is how often a comment is sent to idle clients, so proxies do not close the connection
+const eventStreamKeepAlive = 15 * time.Second
+
+// EventStream pushes the events to the clients of the /events endpoint as Server-Sent Events
+// Subscribe it to the event system with a dropping overflow policy, ConsumeEvent never waits for a client:
+// a client whose buffer is full is disconnected, and resumes from the history when it reconnects with Last-Event-ID
+type EventStream struct {
+	history []Event
+	clients map[*streamClient]struct{}
+	closed  bool
+	mutex   sync.Mutex
+}
+
+// streamClient is a connected client of the stream
+// events is closed when the client is disconnected by the stream, because it is too slow or the stream is closed
+type streamClient struct {
+	filter EventFilter
+	events chan Event
+}
+
+// NewEventStream creates an event stream without clients
+func NewEventStream() *EventStream {
+	return &EventStream{
+		clients: make(map[*streamClient]struct{}),
+	}
+}
+
+// ConsumeEvent keeps the event in the history and hands it to the clients accepting it
+func (s *EventStream) ConsumeEvent(event Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.history) == eventStreamHistorySize {
+		copy(s.history, s.history[1:])
+		s.history = s.history[:len(s.history)-1]
+	}
+	s.history = append(s.history, event)
+
+	for client := range s.clients {
+		if client.filter != nil && !client.filter(event) {
+			continue
+		}
+		select {
+		case client.events <- event:
+		default:
+			s.disconnect(client)
+		}
+	}
+}
+
+// Close disconnects every client, the clients connecting afterwards only get the events they missed
+func (s *EventStream) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	for client := range s.clients {
+		s.disconnect(client)
+	}
+}
+
+// connect registers a client and, if it resumes, returns the events of the history after lastSequence it accepts
+// Both are done under the lock, so no event is missed or sent twice between the history and the live events
+func (s *EventStream) connect(filter EventFilter, resume bool, lastSequence uint64) (*streamClient, []Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var missed []Event
+	for _, event := range s.history {
+		if resume && event.Sequence > lastSequence && (filter == nil || filter(event)) {
+			missed = append(missed, event)
+		}
+	}
+	client := &streamClient{filter: filter, events: make(chan Event, eventStreamClientBufferSize)}
+	if s.closed {
+		close(client.events)
+	} else {
+		s.clients[client] = struct{}{}
+	}
+	return client, missed
+}
+
+// leave removes a client that went away, unless the stream already disconnected it
+func (s *EventStream) leave(client *streamClient) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.clients[client]; ok {
+		s.disconnect(client)
+	}
+}
+
+// disconnect removes the client and ends its stream, the caller holds the lock
+func (s *EventStream) disconnect(client *streamClient) {
+	delete(s.clients, client)
+	close(client.events)
+}
+
+// Clients returns the number of connected clients
+func (s *EventStream) Clients() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.clients)
+}
+
+// EventStreamHandler serves the events of the stream as Server-Sent Events, the data of each event is its JSON form
+// The query parameters type and order, repeated or comma separated, only keep the events of the given types and orders
+// A new client only gets the events sent after it connected,
+// a client reconnecting with the Last-Event-ID header, or the lastEventId query parameter, first gets the recent events it missed
+func EventStreamHandler(stream *EventStream) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		filter, err := parseEventStreamFilter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lastSequence, resume, err := parseLastEventID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		client, missed := stream.connect(filter, resume, lastSequence)
+		defer stream.leave(client)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		for _, event := range missed {
+			if err := writeServerSentEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventStreamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case event, ok := <-client.events:
+				if !ok {
+					return
+				}
+				if err := writeServerSentEvent(w, event); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+			flusher.Flush()
+		}
+	})
+}
+
+// writeServerSentEvent writes the event with its sequence number as ID and its type as event name
+func writeServerSentEvent(w http.ResponseWriter, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		utils.Logger().WithError(err).WithField("sequence", event.Sequence).Error("Error encoding streamed event")
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, data)
+	return err
+}
+
+// parseEventStreamFilter returns the filter of the type and order query parameters, nil if there are none
+func parseEventStreamFilter(r *http.Request) (EventFilter, error) {
+	var eventTypes []EventType
+	for _, name := range queryValues(r, "type") {
+		var eventType EventType
+		if err := eventType.UnmarshalText([]byte(name)); err != nil {
+			return nil, err
+		}
+		eventTypes = append(eventTypes, eventType)
+	}
+	orders := make(map[int64]bool)
+	for _, value := range queryValues(r, "order") {
+		orderID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order ID %q", value)
+		}
+		orders[orderID] = true
+	}
+
+	if len(eventTypes) == 0 && len(orders) == 0 {
+		return nil, nil
+	}
+	acceptsType := EventTypes(eventTypes...)
+	return func(event Event) bool {
+		if len(eventTypes) > 0 && !acceptsType(event) {
+			return false
+		}
+		if len(orders) > 0 {
+			orderID, ok := EventOrderID(event)
+			return ok && orders[orderID]
+		}
+		return true
+	}, nil
+}
+
+// parseLastEventID returns the sequence number of the last event the client received, and false for a new client
+func parseLastEventID(r *http.Request) (uint64, bool, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+	sequence, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid last event ID %q", value)
+	}
+	return sequence, true, nil
+}
+
+// queryValues returns the values of a query parameter, which can be repeated or comma separated
+func queryValues(r *http.Request, key string) []string {
+	var values []string
+	for _, value := range r.URL.Query()[key] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+	}
+	return values
+}
+
+// EventOrderID returns the ID of the order the event belongs to, see OrderPayload
+// The customer events sent before the order is taken do not belong to an order
+func EventOrderID(event Event) (int64, bool) {
+	if payload, ok := event.Payload.(OrderPayload); ok {
+		return payload.BelongsToOrder()
+	}
+	return 0, false
+}
@@ -0,0 +1,202 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serverSentEvent is an event read from the stream
+type serverSentEvent struct {
+	id    string
+	name  string
+	event Event
+}
+
+// readServerSentEvents reads count events from the stream
+func readServerSentEvents(t *testing.T, reader *bufio.Reader, count int) []serverSentEvent {
+	var events []serverSentEvent
+	var current serverSentEvent
+	for len(events) < count {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			current.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.event))
+		case line == "" && current.id != "":
+			events = append(events, current)
+			current = serverSentEvent{}
+		}
+	}
+	return events
+}
+
+// streamedOrderEvents returns the events of an order, numbered from the given sequence
+func streamedOrderEvents(orderID int64, sequence uint64) []Event {
+	ref := OrderRef{OrderID: orderID, CustomerRef: CustomerRef{CustomerID: orderID, Customer: "alice"}}
+	order := OrderSnapshot{OrderRef: ref, Coffee: "Latte"}
+	payloads := []Payload{
+		&CustomerArrivedPayload{CustomerRef: ref.CustomerRef},
+		&OrderQueuedPayload{Cashier: 1, Order: order},
+		&GrindStartedPayload{OrderRef: ref, Grinder: "grinder1"},
+		&OrderCompletedPayload{Barista: 2, Order: order},
+	}
+	events := make([]Event, len(payloads))
+	for i, payload := range payloads {
+		events[i] = NewEvent(payload)
+		events[i].Sequence = sequence + uint64(i)
+		events[i].Timestamp = time.Now()
+	}
+	return events
+}
+
+// openEventStream connects to the stream and waits until the client is registered
+func openEventStream(t *testing.T, stream *EventStream, url string, header http.Header) *bufio.Reader {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	clients := stream.Clients()
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	t.Cleanup(func() { response.Body.Close() })
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	require.Eventually(t, func() bool { return stream.Clients() > clients }, time.Second, time.Millisecond)
+	return bufio.NewReader(response.Body)
+}
+
+func TestEventStreamHandlerFiltersLiveEvents(t *testing.T) {
+	stream := NewEventStream()
+	server := httptest.NewServer(EventStreamHandler(stream))
+	t.Cleanup(server.Close)
+
+	for _, event := range streamedOrderEvents(2, 1) {
+		stream.ConsumeEvent(event)
+	}
+	reader := openEventStream(t, stream, server.URL+"?type=OrderQueued,OrderCompleted&order=2", nil)
+	for _, event := range streamedOrderEvents(2, 5) {
+		stream.ConsumeEvent(event)
+	}
+
+	events := readServerSentEvents(t, reader, 2)
+	assert.Equal(t, "6", events[0].id)
+	assert.Equal(t, "OrderQueued", events[0].name)
+	assert.Equal(t, "8", events[1].id)
+	assert.Equal(t, "OrderCompleted", events[1].name)
+	require.IsType(t, &OrderCompletedPayload{}, events[1].event.Payload)
+	assert.Equal(t, int64(2), events[1].event.Payload.(*OrderCompletedPayload).Order.OrderID)
+}
+
+func TestEventStreamHandlerResumesFromLastEventID(t *testing.T) {
+	stream := NewEventStream()
+	server := httptest.NewServer(EventStreamHandler(stream))
+	t.Cleanup(server.Close)
+
+	for _, event := range streamedOrderEvents(1, 1) {
+		stream.ConsumeEvent(event)
+	}
+	reader := openEventStream(t, stream, server.URL, http.Header{"Last-Event-ID": []string{"2"}})
+	for _, event := range streamedOrderEvents(2, 5) {
+		stream.ConsumeEvent(event)
+	}
+
+	var ids []string
+	for _, event := range readServerSentEvents(t, reader, 6) {
+		ids = append(ids, event.id)
+	}
+	assert.Equal(t, []string{"3", "4", "5", "6", "7", "8"}, ids)
+}
+
+func TestEventStreamHandlerRejectsInvalidParameters(t *testing.T) {
+	handler := EventStreamHandler(NewEventStream())
+	for _, url := range []string{"/events?type=Unknown", "/events?order=abc", "/events?lastEventId=-1"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, url)
+	}
+}
+
+func TestEventStreamDisconnectsSlowClients(t *testing.T) {
+	stream := NewEventStream()
+	client, missed := stream.connect(nil, true, 0)
+	assert.Empty(t, missed)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < eventStreamClientBufferSize+10; i++ {
+			stream.ConsumeEvent(Event{Sequence: uint64(i + 1), Payload: &CustomerArrivedPayload{}})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ConsumeEvent blocked on a slow client")
+	}
+
+	assert.Zero(t, stream.Clients())
+	count := 0
+	for range client.events {
+		count++
+	}
+	assert.Equal(t, eventStreamClientBufferSize, count)
+}
+
+func TestEventStreamKeepsRecentHistory(t *testing.T) {
+	stream := NewEventStream()
+	for i := 0; i < eventStreamHistorySize+5; i++ {
+		stream.ConsumeEvent(Event{Sequence: uint64(i + 1), Payload: &CustomerArrivedPayload{}})
+	}
+	_, missed := stream.connect(nil, true, 0)
+	require.Len(t, missed, eventStreamHistorySize)
+	assert.Equal(t, uint64(6), missed[0].Sequence)
+
+	stream.Close()
+	client, _ := stream.connect(nil, true, 0)
+	_, ok := <-client.events
+	assert.False(t, ok, "a client connecting after Close is not kept")
+}
+
+func TestEventOrderID(t *testing.T) {
+	orderID, ok := EventOrderID(NewEvent(&BrewStartedPayload{OrderRef: OrderRef{OrderID: 4}}))
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), orderID)
+
+	orderID, ok = EventOrderID(NewEvent(&OrderPickedUpPayload{Order: OrderSnapshot{OrderRef: OrderRef{OrderID: 5}}}))
+	assert.True(t, ok)
+	assert.Equal(t, int64(5), orderID)
+
+	_, ok = EventOrderID(NewEvent(&CustomerGreetedPayload{}))
+	assert.False(t, ok)
+}
+
+func TestEveryPayloadWithAnOrderBelongsToIt(t *testing.T) {
+	orderTypes := []reflect.Type{reflect.TypeOf(OrderRef{}), reflect.TypeOf(OrderSnapshot{})}
+	for eventType, newPayload := range payloadFactories {
+		payload := reflect.TypeOf(newPayload()).Elem()
+		for i := 0; i < payload.NumField(); i++ {
+			if field := payload.Field(i); field.Type == orderTypes[0] || field.Type == orderTypes[1] {
+				_, ok := newPayload().(OrderPayload)
+				assert.True(t, ok, "the %s payload carries an order, its events belong to it", eventType)
+			}
+		}
+	}
+}
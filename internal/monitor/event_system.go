@@ -2,65 +2,166 @@ package monitor
 
 import (
 	"sync"
+	"time"
 
-	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
 )
 
+// defaultSubscriberBufferSize is used when the settings do not configure a buffer size
+const defaultSubscriberBufferSize = 100
+
+// droppedAfterStop is the name under which the events sent after the event system is stopped are counted
+const droppedAfterStop = "afterStop"
+
 type EventSystemer interface {
 	SendEvent(event Event)
 }
 
-// EventSystem is the event system that will be used to monitor the coffee shop
+// EventSystem is the event bus that will be used to monitor the coffee shop
+// Every event is stamped with a sequence number and a timestamp, and delivered to each subscriber whose filter accepts it
+// The metrics are always subscribed, and so is the event log if one is configured
+// Events dropped by the overflow policy, or sent after Stop, are counted in the metrics
 type EventSystem struct {
-	eventChannel chan Event
-	metrics      *Metrics
-	wg           sync.WaitGroup
+	subscriptions  []*Subscription
+	bufferSize     int
+	overflowPolicy OverflowPolicy
+	metrics        *Metrics
+	eventLog       *EventLog
+	sequence       uint64
+	stopped        bool
+	mutex          sync.Mutex
 }
 
 // NewEventSystem creates a new EventSystem
-func NewEventSystem() *EventSystem {
-	return &EventSystem{
-		eventChannel: make(chan Event),
-		metrics:      NewMetrics(),
-		wg:           sync.WaitGroup{},
+// If the settings name an event log path, the events are appended to that file
+func NewEventSystem(settings *config.MonitorSettings) (*EventSystem, error) {
+	if settings == nil {
+		settings = &config.MonitorSettings{}
+	}
+	buckets, err := ParseHistogramBuckets(settings.HistogramBuckets)
+	if err != nil {
+		return nil, err
+	}
+	es := &EventSystem{
+		bufferSize: defaultSubscriberBufferSize,
+		metrics:    NewMetrics(buckets...),
+	}
+	if settings.SubscriberBufferSize > 0 {
+		es.bufferSize = settings.SubscriberBufferSize
+	}
+	overflowPolicy, err := ParseOverflowPolicy(settings.OverflowPolicy)
+	if err != nil {
+		return nil, err
+	}
+	es.overflowPolicy = overflowPolicy
+	if settings.EventLogPath != "" {
+		eventLog, err := NewEventLog(settings.EventLogPath)
+		if err != nil {
+			return nil, err
+		}
+		es.eventLog = eventLog
+		es.Subscribe("eventLog", eventLog, nil)
 	}
+	es.Subscribe("metrics", es.metrics, nil)
+	return es, nil
 }
 
-// SendEvent sends an event to the event system
-func (es *EventSystem) SendEvent(event Event) {
-	es.wg.Add(1)
-	es.eventChannel <- event
+// Subscribe registers a consumer for the events accepted by the filter
+// The consumer is called from a dedicated goroutine, in sequence order
+// The subscription uses the buffer size and overflow policy of the event system
+func (es *EventSystem) Subscribe(name string, consumer EventConsumer, filter EventFilter) *Subscription {
+	return es.SubscribeWithPolicy(name, consumer, filter, es.overflowPolicy)
+}
+
+// SubscribeWithPolicy registers a consumer with its own overflow policy
+// For example, an exporter that can afford to lose events should not slow down the baristas
+func (es *EventSystem) SubscribeWithPolicy(name string, consumer EventConsumer, filter EventFilter, policy OverflowPolicy) *Subscription {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	subscription := newSubscription(name, consumer, filter, es.bufferSize, policy, es.sequence+1)
+	es.subscriptions = append(es.subscriptions, subscription)
+	return subscription
+}
+
+// Unsubscribe removes the subscription, the events already buffered are still consumed
+func (es *EventSystem) Unsubscribe(subscription *Subscription) {
+	es.mutex.Lock()
+	found := false
+	for i, s := range es.subscriptions {
+		if s == subscription {
+			// the senders may be iterating over the subscriptions, they are copied rather than changed in place
+			subscriptions := make([]*Subscription, 0, len(es.subscriptions)-1)
+			subscriptions = append(subscriptions, es.subscriptions[:i]...)
+			es.subscriptions = append(subscriptions, es.subscriptions[i+1:]...)
+			found = true
+			break
+		}
+	}
+	es.mutex.Unlock()
+
+	// a subscription that is not registered anymore has already been closed by Stop or Unsubscribe
+	if found {
+		subscription.close()
+	}
 }
 
-// StartEventListener starts the event listener
-// It will listen to the event channel and update the metrics based on the event type
-func (es *EventSystem) StartEventListener() {
-	for event := range es.eventChannel {
-		switch event.Type {
-		case OrderReceived:
-			es.metrics.IncrementReceivedOrders()
-		case OrderProcessed:
-			es.metrics.IncrementProcessedOrders()
-		case OrderCompleted:
-			es.metrics.IncrementCompletedOrders()
-			order := event.Data.(*types.Order)
-			es.metrics.AddGrindTime(order.Coffee().GrindTime())
-			es.metrics.AddBrewTime(order.Coffee().BrewTime())
-			es.metrics.AddWaitTime(order.Customer().WaitTime())
-			es.metrics.AddProcessTime(event.Data.(*types.Order).ProcessingTime())
+// SendEvent stamps the event and sends it to every subscriber that accepts it
+// The sequence number is assigned while holding the lock, and each subscriber receives the events in sequence order
+// The events are sent once the lock is released, so it only blocks when the buffer of a subscriber with the Block policy
+// is full, and only the senders behind it wait meanwhile
+// It is safe to call after Stop, the event is then dropped and counted
+func (es *EventSystem) SendEvent(event Event) {
+	es.mutex.Lock()
+	if es.stopped {
+		es.mutex.Unlock()
+		es.metrics.IncrementDroppedEvents(droppedAfterStop)
+		return
+	}
+	es.sequence++
+	event.Sequence = es.sequence
+	event.Timestamp = time.Now()
+	subscriptions := es.subscriptions
+	es.mutex.Unlock()
+
+	for _, subscription := range subscriptions {
+		if !subscription.send(event) {
+			es.metrics.IncrementDroppedEvents(subscription.Name())
 		}
-		es.wg.Done()
 	}
 }
 
+// Metrics returns the metrics fed by the event system
+func (es *EventSystem) Metrics() *Metrics {
+	return es.metrics
+}
+
 // PrintMetricsSummary prints the metrics summary
 func (es *EventSystem) PrintMetricsSummary() {
 	es.metrics.PrintSummary()
 }
 
 // Stop stops the event system
-// It closes the event channel and waits for all events to be processed
+// It closes every subscription, waits for all events to be consumed and closes the event log
+// It is safe to call more than once
 func (es *EventSystem) Stop() {
-	close(es.eventChannel)
-	es.wg.Wait()
+	es.mutex.Lock()
+	if es.stopped {
+		es.mutex.Unlock()
+		return
+	}
+	es.stopped = true
+	subscriptions := es.subscriptions
+	es.subscriptions = nil
+	es.mutex.Unlock()
+
+	for _, subscription := range subscriptions {
+		subscription.close()
+	}
+	if es.eventLog != nil {
+		if err := es.eventLog.Close(); err != nil {
+			utils.Logger().WithError(err).Error("Error closing event log")
+		}
+	}
 }
@@ -0,0 +1,188 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventSystemSubscribers(t *testing.T) {
+	eventSystem, err := NewEventSystem(&config.MonitorSettings{})
+	require.NoError(t, err)
+
+	all := &recordingConsumer{}
+	completed := &recordingConsumer{}
+	eventSystem.Subscribe("all", all, nil)
+	eventSystem.Subscribe("completed", completed, EventTypes(OrderCompleted))
+
+	eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{Cashier: 1}))
+	eventSystem.SendEvent(NewEvent(&OrderProcessedPayload{Barista: 1}))
+	eventSystem.SendEvent(NewEvent(&OrderCompletedPayload{Barista: 1}))
+	eventSystem.Stop()
+
+	require.Len(t, all.events, 3)
+	for i, event := range all.events {
+		assert.Equal(t, uint64(i+1), event.Sequence)
+		assert.False(t, event.Timestamp.IsZero())
+	}
+	require.Len(t, completed.events, 1)
+	assert.Equal(t, OrderCompleted, completed.events[0].Type)
+	assert.Equal(t, uint64(3), completed.events[0].Sequence)
+
+	// The metrics are always subscribed
+	assert.Equal(t, 1, eventSystem.Metrics().receivedOrders)
+	assert.Equal(t, 1, eventSystem.Metrics().completedOrders)
+}
+
+func TestEventSystemUnsubscribe(t *testing.T) {
+	eventSystem, err := NewEventSystem(nil)
+	require.NoError(t, err)
+
+	recorder := &recordingConsumer{}
+	subscription := eventSystem.Subscribe("probe", recorder, nil)
+	eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{}))
+	eventSystem.Unsubscribe(subscription)
+	eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{}))
+	eventSystem.Stop()
+
+	assert.Len(t, recorder.events, 1)
+	assert.Equal(t, 2, eventSystem.Metrics().receivedOrders)
+}
+
+func TestEventSystemEventLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	eventSystem, err := NewEventSystem(&config.MonitorSettings{EventLogPath: path})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{Cashier: i}))
+	}
+	eventSystem.Stop()
+
+	recorder := &recordingConsumer{}
+	require.NoError(t, ReplayEventLog(path, recorder))
+	require.Len(t, recorder.events, 5)
+	for i, event := range recorder.events {
+		assert.Equal(t, uint64(i+1), event.Sequence)
+		assert.Equal(t, i, event.Payload.(*OrderReceivedPayload).Cashier)
+	}
+}
+
+// blockingConsumer records the events but does not consume any until it is released
+type blockingConsumer struct {
+	recordingConsumer
+	release chan struct{}
+}
+
+func (bc *blockingConsumer) ConsumeEvent(event Event) {
+	<-bc.release
+	bc.recordingConsumer.ConsumeEvent(event)
+}
+
+func TestEventSystemOverflowPolicies(t *testing.T) {
+	for _, test := range []struct {
+		policy    OverflowPolicy
+		sequences []uint64
+	}{
+		// the first event is taken by the consumer, the buffer holds the next two
+		{policy: DropNewest, sequences: []uint64{1, 2, 3}},
+		{policy: DropOldest, sequences: []uint64{1, 4, 5}},
+	} {
+		t.Run(test.policy.String(), func(t *testing.T) {
+			eventSystem, err := NewEventSystem(&config.MonitorSettings{SubscriberBufferSize: 2, OverflowPolicy: "block"})
+			require.NoError(t, err)
+
+			consumer := &blockingConsumer{release: make(chan struct{})}
+			subscription := eventSystem.SubscribeWithPolicy("slow", consumer, nil, test.policy)
+
+			eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{}))
+			// wait for the consumer to take the first event
+			require.Eventually(t, func() bool { return subscription.Pending() == 0 }, time.Second, time.Millisecond)
+			for i := 0; i < 4; i++ {
+				eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{}))
+			}
+			close(consumer.release)
+			eventSystem.Stop()
+
+			sequences := make([]uint64, 0, len(consumer.events))
+			for _, event := range consumer.events {
+				sequences = append(sequences, event.Sequence)
+			}
+			assert.Equal(t, test.sequences, sequences)
+			assert.Equal(t, int64(2), subscription.Dropped())
+			assert.Equal(t, 2, eventSystem.Metrics().droppedEvents["slow"])
+			// the metrics block instead of dropping, so they see every event
+			assert.Equal(t, 5, eventSystem.Metrics().receivedOrders)
+		})
+	}
+}
+
+func TestEventSystemBlockedSubscriberDoesNotHoldTheLock(t *testing.T) {
+	eventSystem, err := NewEventSystem(&config.MonitorSettings{SubscriberBufferSize: 1})
+	require.NoError(t, err)
+	consumer := &blockingConsumer{release: make(chan struct{})}
+	subscription := eventSystem.Subscribe("slow", consumer, nil)
+
+	eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{}))
+	require.Eventually(t, func() bool { return subscription.Pending() == 0 }, time.Second, time.Millisecond)
+	eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{}))
+	sent := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		go func() {
+			eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{}))
+			sent <- struct{}{}
+		}()
+	}
+
+	// the senders wait for the slow subscriber, the event system is not locked meanwhile
+	done := make(chan struct{})
+	go func() {
+		eventSystem.Subscribe("late", &recordingConsumer{}, nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe waited for the slow subscriber")
+	}
+
+	close(consumer.release)
+	for i := 0; i < 3; i++ {
+		<-sent
+	}
+	eventSystem.Stop()
+	sequences := make([]uint64, 0, len(consumer.events))
+	for _, event := range consumer.events {
+		sequences = append(sequences, event.Sequence)
+	}
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, sequences, "The blocked senders are delivered in sequence order")
+	assert.Equal(t, 5, eventSystem.Metrics().receivedOrders)
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for name, expected := range map[string]OverflowPolicy{"": Block, "block": Block, "drop-newest": DropNewest, "drop-oldest": DropOldest} {
+		policy, err := ParseOverflowPolicy(name)
+		require.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+
+	_, err := NewEventSystem(&config.MonitorSettings{OverflowPolicy: "drop-everything"})
+	assert.Error(t, err)
+}
+
+func TestEventSystemSendEventAfterStop(t *testing.T) {
+	eventSystem, err := NewEventSystem(nil)
+	require.NoError(t, err)
+	eventSystem.Stop()
+
+	assert.NotPanics(t, func() {
+		eventSystem.SendEvent(NewEvent(&OrderReceivedPayload{}))
+		eventSystem.Stop()
+	})
+	assert.Equal(t, 0, eventSystem.Metrics().receivedOrders)
+	assert.Equal(t, 1, eventSystem.Metrics().droppedEvents[droppedAfterStop])
+}
@@ -0,0 +1,200 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultHistogramBuckets are the upper bounds of the histogram buckets used when none are configured
+// They cover the grinding and brewing times of a single coffee up to the waiting time of a rush hour
+var DefaultHistogramBuckets = []time.Duration{
+	500 * time.Millisecond,
+	1 * time.Second,
+	2 * time.Second,
+	3 * time.Second,
+	4 * time.Second,
+	5 * time.Second,
+	7500 * time.Millisecond,
+	10 * time.Second,
+	15 * time.Second,
+	20 * time.Second,
+	30 * time.Second,
+	45 * time.Second,
+	60 * time.Second,
+	90 * time.Second,
+	120 * time.Second,
+	180 * time.Second,
+	300 * time.Second,
+}
+
+// ParseHistogramBuckets converts bucket upper bounds in seconds, as written in the config file, to durations
+// The bounds must be positive and strictly increasing, no bounds means the default buckets
+func ParseHistogramBuckets(seconds []float64) ([]time.Duration, error) {
+	if len(seconds) == 0 {
+		return DefaultHistogramBuckets, nil
+	}
+	buckets := make([]time.Duration, len(seconds))
+	for i, s := range seconds {
+		if s <= 0 {
+			return nil, fmt.Errorf("histogram bucket %v must be positive", s)
+		}
+		if i > 0 && s <= seconds[i-1] {
+			return nil, fmt.Errorf("histogram buckets must be increasing, %v follows %v", s, seconds[i-1])
+		}
+		buckets[i] = time.Duration(s * float64(time.Second))
+	}
+	return buckets, nil
+}
+
+// Histogram is a streaming histogram of durations with fixed buckets
+// It keeps the exact count, mean, standard deviation and maximum, the percentiles are interpolated within a bucket
+// It is not thread safe, Metrics guards it with its own mutex
+type Histogram struct {
+	bounds []time.Duration
+	// counts has one more bucket than bounds for the values above the last bound
+	counts     []int
+	count      int
+	sum        float64
+	sumSquares float64
+	min        time.Duration
+	max        time.Duration
+}
+
+// HistogramSummary is the summary of a histogram, in seconds
+type HistogramSummary struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	Max    float64 `json:"max"`
+}
+
+// NewHistogram creates a histogram with the given bucket upper bounds
+func NewHistogram(bounds []time.Duration) *Histogram {
+	sorted := append([]time.Duration{}, bounds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &Histogram{
+		bounds: sorted,
+		counts: make([]int, len(sorted)+1),
+	}
+}
+
+// Observe adds a duration to the histogram
+func (h *Histogram) Observe(duration time.Duration) {
+	bucket := sort.Search(len(h.bounds), func(i int) bool { return duration <= h.bounds[i] })
+	h.counts[bucket]++
+	if h.count == 0 || duration < h.min {
+		h.min = duration
+	}
+	if duration > h.max {
+		h.max = duration
+	}
+	h.count++
+	seconds := duration.Seconds()
+	h.sum += seconds
+	h.sumSquares += seconds * seconds
+}
+
+// Count returns the number of observed durations
+func (h *Histogram) Count() int {
+	return h.count
+}
+
+// Sum returns the sum of the observed durations in seconds
+func (h *Histogram) Sum() float64 {
+	return h.sum
+}
+
+// Bounds returns the bucket upper bounds
+func (h *Histogram) Bounds() []time.Duration {
+	return append([]time.Duration{}, h.bounds...)
+}
+
+// CumulativeCounts returns, for each bound, the number of observed durations lower than or equal to it
+func (h *Histogram) CumulativeCounts() []int {
+	cumulative := make([]int, len(h.bounds))
+	total := 0
+	for i := range h.bounds {
+		total += h.counts[i]
+		cumulative[i] = total
+	}
+	return cumulative
+}
+
+// Mean returns the mean of the observed durations
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return secondsToDuration(h.sum / float64(h.count))
+}
+
+// StdDev returns the population standard deviation of the observed durations
+func (h *Histogram) StdDev() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	mean := h.sum / float64(h.count)
+	variance := h.sumSquares/float64(h.count) - mean*mean
+	if variance < 0 {
+		// rounding errors when all the durations are equal
+		variance = 0
+	}
+	return secondsToDuration(math.Sqrt(variance))
+}
+
+// Max returns the largest observed duration
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Percentile returns an estimate of the duration below which the given percentage of the durations fall
+// The estimate is interpolated linearly within the bucket, and never exceeds the observed minimum and maximum
+func (h *Histogram) Percentile(percentile float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := percentile / 100 * float64(h.count)
+	cumulative := 0
+	for i, count := range h.counts {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		lower := h.min
+		if i > 0 && h.bounds[i-1] > lower {
+			lower = h.bounds[i-1]
+		}
+		upper := h.max
+		if i < len(h.bounds) && h.bounds[i] < upper {
+			upper = h.bounds[i]
+		}
+		fraction := (rank - float64(cumulative)) / float64(count)
+		return lower + time.Duration(fraction*float64(upper-lower))
+	}
+	return h.max
+}
+
+// Summary returns the count, mean, standard deviation, p50, p90, p95, p99 and maximum in seconds
+func (h *Histogram) Summary() HistogramSummary {
+	return HistogramSummary{
+		Count:  h.count,
+		Mean:   h.Mean().Seconds(),
+		StdDev: h.StdDev().Seconds(),
+		P50:    h.Percentile(50).Seconds(),
+		P90:    h.Percentile(90).Seconds(),
+		P95:    h.Percentile(95).Seconds(),
+		P99:    h.Percentile(99).Seconds(),
+		Max:    h.Max().Seconds(),
+	}
+}
+
+// secondsToDuration converts a number of seconds to a duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
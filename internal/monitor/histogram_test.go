@@ -0,0 +1,65 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	histogram := NewHistogram([]time.Duration{time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second})
+
+	// Test an empty histogram
+	assert.Equal(t, time.Duration(0), histogram.Percentile(50))
+	assert.Equal(t, HistogramSummary{}, histogram.Summary())
+
+	// 1s to 100s, one observation per second
+	for i := 1; i <= 100; i++ {
+		histogram.Observe(time.Duration(i) * time.Second)
+	}
+
+	assert.Equal(t, 100, histogram.Count())
+	assert.Equal(t, 50500*time.Millisecond, histogram.Mean())
+	assert.InDelta(t, 28.866, histogram.StdDev().Seconds(), 0.001)
+	assert.Equal(t, 100*time.Second, histogram.Max())
+	assert.Equal(t, []int{1, 2, 5, 10}, histogram.CumulativeCounts())
+
+	// The percentiles are interpolated within the overflow bucket, between 10s and the maximum
+	assert.Equal(t, 50*time.Second, histogram.Percentile(50))
+	assert.Equal(t, 90*time.Second, histogram.Percentile(90))
+	assert.Equal(t, 99*time.Second, histogram.Percentile(99))
+	assert.Equal(t, 100*time.Second, histogram.Percentile(100))
+	// The percentiles within a bucket stay within its bounds
+	assert.Equal(t, 5*time.Second, histogram.Percentile(5))
+}
+
+func TestHistogramEqualDurations(t *testing.T) {
+	histogram := NewHistogram(DefaultHistogramBuckets)
+	for i := 0; i < 10; i++ {
+		histogram.Observe(3 * time.Second)
+	}
+
+	summary := histogram.Summary()
+	assert.Equal(t, 3.0, summary.Mean)
+	assert.Equal(t, 0.0, summary.StdDev)
+	assert.Equal(t, 3.0, summary.P50)
+	assert.Equal(t, 3.0, summary.P99)
+	assert.Equal(t, 3.0, summary.Max)
+}
+
+func TestParseHistogramBuckets(t *testing.T) {
+	buckets, err := ParseHistogramBuckets(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultHistogramBuckets, buckets)
+
+	buckets, err = ParseHistogramBuckets([]float64{0.5, 1, 2.5})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second, 2500 * time.Millisecond}, buckets)
+
+	_, err = ParseHistogramBuckets([]float64{1, 1})
+	assert.Error(t, err)
+	_, err = ParseHistogramBuckets([]float64{-1})
+	assert.Error(t, err)
+}
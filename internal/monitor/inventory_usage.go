@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// itemUsage is the usage of an item of the inventory
+// outSince is when the item ran out of stock, zero while it is in stock
+type itemUsage struct {
+	consumed      decimal.Decimal
+	wasted        decimal.Decimal
+	restocked     decimal.Decimal
+	reorders      int
+	deliveries    int
+	partial       int
+	leadTime      time.Duration
+	substitutions int
+	stockOuts     int
+	outSince      time.Time
+	outOfStock    time.Duration
+}
+
+// InventoryUsage is the usage of an item of the inventory during the run
+// Consumed is what went into the orders and Wasted what was lost doing so, in the unit of the item
+// Restocked is what the supplier delivered, Reorders the purchase orders placed and PartialDeliveries the deliveries
+// that brought less than ordered, AverageLeadTimeSeconds is the average time between a purchase order and its delivery
+// StockOutMinutes is the time the item was out of stock, up to the last event if it is still out of stock
+type InventoryUsage struct {
+	Item                   string  `json:"item"`
+	Consumed               float64 `json:"consumed"`
+	Wasted                 float64 `json:"wasted"`
+	Restocked              float64 `json:"restocked"`
+	Reorders               int     `json:"reorders"`
+	PartialDeliveries      int     `json:"partialDeliveries"`
+	AverageLeadTimeSeconds float64 `json:"averageLeadTimeSeconds"`
+	Substitutions          int     `json:"substitutions"`
+	StockOuts              int     `json:"stockOuts"`
+	StockOutMinutes        float64 `json:"stockOutMinutes"`
+}
+
+// item returns the usage of the item, creating it the first time the item is seen
+func (m *Metrics) item(name string) *itemUsage {
+	usage, ok := m.inventory[name]
+	if !ok {
+		usage = &itemUsage{}
+		m.inventory[name] = usage
+	}
+	return usage
+}
+
+// consumeInventoryEvent updates the usage of the items from the inventory events
+func (m *Metrics) consumeInventoryEvent(event Event) {
+	switch payload := event.Payload.(type) {
+	case *OrderRejectedPayload:
+		m.rejectedOrders++
+	case *IngredientSubstitutedPayload:
+		m.item(payload.Item).substitutions++
+	case *IngredientConsumedPayload:
+		usage := m.item(payload.Item)
+		usage.consumed = usage.consumed.Add(payload.Amount)
+		usage.wasted = usage.wasted.Add(payload.Waste)
+	case *StockOutPayload:
+		usage := m.item(payload.Item)
+		if usage.outSince.IsZero() {
+			usage.stockOuts++
+			usage.outSince = event.Timestamp
+		}
+	case *ReorderPlacedPayload:
+		m.item(payload.Item).reorders++
+	case *DeliveryReceivedPayload:
+		usage := m.item(payload.Item)
+		usage.restocked = usage.restocked.Add(payload.Delivered)
+		usage.deliveries++
+		usage.leadTime += payload.LeadTime
+		if payload.Delivered.LessThan(payload.Ordered) {
+			usage.partial++
+		}
+		if !usage.outSince.IsZero() {
+			usage.outOfStock += event.Timestamp.Sub(usage.outSince)
+			usage.outSince = time.Time{}
+		}
+	}
+}
+
+// inventoryUsage returns the usage of every item seen, sorted by name
+func (m *Metrics) inventoryUsage() []InventoryUsage {
+	usages := make([]InventoryUsage, 0, len(m.inventory))
+	for name, usage := range m.inventory {
+		outOfStock := usage.outOfStock
+		if !usage.outSince.IsZero() {
+			outOfStock += m.lastEventTime.Sub(usage.outSince)
+		}
+		averageLeadTime := 0.0
+		if usage.deliveries > 0 {
+			averageLeadTime = (usage.leadTime / time.Duration(usage.deliveries)).Seconds()
+		}
+		usages = append(usages, InventoryUsage{
+			Item:                   name,
+			Consumed:               usage.consumed.InexactFloat64(),
+			Wasted:                 usage.wasted.InexactFloat64(),
+			Restocked:              usage.restocked.InexactFloat64(),
+			Reorders:               usage.reorders,
+			PartialDeliveries:      usage.partial,
+			AverageLeadTimeSeconds: averageLeadTime,
+			Substitutions:          usage.substitutions,
+			StockOuts:              usage.stockOuts,
+			StockOutMinutes:        outOfStock.Minutes(),
+		})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Item < usages[j].Item
+	})
+	return usages
+}
@@ -0,0 +1,39 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsInventoryUsage(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Timestamp: start, Payload: &IngredientConsumedPayload{Item: "beans", Amount: decimal.NewFromInt(30), Waste: decimal.NewFromInt(3)}},
+		{Timestamp: start, Payload: &IngredientConsumedPayload{Item: "beans", Amount: decimal.NewFromInt(20), Waste: decimal.NewFromInt(2)}},
+		{Timestamp: start.Add(time.Minute), Payload: &StockOutPayload{Item: "milk"}},
+		{Timestamp: start.Add(time.Minute), Payload: &IngredientSubstitutedPayload{Item: "milk", Substitute: "oat milk"}},
+		{Timestamp: start.Add(90 * time.Second), Payload: &StockOutPayload{Item: "milk"}},
+		{Timestamp: start.Add(time.Minute), Payload: &ReorderPlacedPayload{Item: "milk", Quantity: decimal.NewFromInt(40)}},
+		{Timestamp: start.Add(3 * time.Minute), Payload: &DeliveryReceivedPayload{Item: "milk", Ordered: decimal.NewFromInt(40), Delivered: decimal.NewFromInt(10), LeadTime: 2 * time.Minute}},
+		{Timestamp: start.Add(3 * time.Minute), Payload: &ReorderPlacedPayload{Item: "milk", Quantity: decimal.NewFromInt(40)}},
+		{Timestamp: start.Add(4 * time.Minute), Payload: &DeliveryReceivedPayload{Item: "milk", Ordered: decimal.NewFromInt(40), Delivered: decimal.NewFromInt(40), LeadTime: time.Minute}},
+		{Timestamp: start.Add(4 * time.Minute), Payload: &StockOutPayload{Item: "cups"}},
+		{Timestamp: start.Add(4 * time.Minute), Payload: &OrderRejectedPayload{Item: "cups"}},
+		{Timestamp: start.Add(5 * time.Minute), Payload: &OrderCompletedPayload{}},
+	}
+	metrics := NewMetrics()
+	for _, event := range events {
+		metrics.ConsumeEvent(event)
+	}
+
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, 1, snapshot.RejectedOrders)
+	require.Len(t, snapshot.Inventory, 3)
+	assert.Equal(t, InventoryUsage{Item: "beans", Consumed: 50, Wasted: 5}, snapshot.Inventory[0])
+	assert.Equal(t, InventoryUsage{Item: "cups", StockOuts: 1, StockOutMinutes: 1}, snapshot.Inventory[1], "a stock out lasts until the last event if the item is not restocked")
+	assert.Equal(t, InventoryUsage{Item: "milk", Restocked: 50, Reorders: 2, PartialDeliveries: 1, AverageLeadTimeSeconds: 90, Substitutions: 1, StockOuts: 1, StockOutMinutes: 2}, snapshot.Inventory[2])
+}
@@ -8,19 +8,51 @@ import (
 )
 
 // Metrics represents the metrics of the coffee shop
+// The times of the events it consumes are its clock, so the rolling windows follow the simulation, even when replayed
 type Metrics struct {
 	receivedOrders   int
 	processedOrders  int
 	completedOrders  int
+	rejectedOrders   int
 	totalProcessTime time.Duration
 	totalGrindTime   time.Duration
 	totalBrewTime    time.Duration
 	totalWaitTime    time.Duration
-	metricsMutex     sync.Mutex
+	droppedEvents    map[string]int
+	processTimes     *Histogram
+	grindTimes       *Histogram
+	brewTimes        *Histogram
+	waitTimes        *Histogram
+	steamTimes       *Histogram
+	condimentTimes   *Histogram
+	iceTimes         *Histogram
+	firstEventTime   time.Time
+	lastEventTime    time.Time
+	// completions are the orders completed within the largest rolling window, oldest first
+	completions []completion
+	utilization *utilization
+	// drinks are the timings broken down by coffee type, size and extras, with the same buckets as the other histograms
+	drinks  map[drinkKey]*drinkTimings
+	buckets []time.Duration
+	// inventory is the consumption, waste and stock outs of each item
+	inventory map[string]*itemUsage
+	// beans is the contention for the grinders of each type of beans
+	beans map[string]*beanUsage
+	// equipment is the time the baristas were blocked waiting for each kind of equipment
+	equipment map[ResourceKind]*equipmentWait
+	// outages are the breakdowns and maintenances of each unit of equipment, by kind and name
+	outages map[ResourceKind]map[string]*outageUsage
+	// coldBrew is the usage of the cold brew stock
+	coldBrew     coldBrewUsage
+	metricsMutex sync.Mutex
 }
 
 // NewMetrics creates a new metrics object
-func NewMetrics() *Metrics {
+// The durations are also recorded in histograms with the given bucket upper bounds, or DefaultHistogramBuckets if none are given
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultHistogramBuckets
+	}
 	return &Metrics{
 		receivedOrders:   0,
 		processedOrders:  0,
@@ -29,6 +61,21 @@ func NewMetrics() *Metrics {
 		totalGrindTime:   0,
 		totalBrewTime:    0,
 		totalWaitTime:    0,
+		droppedEvents:    make(map[string]int),
+		processTimes:     NewHistogram(buckets),
+		grindTimes:       NewHistogram(buckets),
+		brewTimes:        NewHistogram(buckets),
+		waitTimes:        NewHistogram(buckets),
+		steamTimes:       NewHistogram(buckets),
+		condimentTimes:   NewHistogram(buckets),
+		iceTimes:         NewHistogram(buckets),
+		utilization:      newUtilization(),
+		drinks:           make(map[drinkKey]*drinkTimings),
+		buckets:          buckets,
+		inventory:        make(map[string]*itemUsage),
+		beans:            make(map[string]*beanUsage),
+		equipment:        make(map[ResourceKind]*equipmentWait),
+		outages:          make(map[ResourceKind]map[string]*outageUsage),
 		metricsMutex:     sync.Mutex{},
 	}
 }
@@ -57,51 +104,164 @@ func (m *Metrics) IncrementCompletedOrders() {
 // AddProcessTime adds the given duration to the total process time
 func (m *Metrics) AddProcessTime(duration time.Duration) {
 	m.metricsMutex.Lock()
-	m.totalProcessTime += duration
+	m.addProcessTime(duration)
 	m.metricsMutex.Unlock()
 }
 
+// addProcessTime is AddProcessTime without locking
+func (m *Metrics) addProcessTime(duration time.Duration) {
+	m.totalProcessTime += duration
+	m.processTimes.Observe(duration)
+}
+
 // AddGrindTime adds the given duration to the total grind time
 func (m *Metrics) AddGrindTime(duration time.Duration) {
 	m.metricsMutex.Lock()
-	m.totalGrindTime += duration
+	m.addGrindTime(duration)
 	m.metricsMutex.Unlock()
 }
 
+// addGrindTime is AddGrindTime without locking
+func (m *Metrics) addGrindTime(duration time.Duration) {
+	m.totalGrindTime += duration
+	m.grindTimes.Observe(duration)
+}
+
 // AddBrewTime adds the given duration to the total brew time
 func (m *Metrics) AddBrewTime(duration time.Duration) {
 	m.metricsMutex.Lock()
-	m.totalBrewTime += duration
+	m.addBrewTime(duration)
 	m.metricsMutex.Unlock()
 }
 
+// addBrewTime is AddBrewTime without locking
+func (m *Metrics) addBrewTime(duration time.Duration) {
+	m.totalBrewTime += duration
+	m.brewTimes.Observe(duration)
+}
+
 // AddWaitTime adds the given duration to the total wait time
 func (m *Metrics) AddWaitTime(duration time.Duration) {
 	m.metricsMutex.Lock()
+	m.addWaitTime(duration)
+	m.metricsMutex.Unlock()
+}
+
+// addWaitTime is AddWaitTime without locking
+func (m *Metrics) addWaitTime(duration time.Duration) {
 	m.totalWaitTime += duration
+	m.waitTimes.Observe(duration)
+}
+
+// addPreparationTimes records the steam, condiment and ice times of a completed order
+// A step the order did not have is not counted, so the espressos do not hide how long the milk of the lattes takes to steam
+func (m *Metrics) addPreparationTimes(order OrderSnapshot) {
+	observeStep(m.steamTimes, order.SteamTime)
+	observeStep(m.condimentTimes, order.CondimentTime)
+	observeStep(m.iceTimes, order.IceTime)
+}
+
+// observeStep records the duration of a step in its histogram if the order had the step
+func observeStep(histogram *Histogram, duration time.Duration) {
+	if duration > 0 {
+		histogram.Observe(duration)
+	}
+}
+
+// IncrementDroppedEvents increments the number of events dropped for the given subscriber
+func (m *Metrics) IncrementDroppedEvents(subscriber string) {
+	m.metricsMutex.Lock()
+	m.droppedEvents[subscriber]++
 	m.metricsMutex.Unlock()
 }
 
-// PrintSummary prints the metrics summary
-// This function is not thread safe, it should be called only after all the events are processed
-// and the event listener is stopped
-func (m *Metrics) PrintSummary() {
+// ConsumeEvent updates the metrics based on the event payload
+// It is used both as a subscriber of the event system and when replaying an event log
+// An event is applied as a whole, so a snapshot never sees half of it
+func (m *Metrics) ConsumeEvent(event Event) {
 	m.metricsMutex.Lock()
 	defer m.metricsMutex.Unlock()
 
+	m.advanceClock(event.Timestamp)
+	m.utilization.consumeEvent(event)
+	m.consumeInventoryEvent(event)
+	m.consumeBeanEvent(event)
+	m.consumeEquipmentWaitEvent(event)
+	m.consumeAvailabilityEvent(event)
+	m.consumeColdBrewEvent(event)
+	switch payload := event.Payload.(type) {
+	case *OrderReceivedPayload:
+		m.receivedOrders++
+	case *OrderProcessedPayload:
+		m.processedOrders++
+	case *OrderCompletedPayload:
+		m.completedOrders++
+		m.addGrindTime(payload.Order.GrindTime)
+		m.addBrewTime(payload.Order.BrewTime)
+		m.addWaitTime(payload.Order.WaitTime)
+		m.addProcessTime(payload.Order.ProcessingTime)
+		m.addPreparationTimes(payload.Order)
+		m.addCompletion(event.Timestamp, payload.Order.WaitTime)
+		m.addDrinkTimings(payload.Order)
+	}
+}
+
+// PrintSummary prints the metrics summary
+// It is thread safe, the summary is built from a snapshot of the metrics
+func (m *Metrics) PrintSummary() {
+	snapshot := m.Snapshot()
+
 	logger := utils.Logger().WithFields(utils.LogFields{
-		"received_orders":  m.receivedOrders,
-		"processed_orders": m.processedOrders,
-		"completed_orders": m.completedOrders,
+		"received_orders":  snapshot.ReceivedOrders,
+		"processed_orders": snapshot.ProcessedOrders,
+		"completed_orders": snapshot.CompletedOrders,
 	})
-	if m.completedOrders > 0 {
-		// Calculate average times for each order
-		// avoid division by zero
+	if len(snapshot.DroppedEvents) > 0 {
+		logger = logger.WithField("dropped_events", snapshot.DroppedEvents)
+	}
+	if snapshot.RejectedOrders > 0 {
+		logger = logger.WithField("rejected_orders", snapshot.RejectedOrders)
+	}
+	// the consumption, waste and stock out minutes of each item, to tune the stock and reorder points
+	if len(snapshot.Inventory) > 0 {
+		logger = logger.WithField("inventory", snapshot.Inventory)
+	}
+	// the waits for a grinder by beans, to tell which beans need another grinder
+	if len(snapshot.Beans) > 0 {
+		logger = logger.WithField("beans", snapshot.Beans)
+	}
+	// the time the baristas were blocked waiting for each kind of equipment, to tell which one needs another unit
+	if len(snapshot.Equipment) > 0 {
+		logger = logger.WithField("equipment_waits", snapshot.Equipment)
+	}
+	// the breakdowns and maintenances of the equipment, to tell how much redundancy the shop needs
+	if len(snapshot.Availability) > 0 {
+		logger = logger.WithField("availability", snapshot.Availability)
+	}
+	// the waits for a batch of cold brew, to tune its replenish point
+	if snapshot.ColdBrew != nil {
+		logger = logger.WithField("cold_brew", snapshot.ColdBrew)
+	}
+	if snapshot.CompletedOrders > 0 {
 		logger = logger.WithFields(utils.LogFields{
-			"average_grinding_time": (m.totalGrindTime / time.Duration(m.completedOrders)).Seconds(),
-			"average_brewing_time":  (m.totalBrewTime / time.Duration(m.completedOrders)).Seconds(),
-			"average_waiting_time":  (m.totalWaitTime / time.Duration(m.completedOrders)).Seconds(),
-			"average_process_time":  (m.totalProcessTime / time.Duration(m.completedOrders)).Seconds(),
+			"average_grinding_time": snapshot.AverageGrindTime,
+			"average_brewing_time":  snapshot.AverageBrewTime,
+			"average_waiting_time":  snapshot.AverageWaitTime,
+			"average_process_time":  snapshot.AverageProcessTime,
+			// the distributions show the tail hidden by the averages
+			"grinding_time":   snapshot.GrindTime,
+			"brewing_time":    snapshot.BrewTime,
+			"steaming_time":   snapshot.SteamTime,
+			"condiment_time":  snapshot.CondimentTime,
+			"ice_time":        snapshot.IceTime,
+			"waiting_time":    snapshot.WaitTime,
+			"process_time":    snapshot.ProcessTime,
+			"rolling_windows": snapshot.Windows,
+			// the utilization of each pool and resource shows whether the bottleneck is staff or equipment
+			"pools":     snapshot.Pools,
+			"resources": snapshot.Resources,
+			// the drinks with the longest waits come first
+			"drinks": snapshot.Drinks,
 		})
 	}
 
@@ -0,0 +1,176 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+)
+
+// RollingWindows are the windows of the rolling aggregates, in simulated time
+var RollingWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// completion is an order completed at a given time
+type completion struct {
+	time     time.Time
+	waitTime time.Duration
+}
+
+// MetricsSnapshot is a consistent copy of the metrics at a point in time
+// It shares nothing with the metrics, so it can be read while the metrics keep being updated
+// The times are in seconds, the steam, condiment and ice times are those of the orders that had the step
+type MetricsSnapshot struct {
+	Time               time.Time          `json:"time"`
+	ReceivedOrders     int                `json:"receivedOrders"`
+	ProcessedOrders    int                `json:"processedOrders"`
+	CompletedOrders    int                `json:"completedOrders"`
+	RejectedOrders     int                `json:"rejectedOrders"`
+	DroppedEvents      map[string]int     `json:"droppedEvents,omitempty"`
+	AverageGrindTime   float64            `json:"averageGrindTime"`
+	AverageBrewTime    float64            `json:"averageBrewTime"`
+	AverageWaitTime    float64            `json:"averageWaitTime"`
+	AverageProcessTime float64            `json:"averageProcessTime"`
+	GrindTime          HistogramSummary   `json:"grindTime"`
+	BrewTime           HistogramSummary   `json:"brewTime"`
+	SteamTime          HistogramSummary   `json:"steamTime"`
+	CondimentTime      HistogramSummary   `json:"condimentTime"`
+	IceTime            HistogramSummary   `json:"iceTime"`
+	WaitTime           HistogramSummary   `json:"waitTime"`
+	ProcessTime        HistogramSummary   `json:"processTime"`
+	Windows            []WindowAggregates `json:"windows"`
+	// Pools and Resources are the utilization of the baristas, grinders and brewers since the first event
+	Pools     []PoolUtilization     `json:"pools"`
+	Resources []ResourceUtilization `json:"resources"`
+	// Drinks are the timings broken down by coffee type, size and extras
+	Drinks []DrinkBreakdown `json:"drinks"`
+	// Inventory is the usage of each item of the inventory
+	Inventory []InventoryUsage `json:"inventory"`
+	// Beans is the contention for the grinders of each type of beans
+	Beans []BeanContention `json:"beans"`
+	// Equipment is the time the baristas were blocked waiting for each kind of equipment
+	Equipment []EquipmentWait `json:"equipment"`
+	// Availability is the time the equipment was broken down, maintained or cleaned, for the kinds of equipment that were out of service
+	Availability []PoolAvailability `json:"availability"`
+	// ColdBrew is the usage of the cold brew stock, nil if no cold brew was drawn or prepared
+	ColdBrew *ColdBrewUsage `json:"coldBrew,omitempty"`
+}
+
+// WindowAggregates are the throughput and wait times of the orders completed within a rolling window
+// Throughput is in orders per minute, over the part of the window the shop has been open, the times are in seconds
+type WindowAggregates struct {
+	Window          float64 `json:"window"`
+	CompletedOrders int     `json:"completedOrders"`
+	Throughput      float64 `json:"throughput"`
+	AverageWaitTime float64 `json:"averageWaitTime"`
+	P95WaitTime     float64 `json:"p95WaitTime"`
+	MaxWaitTime     float64 `json:"maxWaitTime"`
+}
+
+// Snapshot returns a consistent copy of the metrics
+// It can be called at any time, including while events are being consumed
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.metricsMutex.Lock()
+	defer m.metricsMutex.Unlock()
+
+	snapshot := MetricsSnapshot{
+		Time:            m.lastEventTime,
+		ReceivedOrders:  m.receivedOrders,
+		ProcessedOrders: m.processedOrders,
+		CompletedOrders: m.completedOrders,
+		RejectedOrders:  m.rejectedOrders,
+		DroppedEvents:   make(map[string]int, len(m.droppedEvents)),
+		GrindTime:       m.grindTimes.Summary(),
+		BrewTime:        m.brewTimes.Summary(),
+		SteamTime:       m.steamTimes.Summary(),
+		CondimentTime:   m.condimentTimes.Summary(),
+		IceTime:         m.iceTimes.Summary(),
+		WaitTime:        m.waitTimes.Summary(),
+		ProcessTime:     m.processTimes.Summary(),
+		Windows:         make([]WindowAggregates, 0, len(RollingWindows)),
+	}
+	for subscriber, dropped := range m.droppedEvents {
+		snapshot.DroppedEvents[subscriber] = dropped
+	}
+	// avoid division by zero
+	if m.completedOrders > 0 {
+		snapshot.AverageGrindTime = (m.totalGrindTime / time.Duration(m.completedOrders)).Seconds()
+		snapshot.AverageBrewTime = (m.totalBrewTime / time.Duration(m.completedOrders)).Seconds()
+		snapshot.AverageWaitTime = (m.totalWaitTime / time.Duration(m.completedOrders)).Seconds()
+		snapshot.AverageProcessTime = (m.totalProcessTime / time.Duration(m.completedOrders)).Seconds()
+	}
+	for _, window := range RollingWindows {
+		snapshot.Windows = append(snapshot.Windows, m.windowAggregates(window))
+	}
+	snapshot.Resources, snapshot.Pools = m.utilization.summaries(m.lastEventTime, m.lastEventTime.Sub(m.firstEventTime))
+	snapshot.Drinks = m.drinkBreakdowns()
+	snapshot.Inventory = m.inventoryUsage()
+	snapshot.Beans = m.beanContention()
+	snapshot.Equipment = m.equipmentWaits()
+	snapshot.Availability = m.availability(m.lastEventTime, m.lastEventTime.Sub(m.firstEventTime))
+	snapshot.ColdBrew = m.coldBrewUsage()
+	return snapshot
+}
+
+// advanceClock moves the clock of the metrics to the time of the event being consumed
+// Events are consumed in sequence order, so the clock never goes backwards by more than the send jitter
+func (m *Metrics) advanceClock(eventTime time.Time) {
+	if eventTime.IsZero() {
+		return
+	}
+	if m.firstEventTime.IsZero() {
+		m.firstEventTime = eventTime
+	}
+	if eventTime.After(m.lastEventTime) {
+		m.lastEventTime = eventTime
+	}
+	m.pruneCompletions()
+}
+
+// addCompletion records an order completed at the given time for the rolling windows
+func (m *Metrics) addCompletion(completedTime time.Time, waitTime time.Duration) {
+	if completedTime.IsZero() {
+		return
+	}
+	m.completions = append(m.completions, completion{time: completedTime, waitTime: waitTime})
+}
+
+// pruneCompletions drops the completions that fell out of the largest rolling window
+func (m *Metrics) pruneCompletions() {
+	cutoff := m.lastEventTime.Add(-RollingWindows[len(RollingWindows)-1])
+	kept := sort.Search(len(m.completions), func(i int) bool { return m.completions[i].time.After(cutoff) })
+	if kept > 0 {
+		m.completions = append(m.completions[:0], m.completions[kept:]...)
+	}
+}
+
+// windowAggregates computes the aggregates of the orders completed within the window ending at the clock of the metrics
+func (m *Metrics) windowAggregates(window time.Duration) WindowAggregates {
+	aggregates := WindowAggregates{Window: window.Seconds()}
+	cutoff := m.lastEventTime.Add(-window)
+	waitTimes := make([]time.Duration, 0, len(m.completions))
+	var totalWaitTime time.Duration
+	for _, c := range m.completions {
+		if !c.time.After(cutoff) {
+			continue
+		}
+		waitTimes = append(waitTimes, c.waitTime)
+		totalWaitTime += c.waitTime
+	}
+	if len(waitTimes) == 0 {
+		return aggregates
+	}
+
+	sort.Slice(waitTimes, func(i, j int) bool { return waitTimes[i] < waitTimes[j] })
+	aggregates.CompletedOrders = len(waitTimes)
+	aggregates.AverageWaitTime = (totalWaitTime / time.Duration(len(waitTimes))).Seconds()
+	aggregates.P95WaitTime = waitTimes[(len(waitTimes)*95-1)/100].Seconds()
+	aggregates.MaxWaitTime = waitTimes[len(waitTimes)-1].Seconds()
+
+	// the shop may have been open for less than the window
+	elapsed := m.lastEventTime.Sub(m.firstEventTime)
+	if elapsed > window {
+		elapsed = window
+	}
+	if elapsed > 0 {
+		aggregates.Throughput = float64(len(waitTimes)) / elapsed.Minutes()
+	}
+	return aggregates
+}
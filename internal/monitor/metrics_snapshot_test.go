@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// completedEvent creates an order completed event at the given time
+func completedEvent(at time.Time, waitTime time.Duration) Event {
+	return Event{
+		Type:      OrderCompleted,
+		Timestamp: at,
+		Payload: &OrderCompletedPayload{Order: OrderSnapshot{
+			GrindTime:      time.Second,
+			BrewTime:       2 * time.Second,
+			WaitTime:       waitTime,
+			ProcessingTime: waitTime,
+		}},
+	}
+}
+
+func TestMetricsSnapshot(t *testing.T) {
+	metrics := NewMetrics()
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+
+	// One order per minute for 20 minutes, each waiting one second more than the previous one
+	metrics.ConsumeEvent(Event{Type: OrderReceived, Timestamp: start, Payload: &OrderReceivedPayload{}})
+	for i := 1; i <= 20; i++ {
+		metrics.ConsumeEvent(completedEvent(start.Add(time.Duration(i)*time.Minute), time.Duration(i)*time.Second))
+	}
+
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, start.Add(20*time.Minute), snapshot.Time)
+	assert.Equal(t, 1, snapshot.ReceivedOrders)
+	assert.Equal(t, 20, snapshot.CompletedOrders)
+	assert.Equal(t, 1.0, snapshot.AverageGrindTime)
+	assert.Equal(t, 10.5, snapshot.AverageWaitTime)
+	assert.Equal(t, 20, snapshot.WaitTime.Count)
+
+	require.Len(t, snapshot.Windows, 3)
+	// The last minute only holds the order completed at 20 minutes
+	assert.Equal(t, WindowAggregates{Window: 60, CompletedOrders: 1, Throughput: 1, AverageWaitTime: 20, P95WaitTime: 20, MaxWaitTime: 20}, snapshot.Windows[0])
+	// The last 5 minutes hold the orders completed at 16 to 20 minutes
+	assert.Equal(t, WindowAggregates{Window: 300, CompletedOrders: 5, Throughput: 1, AverageWaitTime: 18, P95WaitTime: 20, MaxWaitTime: 20}, snapshot.Windows[1])
+	// The last 15 minutes hold the orders completed at 6 to 20 minutes
+	assert.Equal(t, 15, snapshot.Windows[2].CompletedOrders)
+	assert.Equal(t, 1.0, snapshot.Windows[2].Throughput)
+	assert.Equal(t, 13.0, snapshot.Windows[2].AverageWaitTime)
+	assert.Equal(t, 20.0, snapshot.Windows[2].P95WaitTime)
+
+	// The completions out of the largest window are pruned
+	assert.Len(t, metrics.completions, 15)
+}
+
+func TestMetricsSnapshotIsACopy(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.IncrementDroppedEvents("exporter")
+
+	snapshot := metrics.Snapshot()
+	metrics.IncrementDroppedEvents("exporter")
+	metrics.IncrementReceivedOrders()
+
+	assert.Equal(t, 1, snapshot.DroppedEvents["exporter"])
+	assert.Equal(t, 0, snapshot.ReceivedOrders)
+}
+
+func TestMetricsSnapshotWhileConsuming(t *testing.T) {
+	metrics := NewMetrics()
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			metrics.ConsumeEvent(completedEvent(start.Add(time.Duration(i)*time.Second), time.Second))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		snapshot := metrics.Snapshot()
+		// every completed order has been fully applied
+		assert.Equal(t, snapshot.CompletedOrders, snapshot.WaitTime.Count)
+		assert.Equal(t, snapshot.CompletedOrders, snapshot.GrindTime.Count)
+	}
+	wg.Wait()
+}
@@ -67,6 +67,13 @@ func TestMetrics(t *testing.T) {
 	assert.Equal(t, 101*time.Second, metrics.totalBrewTime)
 	assert.Equal(t, 101*time.Second, metrics.totalWaitTime)
 
+	// Test the histograms
+	assert.Equal(t, 101, metrics.processTimes.Count())
+	assert.Equal(t, 101, metrics.grindTimes.Count())
+	assert.Equal(t, 101, metrics.brewTimes.Count())
+	assert.Equal(t, 101, metrics.waitTimes.Count())
+	assert.Equal(t, time.Second, metrics.waitTimes.Percentile(99))
+
 	// Test PrintSummary with completed orders
 	metrics.PrintSummary() // Just test that it does not panic
 
@@ -75,3 +82,23 @@ func TestMetrics(t *testing.T) {
 	metrics2.IncrementReceivedOrders()
 	metrics2.PrintSummary() // Just test that it does not panic
 }
+
+func TestMetricsPreparationTimes(t *testing.T) {
+	metrics := NewMetrics()
+	for _, order := range []OrderSnapshot{
+		{Coffee: "Latte", SteamTime: 2 * time.Second, CondimentTime: time.Second},
+		{Coffee: "Iced Latte", IceTime: time.Second, CondimentTime: 3 * time.Second},
+		{Coffee: "Espresso"},
+	} {
+		metrics.ConsumeEvent(Event{Type: OrderCompleted, Payload: &OrderCompletedPayload{Order: order}})
+	}
+
+	snapshot := metrics.Snapshot()
+	// only the orders that had the step are counted
+	assert.Equal(t, 1, snapshot.SteamTime.Count)
+	assert.Equal(t, 2.0, snapshot.SteamTime.Mean)
+	assert.Equal(t, 2, snapshot.CondimentTime.Count)
+	assert.Equal(t, 2.0, snapshot.CondimentTime.Mean)
+	assert.Equal(t, 1, snapshot.IceTime.Count)
+	assert.Equal(t, 3, snapshot.BrewTime.Count, "every order counts in the other stages")
+}
@@ -0,0 +1,44 @@
+package monitor
+
+import "fmt"
+
+// OverflowPolicy decides what happens to an event when the buffer of a subscriber is full
+type OverflowPolicy int
+
+const (
+	// Block waits until the subscriber has room for the event, no event is lost but the sender is slowed down
+	Block OverflowPolicy = iota
+	// DropNewest discards the event being sent
+	DropNewest
+	// DropOldest discards the oldest buffered event to make room for the event being sent
+	DropOldest
+)
+
+// overflowPolicyNames maps each overflow policy to the name used in the config file
+var overflowPolicyNames = map[OverflowPolicy]string{
+	Block:      "block",
+	DropNewest: "drop-newest",
+	DropOldest: "drop-oldest",
+}
+
+// String returns the name of the overflow policy
+func (op OverflowPolicy) String() string {
+	if name, ok := overflowPolicyNames[op]; ok {
+		return name
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(op))
+}
+
+// ParseOverflowPolicy returns the overflow policy with the given name
+// An empty name is the Block policy, so no event is lost unless a policy is configured
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	if name == "" {
+		return Block, nil
+	}
+	for policy, policyName := range overflowPolicyNames {
+		if policyName == name {
+			return policy, nil
+		}
+	}
+	return Block, fmt.Errorf("unknown overflow policy %q", name)
+}
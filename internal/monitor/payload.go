@@ -0,0 +1,753 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/s3ndd/coffeeshop/internal/types"
+	"github.com/shopspring/decimal"
+)
+
+// payloadFactories creates an empty payload for each event type, it is used to decode persisted events
+var payloadFactories = map[EventType]func() Payload{
+	OrderReceived:             func() Payload { return &OrderReceivedPayload{} },
+	OrderProcessed:            func() Payload { return &OrderProcessedPayload{} },
+	OrderCompleted:            func() Payload { return &OrderCompletedPayload{} },
+	CustomerArrived:           func() Payload { return &CustomerArrivedPayload{} },
+	CustomerGreeted:           func() Payload { return &CustomerGreetedPayload{} },
+	CustomerAssignedToCashier: func() Payload { return &CustomerAssignedToCashierPayload{} },
+	OrderQueued:               func() Payload { return &OrderQueuedPayload{} },
+	BaristaAssigned:           func() Payload { return &BaristaAssignedPayload{} },
+	GrinderAcquired:           func() Payload { return &GrinderAcquiredPayload{} },
+	GrindStarted:              func() Payload { return &GrindStartedPayload{} },
+	GrindFinished:             func() Payload { return &GrindFinishedPayload{} },
+	GrinderReleased:           func() Payload { return &GrinderReleasedPayload{} },
+	BrewerAcquired:            func() Payload { return &BrewerAcquiredPayload{} },
+	BrewStarted:               func() Payload { return &BrewStartedPayload{} },
+	BrewFinished:              func() Payload { return &BrewFinishedPayload{} },
+	BrewerReleased:            func() Payload { return &BrewerReleasedPayload{} },
+	OrderPickedUp:             func() Payload { return &OrderPickedUpPayload{} },
+	OrderRejected:             func() Payload { return &OrderRejectedPayload{} },
+	IngredientSubstituted:     func() Payload { return &IngredientSubstitutedPayload{} },
+	IngredientConsumed:        func() Payload { return &IngredientConsumedPayload{} },
+	StockOut:                  func() Payload { return &StockOutPayload{} },
+	ReorderPlaced:             func() Payload { return &ReorderPlacedPayload{} },
+	DeliveryReceived:          func() Payload { return &DeliveryReceivedPayload{} },
+	SteamerAcquired:           func() Payload { return &SteamerAcquiredPayload{} },
+	SteamStarted:              func() Payload { return &SteamStartedPayload{} },
+	SteamFinished:             func() Payload { return &SteamFinishedPayload{} },
+	SteamerReleased:           func() Payload { return &SteamerReleasedPayload{} },
+	CondimentsAdded:           func() Payload { return &CondimentsAddedPayload{} },
+	IceDispenserAcquired:      func() Payload { return &IceDispenserAcquiredPayload{} },
+	IceDispensed:              func() Payload { return &IceDispensedPayload{} },
+	IceDispenserReleased:      func() Payload { return &IceDispenserReleasedPayload{} },
+	ColdBrewDrawn:             func() Payload { return &ColdBrewDrawnPayload{} },
+	ColdBrewReplenished:       func() Payload { return &ColdBrewReplenishedPayload{} },
+	StepPerformed:             func() Payload { return &StepPerformedPayload{} },
+	EquipmentTimedOut:         func() Payload { return &EquipmentTimedOutPayload{} },
+	EquipmentOutOfService:     func() Payload { return &EquipmentOutOfServicePayload{} },
+	EquipmentBackInService:    func() Payload { return &EquipmentBackInServicePayload{} },
+	StepAborted:               func() Payload { return &StepAbortedPayload{} },
+	EquipmentCleaned:          func() Payload { return &EquipmentCleanedPayload{} },
+	ResourcesRegistered:       func() Payload { return &ResourcesRegisteredPayload{} },
+	EquipmentAcquired:         func() Payload { return &EquipmentAcquiredPayload{} },
+	OrderCancelled:            func() Payload { return &OrderCancelledPayload{} },
+}
+
+// CustomerRef identifies the customer an event belongs to
+// The name is kept for readability, the ID is what correlates the events of a customer
+// TraceID is the trace of the customer's journey, it correlates the events with the log lines
+type CustomerRef struct {
+	CustomerID int64  `json:"customerId"`
+	Customer   string `json:"customer"`
+	TraceID    string `json:"traceId,omitempty"`
+}
+
+// NewCustomerRef creates the reference of a customer
+func NewCustomerRef(customer *types.Customer) CustomerRef {
+	ref := CustomerRef{
+		CustomerID: customer.ID(),
+		Customer:   customer.Name(),
+	}
+	if spanContext := customer.SpanContext(); spanContext.IsValid() {
+		ref.TraceID = spanContext.TraceID.String()
+	}
+	return ref
+}
+
+// OrderRef identifies the order an event belongs to
+type OrderRef struct {
+	OrderID int64 `json:"orderId"`
+	CustomerRef
+}
+
+// OrderPayload is implemented by the payloads of the events that belong to an order
+// The payloads embedding an OrderRef implement it through it, those carrying an OrderSnapshot return the ID of its order
+type OrderPayload interface {
+	BelongsToOrder() (int64, bool)
+}
+
+// BelongsToOrder returns the ID of the order
+func (r OrderRef) BelongsToOrder() (int64, bool) {
+	return r.OrderID, true
+}
+
+// NewOrderRef creates the reference of an order
+func NewOrderRef(order *types.Order) OrderRef {
+	return OrderRef{
+		OrderID:     order.ID(),
+		CustomerRef: NewCustomerRef(order.Customer()),
+	}
+}
+
+// OrderSnapshot is a copy of the state of an order at the time the event was sent
+// The timings are only known once the order is completed, so they are zero before that
+type OrderSnapshot struct {
+	OrderRef
+	Coffee         string           `json:"coffee"`
+	Size           types.CoffeeSize `json:"size"`
+	Extras         []string         `json:"extras"`
+	Price          string           `json:"price"`
+	OrderTime      time.Time        `json:"orderTime"`
+	ServedTime     *time.Time       `json:"servedTime,omitempty"`
+	GrindTime      time.Duration    `json:"grindTime"`
+	BrewTime       time.Duration    `json:"brewTime"`
+	SteamTime      time.Duration    `json:"steamTime,omitempty"`
+	CondimentTime  time.Duration    `json:"condimentTime,omitempty"`
+	IceTime        time.Duration    `json:"iceTime,omitempty"`
+	WaitTime       time.Duration    `json:"waitTime"`
+	ProcessingTime time.Duration    `json:"processingTime"`
+}
+
+// NewOrderSnapshot copies the state of an order
+// The copy is taken by the sender, so subscribers never read an order that is still being processed
+func NewOrderSnapshot(order *types.Order) OrderSnapshot {
+	coffee := order.Coffee()
+	snapshot := OrderSnapshot{
+		OrderRef:  NewOrderRef(order),
+		Coffee:    coffee.CoffeeType().Name,
+		Size:      coffee.Size(),
+		Extras:    append([]string{}, coffee.Extras()...),
+		Price:     order.Price().String(),
+		OrderTime: order.OrderTime(),
+	}
+	if servedTime := order.ServedTime(); servedTime != nil {
+		served := *servedTime
+		snapshot.ServedTime = &served
+		snapshot.GrindTime = coffee.GrindTime()
+		snapshot.BrewTime = coffee.BrewTime()
+		snapshot.SteamTime = coffee.SteamTime()
+		snapshot.CondimentTime = coffee.CondimentTime()
+		snapshot.IceTime = coffee.IceTime()
+		snapshot.WaitTime = order.Customer().WaitTime()
+		snapshot.ProcessingTime = order.ProcessingTime()
+	}
+	return snapshot
+}
+
+// OrderReceivedPayload is sent when a cashier publishes an order to the order queue
+type OrderReceivedPayload struct {
+	Cashier int           `json:"cashier"`
+	Order   OrderSnapshot `json:"order"`
+}
+
+// EventType returns OrderReceived
+func (p *OrderReceivedPayload) EventType() EventType {
+	return OrderReceived
+}
+
+// BelongsToOrder returns the ID of the order of the snapshot
+func (p *OrderReceivedPayload) BelongsToOrder() (int64, bool) {
+	return p.Order.BelongsToOrder()
+}
+
+// OrderProcessedPayload is sent when a barista starts processing an order
+type OrderProcessedPayload struct {
+	Barista int           `json:"barista"`
+	Order   OrderSnapshot `json:"order"`
+}
+
+// EventType returns OrderProcessed
+func (p *OrderProcessedPayload) EventType() EventType {
+	return OrderProcessed
+}
+
+// BelongsToOrder returns the ID of the order of the snapshot
+func (p *OrderProcessedPayload) BelongsToOrder() (int64, bool) {
+	return p.Order.BelongsToOrder()
+}
+
+// OrderCompletedPayload is sent when a barista hands the coffee to the customer
+type OrderCompletedPayload struct {
+	Barista int           `json:"barista"`
+	Order   OrderSnapshot `json:"order"`
+}
+
+// EventType returns OrderCompleted
+func (p *OrderCompletedPayload) EventType() EventType {
+	return OrderCompleted
+}
+
+// BelongsToOrder returns the ID of the order of the snapshot
+func (p *OrderCompletedPayload) BelongsToOrder() (int64, bool) {
+	return p.Order.BelongsToOrder()
+}
+
+// CustomerArrivedPayload is sent when a customer enters the coffee shop
+type CustomerArrivedPayload struct {
+	CustomerRef
+}
+
+// EventType returns CustomerArrived
+func (p *CustomerArrivedPayload) EventType() EventType {
+	return CustomerArrived
+}
+
+// CustomerGreetedPayload is sent when a greeter starts greeting a customer
+type CustomerGreetedPayload struct {
+	CustomerRef
+	Greeter int `json:"greeter"`
+}
+
+// EventType returns CustomerGreeted
+func (p *CustomerGreetedPayload) EventType() EventType {
+	return CustomerGreeted
+}
+
+// CustomerAssignedToCashierPayload is sent when a greeter puts a customer in the queue of a cashier
+// QueueSize is the length of the customer queue of the cashier including this customer
+type CustomerAssignedToCashierPayload struct {
+	CustomerRef
+	Greeter   int `json:"greeter"`
+	Cashier   int `json:"cashier"`
+	QueueSize int `json:"queueSize"`
+}
+
+// EventType returns CustomerAssignedToCashier
+func (p *CustomerAssignedToCashierPayload) EventType() EventType {
+	return CustomerAssignedToCashier
+}
+
+// OrderQueuedPayload is sent when a cashier publishes an order to the order queue, right before the order is published
+// QueueSize is the length the order queue is expected to have with this order
+type OrderQueuedPayload struct {
+	Cashier   int           `json:"cashier"`
+	QueueSize int           `json:"queueSize"`
+	Order     OrderSnapshot `json:"order"`
+}
+
+// EventType returns OrderQueued
+func (p *OrderQueuedPayload) EventType() EventType {
+	return OrderQueued
+}
+
+// BelongsToOrder returns the ID of the order of the snapshot
+func (p *OrderQueuedPayload) BelongsToOrder() (int64, bool) {
+	return p.Order.BelongsToOrder()
+}
+
+// BaristaAssignedPayload is sent when a barista picks an order from the order queue
+type BaristaAssignedPayload struct {
+	Barista int           `json:"barista"`
+	Order   OrderSnapshot `json:"order"`
+}
+
+// EventType returns BaristaAssigned
+func (p *BaristaAssignedPayload) EventType() EventType {
+	return BaristaAssigned
+}
+
+// BelongsToOrder returns the ID of the order of the snapshot
+func (p *BaristaAssignedPayload) BelongsToOrder() (int64, bool) {
+	return p.Order.BelongsToOrder()
+}
+
+// GrinderAcquiredPayload is sent when a barista gets a grinder from the grinder pool
+// Bean is the beans of the coffee, only a grinder that handles them could be acquired
+// WaitTime is how long the barista waited for a grinder to be available
+type GrinderAcquiredPayload struct {
+	OrderRef
+	Barista  int           `json:"barista"`
+	Grinder  string        `json:"grinder"`
+	Bean     string        `json:"bean,omitempty"`
+	WaitTime time.Duration `json:"waitTime"`
+}
+
+// EventType returns GrinderAcquired
+func (p *GrinderAcquiredPayload) EventType() EventType {
+	return GrinderAcquired
+}
+
+// GrindStartedPayload is sent when a grinder starts grinding the beans of an order
+type GrindStartedPayload struct {
+	OrderRef
+	Barista int             `json:"barista"`
+	Grinder string          `json:"grinder"`
+	Beans   decimal.Decimal `json:"beans"`
+}
+
+// EventType returns GrindStarted
+func (p *GrindStartedPayload) EventType() EventType {
+	return GrindStarted
+}
+
+// GrindFinishedPayload is sent when the beans of an order are ground
+type GrindFinishedPayload struct {
+	OrderRef
+	Barista   int           `json:"barista"`
+	Grinder   string        `json:"grinder"`
+	GrindTime time.Duration `json:"grindTime"`
+}
+
+// EventType returns GrindFinished
+func (p *GrindFinishedPayload) EventType() EventType {
+	return GrindFinished
+}
+
+// GrinderReleasedPayload is sent when a barista returns a grinder to the grinder pool
+type GrinderReleasedPayload struct {
+	OrderRef
+	Barista int    `json:"barista"`
+	Grinder string `json:"grinder"`
+}
+
+// EventType returns GrinderReleased
+func (p *GrinderReleasedPayload) EventType() EventType {
+	return GrinderReleased
+}
+
+// BrewerAcquiredPayload is sent when a barista gets a brewer from the brewer pool
+// WaitTime is how long the barista waited for a brewer to be available
+type BrewerAcquiredPayload struct {
+	OrderRef
+	Barista  int           `json:"barista"`
+	Brewer   string        `json:"brewer"`
+	WaitTime time.Duration `json:"waitTime"`
+}
+
+// EventType returns BrewerAcquired
+func (p *BrewerAcquiredPayload) EventType() EventType {
+	return BrewerAcquired
+}
+
+// BrewStartedPayload is sent when a brewer starts brewing an order
+type BrewStartedPayload struct {
+	OrderRef
+	Barista int             `json:"barista"`
+	Brewer  string          `json:"brewer"`
+	Water   decimal.Decimal `json:"water"`
+}
+
+// EventType returns BrewStarted
+func (p *BrewStartedPayload) EventType() EventType {
+	return BrewStarted
+}
+
+// BrewFinishedPayload is sent when the coffee of an order is brewed
+type BrewFinishedPayload struct {
+	OrderRef
+	Barista  int           `json:"barista"`
+	Brewer   string        `json:"brewer"`
+	BrewTime time.Duration `json:"brewTime"`
+}
+
+// EventType returns BrewFinished
+func (p *BrewFinishedPayload) EventType() EventType {
+	return BrewFinished
+}
+
+// BrewerReleasedPayload is sent when a barista returns a brewer to the brewer pool
+type BrewerReleasedPayload struct {
+	OrderRef
+	Barista int    `json:"barista"`
+	Brewer  string `json:"brewer"`
+}
+
+// EventType returns BrewerReleased
+func (p *BrewerReleasedPayload) EventType() EventType {
+	return BrewerReleased
+}
+
+// SteamerAcquiredPayload is sent when a barista gets a steamer from the steamer pool
+// WaitTime is how long the barista waited for a steamer to be available
+type SteamerAcquiredPayload struct {
+	OrderRef
+	Barista  int           `json:"barista"`
+	Steamer  string        `json:"steamer"`
+	WaitTime time.Duration `json:"waitTime"`
+}
+
+// EventType returns SteamerAcquired
+func (p *SteamerAcquiredPayload) EventType() EventType {
+	return SteamerAcquired
+}
+
+// SteamStartedPayload is sent when a steamer starts steaming the milk of an order
+type SteamStartedPayload struct {
+	OrderRef
+	Barista int             `json:"barista"`
+	Steamer string          `json:"steamer"`
+	Milk    decimal.Decimal `json:"milk"`
+}
+
+// EventType returns SteamStarted
+func (p *SteamStartedPayload) EventType() EventType {
+	return SteamStarted
+}
+
+// SteamFinishedPayload is sent when the milk of an order is steamed
+type SteamFinishedPayload struct {
+	OrderRef
+	Barista   int           `json:"barista"`
+	Steamer   string        `json:"steamer"`
+	SteamTime time.Duration `json:"steamTime"`
+}
+
+// EventType returns SteamFinished
+func (p *SteamFinishedPayload) EventType() EventType {
+	return SteamFinished
+}
+
+// SteamerReleasedPayload is sent when a barista returns a steamer to the steamer pool
+type SteamerReleasedPayload struct {
+	OrderRef
+	Barista int    `json:"barista"`
+	Steamer string `json:"steamer"`
+}
+
+// EventType returns SteamerReleased
+func (p *SteamerReleasedPayload) EventType() EventType {
+	return SteamerReleased
+}
+
+// CondimentsAddedPayload is sent when a barista has added the condiments of an order at the condiment station
+type CondimentsAddedPayload struct {
+	OrderRef
+	Barista       int           `json:"barista"`
+	Condiments    []string      `json:"condiments"`
+	CondimentTime time.Duration `json:"condimentTime"`
+}
+
+// EventType returns CondimentsAdded
+func (p *CondimentsAddedPayload) EventType() EventType {
+	return CondimentsAdded
+}
+
+// IceDispenserAcquiredPayload is sent when a barista gets an ice dispenser from the ice dispenser pool
+// WaitTime is how long the barista waited for an ice dispenser to be available
+type IceDispenserAcquiredPayload struct {
+	OrderRef
+	Barista      int           `json:"barista"`
+	IceDispenser string        `json:"iceDispenser"`
+	WaitTime     time.Duration `json:"waitTime"`
+}
+
+// EventType returns IceDispenserAcquired
+func (p *IceDispenserAcquiredPayload) EventType() EventType {
+	return IceDispenserAcquired
+}
+
+// IceDispensedPayload is sent when the ice of an iced order is dispensed
+type IceDispensedPayload struct {
+	OrderRef
+	Barista      int             `json:"barista"`
+	IceDispenser string          `json:"iceDispenser"`
+	Ice          decimal.Decimal `json:"ice"`
+	IceTime      time.Duration   `json:"iceTime"`
+}
+
+// EventType returns IceDispensed
+func (p *IceDispensedPayload) EventType() EventType {
+	return IceDispensed
+}
+
+// IceDispenserReleasedPayload is sent when a barista returns an ice dispenser to the ice dispenser pool
+type IceDispenserReleasedPayload struct {
+	OrderRef
+	Barista      int    `json:"barista"`
+	IceDispenser string `json:"iceDispenser"`
+}
+
+// EventType returns IceDispenserReleased
+func (p *IceDispenserReleasedPayload) EventType() EventType {
+	return IceDispenserReleased
+}
+
+// ColdBrewDrawnPayload is sent when a barista draws the cold brew of an order from the cold brew stock
+// Stock is the cold brew left, WaitTime is how long the order waited for the cold brew,
+// including the batch prepared for it when the stock was short
+type ColdBrewDrawnPayload struct {
+	OrderRef
+	Barista  int             `json:"barista"`
+	Ounces   decimal.Decimal `json:"ounces"`
+	Stock    decimal.Decimal `json:"stock"`
+	WaitTime time.Duration   `json:"waitTime"`
+}
+
+// EventType returns ColdBrewDrawn
+func (p *ColdBrewDrawnPayload) EventType() EventType {
+	return ColdBrewDrawn
+}
+
+// ColdBrewReplenishedPayload is sent when a barista adds a batch of cold brew to the cold brew stock
+// Stock is the cold brew in stock once the batch is added
+type ColdBrewReplenishedPayload struct {
+	Barista     int             `json:"barista"`
+	Batch       decimal.Decimal `json:"batch"`
+	Stock       decimal.Decimal `json:"stock"`
+	PrepareTime time.Duration   `json:"prepareTime"`
+}
+
+// EventType returns ColdBrewReplenished
+func (p *ColdBrewReplenishedPayload) EventType() EventType {
+	return ColdBrewReplenished
+}
+
+// StepPerformedPayload is sent when a barista has performed a step of the recipe of an order
+// by hand, or on equipment that does not report its use with its own events
+// Equipment is the tag of the unit the step was performed on, empty for a step by hand
+type StepPerformedPayload struct {
+	OrderRef
+	Barista   int           `json:"barista"`
+	Action    string        `json:"action"`
+	Equipment string        `json:"equipment,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// EventType returns StepPerformed
+func (p *StepPerformedPayload) EventType() EventType {
+	return StepPerformed
+}
+
+// EquipmentTimedOutPayload is sent when a barista has waited for the equipment of steps of an order longer than the timeout
+// Equipment are the classes of equipment that had no unit for the order, like steamer when a brewer and a steamer are reserved together,
+// WaitTime is the time waited so far
+type EquipmentTimedOutPayload struct {
+	OrderRef
+	Barista   int           `json:"barista"`
+	Equipment []string      `json:"equipment"`
+	WaitTime  time.Duration `json:"waitTime"`
+}
+
+// EventType returns EquipmentTimedOut
+func (p *EquipmentTimedOutPayload) EventType() EventType {
+	return EquipmentTimedOut
+}
+
+// The reasons a unit of equipment is out of service
+const (
+	// BreakdownOutage is a unit broken down until it is repaired
+	BreakdownOutage = "breakdown"
+	// MaintenanceOutage is a unit taken out for a scheduled maintenance
+	MaintenanceOutage = "maintenance"
+	// CleaningOutage is a unit taken out to be cleaned by a barista
+	CleaningOutage = "cleaning"
+)
+
+// EquipmentOutOfServicePayload is sent when a unit of equipment is taken out of its pool
+// Kind is the class of the unit, like grinder, Reason is breakdown, maintenance or cleaning and Duration the time it is expected to be out
+// Aborted is whether the breakdown threw away the coffee the unit was processing
+type EquipmentOutOfServicePayload struct {
+	Kind      string        `json:"kind"`
+	Equipment string        `json:"equipment"`
+	Reason    string        `json:"reason"`
+	Duration  time.Duration `json:"duration"`
+	Aborted   bool          `json:"aborted,omitempty"`
+}
+
+// EventType returns EquipmentOutOfService
+func (p *EquipmentOutOfServicePayload) EventType() EventType {
+	return EquipmentOutOfService
+}
+
+// EquipmentBackInServicePayload is sent when a unit of equipment is back in its pool after a breakdown, a maintenance or a cleaning
+// Downtime is the time it was out of service
+type EquipmentBackInServicePayload struct {
+	Kind      string        `json:"kind"`
+	Equipment string        `json:"equipment"`
+	Reason    string        `json:"reason"`
+	Downtime  time.Duration `json:"downtime"`
+}
+
+// EventType returns EquipmentBackInService
+func (p *EquipmentBackInServicePayload) EventType() EventType {
+	return EquipmentBackInService
+}
+
+// StepAbortedPayload is sent when the equipment of a step of an order broke down and threw the coffee away
+// Equipment is the tag of the unit that broke down, the barista starts over from the first step whose output was lost, on other units
+type StepAbortedPayload struct {
+	OrderRef
+	Barista   int    `json:"barista"`
+	Action    string `json:"action"`
+	Equipment string `json:"equipment"`
+}
+
+// EventType returns StepAborted
+func (p *StepAbortedPayload) EventType() EventType {
+	return StepAborted
+}
+
+// EquipmentCleanedPayload is sent when a barista has cleaned a unit of equipment
+// Uses is the number of uses since its previous cleaning and Rate the rate it had slowed down to, in its own unit per second,
+// like grams of beans for a grinder, it is back to its full rate
+type EquipmentCleanedPayload struct {
+	Kind         string        `json:"kind"`
+	Equipment    string        `json:"equipment"`
+	Barista      int           `json:"barista"`
+	Uses         int           `json:"uses"`
+	Rate         float64       `json:"rate"`
+	CleaningTime time.Duration `json:"cleaningTime"`
+}
+
+// EventType returns EquipmentCleaned
+func (p *EquipmentCleanedPayload) EventType() EventType {
+	return EquipmentCleaned
+}
+
+// ResourcesRegisteredPayload is sent when the shop opens, once per kind of resource, with the names of its resources
+// like the IDs of the baristas or the tags of the grinders
+type ResourcesRegisteredPayload struct {
+	Kind  ResourceKind `json:"kind"`
+	Names []string     `json:"names"`
+}
+
+// EventType returns ResourcesRegistered
+func (p *ResourcesRegisteredPayload) EventType() EventType {
+	return ResourcesRegistered
+}
+
+// EquipmentAcquiredPayload is sent when a barista gets a unit of equipment for a step of an order
+// Kind is the class of the equipment, like brewer, and WaitTime is the time the barista waited for it
+type EquipmentAcquiredPayload struct {
+	OrderRef
+	Barista   int           `json:"barista"`
+	Kind      ResourceKind  `json:"kind"`
+	Equipment string        `json:"equipment"`
+	WaitTime  time.Duration `json:"waitTime"`
+}
+
+// EventType returns EquipmentAcquired
+func (p *EquipmentAcquiredPayload) EventType() EventType {
+	return EquipmentAcquired
+}
+
+// OrderPickedUpPayload is sent when the customer picks up the coffee and leaves
+type OrderPickedUpPayload struct {
+	Barista int           `json:"barista"`
+	Order   OrderSnapshot `json:"order"`
+}
+
+// EventType returns OrderPickedUp
+func (p *OrderPickedUpPayload) EventType() EventType {
+	return OrderPickedUp
+}
+
+// BelongsToOrder returns the ID of the order of the snapshot
+func (p *OrderPickedUpPayload) BelongsToOrder() (int64, bool) {
+	return p.Order.BelongsToOrder()
+}
+
+// OrderRejectedPayload is sent when a cashier turns down an order because an item is out of stock
+// Item is the item missing, the customer leaves without a coffee
+type OrderRejectedPayload struct {
+	Cashier int           `json:"cashier"`
+	Item    string        `json:"item"`
+	Order   OrderSnapshot `json:"order"`
+}
+
+// EventType returns OrderRejected
+func (p *OrderRejectedPayload) EventType() EventType {
+	return OrderRejected
+}
+
+// BelongsToOrder returns the ID of the order of the snapshot
+func (p *OrderRejectedPayload) BelongsToOrder() (int64, bool) {
+	return p.Order.BelongsToOrder()
+}
+
+// IngredientSubstitutedPayload is sent when an extra out of stock is replaced by its substitute
+type IngredientSubstitutedPayload struct {
+	OrderRef
+	Item       string `json:"item"`
+	Substitute string `json:"substitute"`
+}
+
+// EventType returns IngredientSubstituted
+func (p *IngredientSubstitutedPayload) EventType() EventType {
+	return IngredientSubstituted
+}
+
+// IngredientConsumedPayload is sent when an ingredient or supply is used for an order
+// Amount is what went into the order, Waste what was lost doing so, and Stock what is left
+type IngredientConsumedPayload struct {
+	OrderRef
+	Item   string          `json:"item"`
+	Amount decimal.Decimal `json:"amount"`
+	Waste  decimal.Decimal `json:"waste"`
+	Stock  decimal.Decimal `json:"stock"`
+}
+
+// EventType returns IngredientConsumed
+func (p *IngredientConsumedPayload) EventType() EventType {
+	return IngredientConsumed
+}
+
+// StockOutPayload is sent when an item runs out of stock, the item is out of stock until it is restocked
+// Stock is what is left, not enough for an order
+type StockOutPayload struct {
+	Item  string          `json:"item"`
+	Stock decimal.Decimal `json:"stock"`
+}
+
+// EventType returns StockOut
+func (p *StockOutPayload) EventType() EventType {
+	return StockOut
+}
+
+// ReorderPlacedPayload is sent when a purchase order of an item is placed with the supplier
+// Stock is the stock left that is not reserved when the order is placed
+type ReorderPlacedPayload struct {
+	PurchaseOrderID int64           `json:"purchaseOrderId"`
+	Item            string          `json:"item"`
+	Quantity        decimal.Decimal `json:"quantity"`
+	Stock           decimal.Decimal `json:"stock"`
+}
+
+// EventType returns ReorderPlaced
+func (p *ReorderPlacedPayload) EventType() EventType {
+	return ReorderPlaced
+}
+
+// DeliveryReceivedPayload is sent when a delivery of the supplier is received
+// Delivered is less than Ordered for a partial delivery, Stock is the stock after the delivery
+type DeliveryReceivedPayload struct {
+	PurchaseOrderID int64           `json:"purchaseOrderId"`
+	Item            string          `json:"item"`
+	Ordered         decimal.Decimal `json:"ordered"`
+	Delivered       decimal.Decimal `json:"delivered"`
+	Stock           decimal.Decimal `json:"stock"`
+	LeadTime        time.Duration   `json:"leadTime"`
+}
+
+// EventType returns DeliveryReceived
+func (p *DeliveryReceivedPayload) EventType() EventType {
+	return DeliveryReceived
+}
+
+// OrderCancelledPayload is sent when a barista gives up an order it cannot prepare, like when its equipment cannot be reserved
+// Reason is why, the customer leaves without a coffee
+type OrderCancelledPayload struct {
+	Barista int           `json:"barista"`
+	Reason  string        `json:"reason"`
+	Order   OrderSnapshot `json:"order"`
+}
+
+// EventType returns OrderCancelled
+func (p *OrderCancelledPayload) EventType() EventType {
+	return OrderCancelled
+}
+
+// BelongsToOrder returns the ID of the order of the snapshot
+func (p *OrderCancelledPayload) BelongsToOrder() (int64, bool) {
+	return p.Order.BelongsToOrder()
+}
@@ -0,0 +1,178 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// prometheusContentType is the content type of the Prometheus text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// ShopStater reports the live state of the coffee shop
+// It is implemented by coffeeshop.CoffeeShop, the values are read every time the metrics are scraped
+type ShopStater interface {
+	CashierQueueSizes() map[int]int
+	OrderQueueSize() int
+	BusyBaristas() int
+	BusyGrinders() int
+	BusyBrewers() int
+	BusySteamers() int
+	BusyIceDispensers() int
+	AvailableGrinders() int
+	AvailableBrewers() int
+	AvailableSteamers() int
+	AvailableIceDispensers() int
+}
+
+// PrometheusHandler serves the metrics and the state of the shop in the Prometheus text exposition format
+// shop may be nil, for example when serving the metrics of a replayed event log
+func PrometheusHandler(metrics *Metrics, shop ShopStater) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", prometheusContentType)
+		writer := bufio.NewWriter(w)
+		metrics.WritePrometheus(writer)
+		if shop != nil {
+			writeShopState(writer, shop)
+		}
+		writer.Flush()
+	})
+}
+
+// WritePrometheus writes the counters and histograms in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) {
+	m.metricsMutex.Lock()
+	defer m.metricsMutex.Unlock()
+
+	writeCounter(w, "coffeeshop_orders_received_total", "Number of orders received by the cashiers.", m.receivedOrders)
+	writeCounter(w, "coffeeshop_orders_processed_total", "Number of orders picked up by the baristas.", m.processedOrders)
+	writeCounter(w, "coffeeshop_orders_completed_total", "Number of orders completed by the baristas.", m.completedOrders)
+
+	writeHelp(w, "coffeeshop_events_dropped_total", "counter", "Number of monitoring events dropped, by subscriber.")
+	subscribers := make([]string, 0, len(m.droppedEvents))
+	for subscriber := range m.droppedEvents {
+		subscribers = append(subscribers, subscriber)
+	}
+	sort.Strings(subscribers)
+	for _, subscriber := range subscribers {
+		fmt.Fprintf(w, "coffeeshop_events_dropped_total{subscriber=%q} %d\n", subscriber, m.droppedEvents[subscriber])
+	}
+
+	writeHistogram(w, "coffeeshop_wait_time_seconds", "Time from the arrival of a customer to the pick up of the coffee.", m.waitTimes)
+	writeHistogram(w, "coffeeshop_process_time_seconds", "Time from the order to the pick up of the coffee.", m.processTimes)
+	writeHistogram(w, "coffeeshop_grind_time_seconds", "Time spent grinding the beans of an order.", m.grindTimes)
+	writeHistogram(w, "coffeeshop_brew_time_seconds", "Time spent brewing an order.", m.brewTimes)
+	writeHistogram(w, "coffeeshop_steam_time_seconds", "Time spent steaming the milk of an order, for the orders with steamed milk.", m.steamTimes)
+	writeHistogram(w, "coffeeshop_condiment_time_seconds", "Time spent adding the condiments of an order, for the orders with condiments.", m.condimentTimes)
+	writeHistogram(w, "coffeeshop_ice_time_seconds", "Time spent dispensing the ice of an order, for the iced orders.", m.iceTimes)
+
+	kinds := make([]string, 0, len(m.equipment))
+	for kind := range m.equipment {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	writeHelp(w, "coffeeshop_equipment_wait_seconds", "histogram", "Time the baristas were blocked waiting for equipment, by kind.")
+	for _, kind := range kinds {
+		writeHistogramSeries(w, "coffeeshop_equipment_wait_seconds", fmt.Sprintf("kind=%q", kind), m.equipment[ResourceKind(kind)].waits)
+	}
+	writeHelp(w, "coffeeshop_equipment_timeouts_total", "counter", "Number of times a barista waited for equipment longer than the timeout, by kind.")
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "coffeeshop_equipment_timeouts_total{kind=%q} %d\n", kind, m.equipment[ResourceKind(kind)].timeouts)
+	}
+	m.writeOutages(w)
+}
+
+// writeOutages writes the counters of the breakdowns, maintenances and cleanings and the gauge of the equipment out of service, by kind
+func (m *Metrics) writeOutages(w io.Writer) {
+	kinds := make([]string, 0, len(m.outages))
+	for kind := range m.outages {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	breakdowns := make(map[string]int, len(kinds))
+	maintenances := make(map[string]int, len(kinds))
+	cleanings := make(map[string]int, len(kinds))
+	out := make(map[string]int, len(kinds))
+	for _, kind := range kinds {
+		for _, usage := range m.outages[ResourceKind(kind)] {
+			breakdowns[kind] += usage.breakdowns
+			maintenances[kind] += usage.maintenances
+			cleanings[kind] += usage.cleanings
+			if usage.outages > 0 {
+				out[kind]++
+			}
+		}
+	}
+	writeHelp(w, "coffeeshop_equipment_outages_total", "counter", "Number of times equipment was taken out of service, by kind and reason.")
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "coffeeshop_equipment_outages_total{kind=%q,reason=%q} %d\n", kind, BreakdownOutage, breakdowns[kind])
+		fmt.Fprintf(w, "coffeeshop_equipment_outages_total{kind=%q,reason=%q} %d\n", kind, MaintenanceOutage, maintenances[kind])
+		fmt.Fprintf(w, "coffeeshop_equipment_outages_total{kind=%q,reason=%q} %d\n", kind, CleaningOutage, cleanings[kind])
+	}
+	writeHelp(w, "coffeeshop_equipment_out_of_service", "gauge", "Number of units of equipment out of service, by kind.")
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "coffeeshop_equipment_out_of_service{kind=%q} %d\n", kind, out[kind])
+	}
+}
+
+// writeShopState writes the gauges of the live state of the shop
+func writeShopState(w io.Writer, shop ShopStater) {
+	writeHelp(w, "coffeeshop_cashier_queue_length", "gauge", "Number of customers waiting in line, by cashier.")
+	queueSizes := shop.CashierQueueSizes()
+	cashiers := make([]int, 0, len(queueSizes))
+	for cashier := range queueSizes {
+		cashiers = append(cashiers, cashier)
+	}
+	sort.Ints(cashiers)
+	for _, cashier := range cashiers {
+		fmt.Fprintf(w, "coffeeshop_cashier_queue_length{cashier=\"%d\"} %d\n", cashier, queueSizes[cashier])
+	}
+
+	writeGauge(w, "coffeeshop_order_queue_depth", "Number of orders waiting for a barista.", shop.OrderQueueSize())
+	writeGauge(w, "coffeeshop_baristas_busy", "Number of baristas processing an order.", shop.BusyBaristas())
+	writeGauge(w, "coffeeshop_grinders_busy", "Number of grinders in use.", shop.BusyGrinders())
+	writeGauge(w, "coffeeshop_brewers_busy", "Number of brewers in use.", shop.BusyBrewers())
+	writeGauge(w, "coffeeshop_steamers_busy", "Number of steamers in use.", shop.BusySteamers())
+	writeGauge(w, "coffeeshop_ice_dispensers_busy", "Number of ice dispensers in use.", shop.BusyIceDispensers())
+}
+
+// writeHelp writes the HELP and TYPE lines of a metric
+func writeHelp(w io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// writeCounter writes a counter without labels
+func writeCounter(w io.Writer, name string, help string, value int) {
+	writeHelp(w, name, "counter", help)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+// writeGauge writes a gauge without labels
+func writeGauge(w io.Writer, name string, help string, value int) {
+	writeHelp(w, name, "gauge", help)
+	fmt.Fprintf(w, "%s %d\n", name, value)
+}
+
+// writeHistogram writes the cumulative buckets, the sum and the count of a histogram without labels
+func writeHistogram(w io.Writer, name string, help string, histogram *Histogram) {
+	writeHelp(w, name, "histogram", help)
+	writeHistogramSeries(w, name, "", histogram)
+}
+
+// writeHistogramSeries writes the cumulative buckets, the sum and the count of a histogram with the given labels, like kind="grinder"
+func writeHistogramSeries(w io.Writer, name string, labels string, histogram *Histogram) {
+	bucketLabels, seriesLabels := "", ""
+	if labels != "" {
+		bucketLabels, seriesLabels = labels+",", "{"+labels+"}"
+	}
+	cumulative := histogram.CumulativeCounts()
+	for i, bound := range histogram.Bounds() {
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, bucketLabels, strconv.FormatFloat(bound.Seconds(), 'g', -1, 64), cumulative[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, bucketLabels, histogram.Count())
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, seriesLabels, strconv.FormatFloat(histogram.Sum(), 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, seriesLabels, histogram.Count())
+}
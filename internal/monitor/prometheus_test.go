@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeShop is a fixed state of the coffee shop
+type fakeShop struct{}
+
+func (fakeShop) CashierQueueSizes() map[int]int { return map[int]int{1: 4, 0: 2} }
+func (fakeShop) OrderQueueSize() int            { return 3 }
+func (fakeShop) BusyBaristas() int              { return 5 }
+func (fakeShop) BusyGrinders() int              { return 2 }
+func (fakeShop) BusyBrewers() int               { return 1 }
+func (fakeShop) BusySteamers() int              { return 1 }
+func (fakeShop) BusyIceDispensers() int         { return 0 }
+func (fakeShop) AvailableGrinders() int         { return 1 }
+func (fakeShop) AvailableBrewers() int          { return 2 }
+func (fakeShop) AvailableSteamers() int         { return 0 }
+func (fakeShop) AvailableIceDispensers() int    { return 1 }
+
+func TestPrometheusHandler(t *testing.T) {
+	metrics := NewMetrics(time.Second, 5*time.Second)
+	metrics.IncrementReceivedOrders()
+	metrics.IncrementReceivedOrders()
+	metrics.IncrementProcessedOrders()
+	metrics.IncrementCompletedOrders()
+	metrics.AddWaitTime(500 * time.Millisecond)
+	metrics.AddWaitTime(3 * time.Second)
+	metrics.AddWaitTime(10 * time.Second)
+	metrics.IncrementDroppedEvents("exporter")
+
+	server := httptest.NewServer(NewServer("", metrics, fakeShop{}, nil).Handler)
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "/metrics")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, prometheusContentType, response.Header.Get("Content-Type"))
+	for _, line := range []string{
+		"# TYPE coffeeshop_orders_received_total counter",
+		"coffeeshop_orders_received_total 2",
+		"coffeeshop_orders_processed_total 1",
+		"coffeeshop_orders_completed_total 1",
+		`coffeeshop_events_dropped_total{subscriber="exporter"} 1`,
+		"# TYPE coffeeshop_wait_time_seconds histogram",
+		`coffeeshop_wait_time_seconds_bucket{le="1"} 1`,
+		`coffeeshop_wait_time_seconds_bucket{le="5"} 2`,
+		`coffeeshop_wait_time_seconds_bucket{le="+Inf"} 3`,
+		"coffeeshop_wait_time_seconds_sum 13.5",
+		"coffeeshop_wait_time_seconds_count 3",
+		"coffeeshop_brew_time_seconds_count 0",
+		"# TYPE coffeeshop_steam_time_seconds histogram",
+		"coffeeshop_condiment_time_seconds_count 0",
+		"coffeeshop_ice_time_seconds_count 0",
+		`coffeeshop_cashier_queue_length{cashier="0"} 2`,
+		`coffeeshop_cashier_queue_length{cashier="1"} 4`,
+		"coffeeshop_order_queue_depth 3",
+		"coffeeshop_baristas_busy 5",
+		"coffeeshop_grinders_busy 2",
+		"coffeeshop_brewers_busy 1",
+		"coffeeshop_ice_dispensers_busy 0",
+	} {
+		assert.Contains(t, string(body), line+"\n")
+	}
+}
@@ -0,0 +1,187 @@
+package monitor
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QueueSample is the length of the queues of the coffee shop at a point in time
+// Elapsed is the time since the first sample, in seconds
+type QueueSample struct {
+	Time                   time.Time   `json:"time"`
+	Elapsed                float64     `json:"elapsed"`
+	CashierQueues          map[int]int `json:"cashierQueues"`
+	OrderQueue             int         `json:"orderQueue"`
+	AvailableGrinders      int         `json:"availableGrinders"`
+	AvailableBrewers       int         `json:"availableBrewers"`
+	AvailableSteamers      int         `json:"availableSteamers"`
+	AvailableIceDispensers int         `json:"availableIceDispensers"`
+}
+
+// QueueSampler samples the queues of the coffee shop at a fixed interval and keeps the series in memory
+// The series shows how the queues build up, and with the measured wait times it can be checked against Little's law
+type QueueSampler struct {
+	shop     ShopStater
+	interval time.Duration
+	samples  []QueueSample
+	mutex    sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewQueueSampler creates a sampler of the queues of the shop
+func NewQueueSampler(shop ShopStater, interval time.Duration) *QueueSampler {
+	return &QueueSampler{
+		shop:     shop,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start takes a sample immediately and then at every interval until Stop is called
+func (qs *QueueSampler) Start() {
+	go func() {
+		defer close(qs.done)
+		ticker := time.NewTicker(qs.interval)
+		defer ticker.Stop()
+
+		qs.Sample()
+		for {
+			select {
+			case <-ticker.C:
+				qs.Sample()
+			case <-qs.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop takes a last sample and stops sampling
+func (qs *QueueSampler) Stop() {
+	close(qs.stop)
+	<-qs.done
+	qs.Sample()
+}
+
+// Sample records the current length of the queues
+func (qs *QueueSampler) Sample() {
+	sample := QueueSample{
+		Time:                   time.Now(),
+		CashierQueues:          qs.shop.CashierQueueSizes(),
+		OrderQueue:             qs.shop.OrderQueueSize(),
+		AvailableGrinders:      qs.shop.AvailableGrinders(),
+		AvailableBrewers:       qs.shop.AvailableBrewers(),
+		AvailableSteamers:      qs.shop.AvailableSteamers(),
+		AvailableIceDispensers: qs.shop.AvailableIceDispensers(),
+	}
+
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+	if len(qs.samples) > 0 {
+		sample.Elapsed = sample.Time.Sub(qs.samples[0].Time).Seconds()
+	}
+	qs.samples = append(qs.samples, sample)
+}
+
+// Samples returns a copy of the samples taken so far
+func (qs *QueueSampler) Samples() []QueueSample {
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+	return append([]QueueSample{}, qs.samples...)
+}
+
+// WriteCSV writes the samples as CSV, with one column per cashier
+func (qs *QueueSampler) WriteCSV(w io.Writer) error {
+	samples := qs.Samples()
+
+	cashiers := sampledCashiers(samples)
+
+	writer := csv.NewWriter(w)
+	header := []string{"time", "elapsed_seconds", "order_queue", "available_grinders", "available_brewers", "available_steamers", "available_ice_dispensers"}
+	for _, cashier := range cashiers {
+		header = append(header, fmt.Sprintf("cashier_%d", cashier))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, sample := range samples {
+		row := []string{
+			sample.Time.Format(time.RFC3339Nano),
+			strconv.FormatFloat(sample.Elapsed, 'f', 3, 64),
+			strconv.Itoa(sample.OrderQueue),
+			strconv.Itoa(sample.AvailableGrinders),
+			strconv.Itoa(sample.AvailableBrewers),
+			strconv.Itoa(sample.AvailableSteamers),
+			strconv.Itoa(sample.AvailableIceDispensers),
+		}
+		for _, cashier := range cashiers {
+			row = append(row, strconv.Itoa(sample.CashierQueues[cashier]))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the samples as a JSON array
+func (qs *QueueSampler) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(qs.Samples())
+}
+
+// Export writes the samples to the CSV and JSON files, an empty path skips the format
+func (qs *QueueSampler) Export(csvPath string, jsonPath string) error {
+	if csvPath != "" {
+		if err := writeFile(csvPath, qs.WriteCSV); err != nil {
+			return err
+		}
+	}
+	if jsonPath != "" {
+		if err := writeFile(jsonPath, qs.WriteJSON); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampledCashiers returns the cashiers of the samples in order
+// A cashier may be missing from a sample, so it is the union of the cashiers of all the samples
+func sampledCashiers(samples []QueueSample) []int {
+	cashierSet := make(map[int]bool)
+	for _, sample := range samples {
+		for cashier := range sample.CashierQueues {
+			cashierSet[cashier] = true
+		}
+	}
+	cashiers := make([]int, 0, len(cashierSet))
+	for cashier := range cashierSet {
+		cashiers = append(cashiers, cashier)
+	}
+	sort.Ints(cashiers)
+	return cashiers
+}
+
+// writeFile creates the file at the given path and writes it with the given function
+func writeFile(path string, write func(w io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return file.Close()
+}
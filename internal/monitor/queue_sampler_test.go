@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueSamplerSample(t *testing.T) {
+	sampler := NewQueueSampler(fakeShop{}, time.Second)
+	sampler.Sample()
+	sampler.Sample()
+
+	samples := sampler.Samples()
+	require.Len(t, samples, 2)
+	assert.Equal(t, map[int]int{0: 2, 1: 4}, samples[0].CashierQueues)
+	assert.Equal(t, 3, samples[0].OrderQueue)
+	assert.Equal(t, 1, samples[0].AvailableGrinders)
+	assert.Equal(t, 2, samples[0].AvailableBrewers)
+	assert.Equal(t, 0, samples[0].AvailableSteamers)
+	assert.Equal(t, 1, samples[0].AvailableIceDispensers)
+	assert.Zero(t, samples[0].Elapsed)
+	assert.GreaterOrEqual(t, samples[1].Elapsed, 0.0)
+}
+
+func TestQueueSamplerStartStop(t *testing.T) {
+	sampler := NewQueueSampler(fakeShop{}, time.Millisecond)
+	sampler.Start()
+	time.Sleep(20 * time.Millisecond)
+	sampler.Stop()
+
+	count := len(sampler.Samples())
+	assert.Greater(t, count, 2)
+	time.Sleep(5 * time.Millisecond)
+	assert.Len(t, sampler.Samples(), count, "no sample is taken after Stop")
+}
+
+func TestQueueSamplerWriteCSV(t *testing.T) {
+	sampler := NewQueueSampler(fakeShop{}, time.Second)
+	sampler.Sample()
+
+	var buffer bytes.Buffer
+	require.NoError(t, sampler.WriteCSV(&buffer))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, "time,elapsed_seconds,order_queue,available_grinders,available_brewers,available_steamers,available_ice_dispensers,cashier_0,cashier_1", lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], ",0.000,3,1,2,0,1,2,4"), lines[1])
+}
+
+func TestQueueSamplerExport(t *testing.T) {
+	sampler := NewQueueSampler(fakeShop{}, time.Second)
+	sampler.Sample()
+
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "queues.csv")
+	jsonPath := filepath.Join(dir, "queues.json")
+	require.NoError(t, sampler.Export(csvPath, jsonPath))
+
+	assert.FileExists(t, csvPath)
+	data, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	var samples []QueueSample
+	require.NoError(t, json.Unmarshal(data, &samples))
+	require.Len(t, samples, 1)
+	assert.Equal(t, map[int]int{0: 2, 1: 4}, samples[0].CashierQueues)
+	assert.Equal(t, 3, samples[0].OrderQueue)
+	assert.Equal(t, 1, samples[0].AvailableIceDispensers)
+}
@@ -0,0 +1,385 @@
+package monitor
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The layout of the charts of the report, in pixels
+const (
+	chartWidth        = 720
+	chartHeight       = 240
+	chartMarginLeft   = 50
+	chartMarginRight  = 10
+	chartMarginTop    = 10
+	chartMarginBottom = 40
+	// chartBins is the number of bars of the throughput and wait time charts
+	chartBins = 30
+	// slowestOrders is the number of orders in the table of the slowest orders
+	slowestOrders = 10
+)
+
+// chartColors are the colors of the series of the line charts
+var chartColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f"}
+
+//go:embed report.html
+var reportTemplateSource string
+
+// reportTemplate renders the report, the styles and charts are inline so the file is self-contained
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"seconds": func(seconds float64) string { return fmt.Sprintf("%.2fs", seconds) },
+	"percent": func(percent float64) string { return fmt.Sprintf("%.1f%%", percent) },
+	"join":    strings.Join,
+}).Parse(reportTemplateSource))
+
+// ReportInput is what the report shows besides the orders recorded from the events
+// Config is rendered as YAML, QueueSamples are empty if the queues were not sampled
+type ReportInput struct {
+	Config       interface{}
+	Metrics      MetricsSnapshot
+	QueueSamples []QueueSample
+}
+
+// reportOrder is an order completed at a given time
+type reportOrder struct {
+	completed time.Time
+	order     OrderSnapshot
+}
+
+// ReportRecorder records the completed orders of the run and renders the end-of-run HTML report
+type ReportRecorder struct {
+	orders []reportOrder
+	mutex  sync.Mutex
+}
+
+// NewReportRecorder creates an empty report recorder
+func NewReportRecorder() *ReportRecorder {
+	return &ReportRecorder{}
+}
+
+// ConsumeEvent records the completed orders
+func (rr *ReportRecorder) ConsumeEvent(event Event) {
+	if payload, ok := event.Payload.(*OrderCompletedPayload); ok {
+		rr.mutex.Lock()
+		rr.orders = append(rr.orders, reportOrder{completed: event.Timestamp, order: payload.Order})
+		rr.mutex.Unlock()
+	}
+}
+
+// reportData is what the report template renders
+type reportData struct {
+	Generated     time.Time
+	Config        string
+	Metrics       MetricsSnapshot
+	Throughput    chart
+	WaitTimes     chart
+	Utilization   chart
+	Queues        chart
+	Equipment     chart
+	HasQueues     bool
+	SlowestOrders []OrderSnapshot
+}
+
+// WriteHTML writes the self-contained HTML report
+func (rr *ReportRecorder) WriteHTML(w io.Writer, input ReportInput) error {
+	rr.mutex.Lock()
+	orders := append([]reportOrder{}, rr.orders...)
+	rr.mutex.Unlock()
+
+	data := reportData{
+		Generated:     time.Now(),
+		Metrics:       input.Metrics,
+		Throughput:    throughputChart(orders),
+		WaitTimes:     waitTimeChart(orders),
+		Utilization:   utilizationChart(input.Metrics.Resources),
+		HasQueues:     len(input.QueueSamples) > 0,
+		SlowestOrders: slowest(orders, slowestOrders),
+	}
+	data.Queues, data.Equipment = queueCharts(input.QueueSamples)
+	if input.Config != nil {
+		config, err := yaml.Marshal(input.Config)
+		if err != nil {
+			return fmt.Errorf("marshal config: %w", err)
+		}
+		data.Config = string(config)
+	}
+	return reportTemplate.Execute(w, data)
+}
+
+// Export writes the report to the file at the given path
+func (rr *ReportRecorder) Export(path string, input ReportInput) error {
+	return writeFile(path, func(w io.Writer) error {
+		return rr.WriteHTML(w, input)
+	})
+}
+
+// slowest returns the orders that waited the longest, the slowest first
+func slowest(orders []reportOrder, count int) []OrderSnapshot {
+	snapshots := make([]OrderSnapshot, 0, len(orders))
+	for _, o := range orders {
+		snapshots = append(snapshots, o.order)
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].WaitTime > snapshots[j].WaitTime })
+	if len(snapshots) > count {
+		snapshots = snapshots[:count]
+	}
+	return snapshots
+}
+
+// throughputChart is the number of orders completed per minute over the run
+func throughputChart(orders []reportOrder) chart {
+	if len(orders) == 0 {
+		return chart{}
+	}
+	start, end := orders[0].order.OrderTime, orders[0].completed
+	for _, o := range orders {
+		if o.order.OrderTime.Before(start) {
+			start = o.order.OrderTime
+		}
+		if o.completed.After(end) {
+			end = o.completed
+		}
+	}
+	binWidth := math.Max(math.Ceil(end.Sub(start).Seconds()/chartBins), 1)
+	bins := int(end.Sub(start).Seconds()/binWidth) + 1
+	counts := make([]float64, bins)
+	for _, o := range orders {
+		counts[int(o.completed.Sub(start).Seconds()/binWidth)]++
+	}
+	for i := range counts {
+		counts[i] *= 60 / binWidth
+	}
+	c := newBarChart(counts, 0, "s", "orders/min")
+	c.XLabels = xLabels(c, 0, float64(bins)*binWidth)
+	return c
+}
+
+// waitTimeChart is the distribution of the wait times of the orders
+func waitTimeChart(orders []reportOrder) chart {
+	if len(orders) == 0 {
+		return chart{}
+	}
+	var maxWait float64
+	for _, o := range orders {
+		maxWait = math.Max(maxWait, o.order.WaitTime.Seconds())
+	}
+	binWidth := math.Max(maxWait/chartBins, 0.001)
+	counts := make([]float64, chartBins)
+	for _, o := range orders {
+		bin := int(o.order.WaitTime.Seconds() / binWidth)
+		if bin >= chartBins {
+			bin = chartBins - 1
+		}
+		counts[bin]++
+	}
+	c := newBarChart(counts, 0, "s", "orders")
+	c.XLabels = xLabels(c, 0, binWidth*chartBins)
+	return c
+}
+
+// utilizationChart is the utilization of each barista, grinder and brewer
+func utilizationChart(resources []ResourceUtilization) chart {
+	if len(resources) == 0 {
+		return chart{}
+	}
+	values := make([]float64, len(resources))
+	labels := make([]string, len(resources))
+	for i, resource := range resources {
+		values[i] = resource.Utilization
+		// the grinders and brewers are named after their kind, the baristas are numbered
+		labels[i] = resource.Name
+		if !strings.HasPrefix(resource.Name, string(resource.Kind)) {
+			labels[i] = fmt.Sprintf("%s %s", resource.Kind, resource.Name)
+		}
+	}
+	c := newBarChart(values, 100, "", "%")
+	for i, bar := range c.Bars {
+		c.Bars[i].Title = fmt.Sprintf("%s: %.1f%%", labels[i], values[i])
+		c.XLabels = append(c.XLabels, axisLabel{Position: bar.X + bar.Width/2, Text: labels[i], Rotate: true})
+	}
+	return c
+}
+
+// queueCharts are the lengths of the queues and the available equipment over the run
+func queueCharts(samples []QueueSample) (chart, chart) {
+	if len(samples) == 0 {
+		return chart{}, chart{}
+	}
+	cashiers := sampledCashiers(samples)
+	elapsed := make([]float64, len(samples))
+	queues := []series{{Name: "order queue", Values: make([]float64, len(samples))}}
+	for _, cashier := range cashiers {
+		queues = append(queues, series{Name: fmt.Sprintf("cashier %d", cashier), Values: make([]float64, len(samples))})
+	}
+	equipment := []series{
+		{Name: "available grinders", Values: make([]float64, len(samples))},
+		{Name: "available brewers", Values: make([]float64, len(samples))},
+		{Name: "available steamers", Values: make([]float64, len(samples))},
+		{Name: "available ice dispensers", Values: make([]float64, len(samples))},
+	}
+	for i, sample := range samples {
+		elapsed[i] = sample.Elapsed
+		queues[0].Values[i] = float64(sample.OrderQueue)
+		for j, cashier := range cashiers {
+			queues[j+1].Values[i] = float64(sample.CashierQueues[cashier])
+		}
+		equipment[0].Values[i] = float64(sample.AvailableGrinders)
+		equipment[1].Values[i] = float64(sample.AvailableBrewers)
+		equipment[2].Values[i] = float64(sample.AvailableSteamers)
+		equipment[3].Values[i] = float64(sample.AvailableIceDispensers)
+	}
+	return newLineChart(elapsed, queues, "s", "length"), newLineChart(elapsed, equipment, "s", "available")
+}
+
+// chart is an SVG chart, the coordinates are in pixels
+type chart struct {
+	Width   float64
+	Height  float64
+	Bars    []bar
+	Lines   []line
+	XLabels []axisLabel
+	YLabels []axisLabel
+	XUnit   string
+	YUnit   string
+	// the plot area
+	Left   float64
+	Right  float64
+	Top    float64
+	Bottom float64
+}
+
+// bar is a bar of a bar chart
+type bar struct {
+	X, Y, Width, Height float64
+	Title               string
+}
+
+// line is a series of a line chart
+type line struct {
+	Name   string
+	Color  string
+	Points string
+}
+
+// series is a named series of values of a line chart
+type series struct {
+	Name   string
+	Values []float64
+}
+
+// axisLabel is a label of an axis at the given position in pixels
+// Rotate is for the long labels of the bars, they are slanted so they do not overlap
+type axisLabel struct {
+	Position float64
+	Text     string
+	Rotate   bool
+}
+
+// newChart creates an empty chart with the standard layout
+func newChart(xUnit string, yUnit string) chart {
+	return chart{
+		Width:  chartWidth,
+		Height: chartHeight,
+		XUnit:  xUnit,
+		YUnit:  yUnit,
+		Left:   chartMarginLeft,
+		Right:  chartWidth - chartMarginRight,
+		Top:    chartMarginTop,
+		Bottom: chartHeight - chartMarginBottom,
+	}
+}
+
+// newBarChart creates a bar chart of the values
+// The y axis goes up to yMax, or to the largest value if yMax is zero
+func newBarChart(values []float64, yMax float64, xUnit string, yUnit string) chart {
+	c := newChart(xUnit, yUnit)
+	if yMax == 0 {
+		for _, value := range values {
+			yMax = math.Max(yMax, value)
+		}
+	}
+	if yMax <= 0 {
+		yMax = 1
+	}
+	width := (c.Right - c.Left) / float64(len(values))
+	for i, value := range values {
+		height := value / yMax * (c.Bottom - c.Top)
+		c.Bars = append(c.Bars, bar{
+			X:      c.Left + float64(i)*width + 1,
+			Y:      c.Bottom - height,
+			Width:  math.Max(width-2, 1),
+			Height: height,
+			Title:  fmt.Sprintf("%s %s", formatValue(value), yUnit),
+		})
+	}
+	c.YLabels = yLabels(c, yMax)
+	return c
+}
+
+// newLineChart creates a line chart of the series over xs
+func newLineChart(xs []float64, data []series, xUnit string, yUnit string) chart {
+	c := newChart(xUnit, yUnit)
+	xMax := xs[len(xs)-1]
+	if xMax <= 0 {
+		xMax = 1
+	}
+	var yMax float64
+	for _, s := range data {
+		for _, value := range s.Values {
+			yMax = math.Max(yMax, value)
+		}
+	}
+	if yMax <= 0 {
+		yMax = 1
+	}
+	for i, s := range data {
+		points := make([]string, len(xs))
+		for j, x := range xs {
+			px := c.Left + x/xMax*(c.Right-c.Left)
+			py := c.Bottom - s.Values[j]/yMax*(c.Bottom-c.Top)
+			points[j] = fmt.Sprintf("%.1f,%.1f", px, py)
+		}
+		c.Lines = append(c.Lines, line{Name: s.Name, Color: chartColors[i%len(chartColors)], Points: strings.Join(points, " ")})
+	}
+	c.XLabels = xLabels(c, 0, xMax)
+	c.YLabels = yLabels(c, yMax)
+	return c
+}
+
+// xLabels are the labels at the start, middle and end of the x axis
+func xLabels(c chart, xMin float64, xMax float64) []axisLabel {
+	labels := make([]axisLabel, 0, 3)
+	for _, fraction := range []float64{0, 0.5, 1} {
+		labels = append(labels, axisLabel{
+			Position: c.Left + fraction*(c.Right-c.Left),
+			Text:     formatValue(xMin + fraction*(xMax-xMin)),
+		})
+	}
+	return labels
+}
+
+// yLabels are the labels at the bottom, middle and top of the y axis
+func yLabels(c chart, yMax float64) []axisLabel {
+	labels := make([]axisLabel, 0, 3)
+	for _, fraction := range []float64{0, 0.5, 1} {
+		labels = append(labels, axisLabel{
+			Position: c.Bottom - fraction*(c.Bottom-c.Top),
+			Text:     formatValue(fraction * yMax),
+		})
+	}
+	return labels
+}
+
+// formatValue formats a value of an axis with at most one decimal
+func formatValue(value float64) string {
+	return strings.TrimSuffix(fmt.Sprintf("%.1f", value), ".0")
+}
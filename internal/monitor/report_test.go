@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportRecorderWriteHTML(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	metrics := NewMetrics()
+	recorder := NewReportRecorder()
+	for i := 1; i <= 12; i++ {
+		event := completedEvent(start.Add(time.Duration(i)*time.Minute), time.Duration(i)*time.Second)
+		payload := event.Payload.(*OrderCompletedPayload)
+		payload.Order.OrderID = int64(i)
+		payload.Order.Coffee = "Latte"
+		payload.Order.OrderTime = start
+		metrics.ConsumeEvent(event)
+		recorder.ConsumeEvent(event)
+	}
+	metrics.ConsumeEvent(Event{Timestamp: start, Payload: &GrinderAcquiredPayload{Grinder: "grinder1"}})
+	metrics.ConsumeEvent(Event{Timestamp: start.Add(6 * time.Minute), Payload: &GrinderReleasedPayload{Grinder: "grinder1"}})
+
+	var buffer bytes.Buffer
+	require.NoError(t, recorder.WriteHTML(&buffer, ReportInput{
+		Config:  map[string]int{"numberOfBaristas": 5},
+		Metrics: metrics.Snapshot(),
+		QueueSamples: []QueueSample{
+			{Elapsed: 0, CashierQueues: map[int]int{0: 1}, OrderQueue: 2, AvailableGrinders: 1},
+			{Elapsed: 1, CashierQueues: map[int]int{0: 3}, OrderQueue: 0, AvailableGrinders: 0},
+		},
+	}))
+	report := buffer.String()
+
+	// the report is self-contained
+	assert.NotContains(t, report, "<script src")
+	assert.NotContains(t, report, "<link")
+	assert.Contains(t, report, "numberOfBaristas: 5")
+	assert.Contains(t, report, "<polyline")
+	assert.Contains(t, report, "cashier 0")
+	// the grinder was busy for 6 of the 11 minutes of events
+	assert.Contains(t, report, "grinder1: 54.5%")
+
+	// the slowest order comes first in the table, and only the 10 slowest are listed
+	slowest := report[strings.Index(report, "<h2>Slowest orders</h2>"):strings.Index(report, "<h2>Drinks</h2>")]
+	assert.Less(t, strings.Index(slowest, "<td>12</td>"), strings.Index(slowest, "<td>11</td>"))
+	assert.Equal(t, 10, strings.Count(slowest, `<td class="text">Latte</td>`))
+}
+
+func TestReportRecorderWriteHTMLWithoutOrders(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, NewReportRecorder().WriteHTML(&buffer, ReportInput{Metrics: NewMetrics().Snapshot()}))
+	assert.Contains(t, buffer.String(), "No order was completed.")
+	assert.Contains(t, buffer.String(), "The queues were not sampled")
+}
+
+func TestThroughputChart(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	orders := []reportOrder{
+		{completed: start.Add(10 * time.Second), order: OrderSnapshot{OrderTime: start}},
+		{completed: start.Add(20 * time.Second), order: OrderSnapshot{OrderTime: start}},
+		{completed: start.Add(59 * time.Second), order: OrderSnapshot{OrderTime: start}},
+	}
+	c := throughputChart(orders)
+
+	// 59 seconds in 2 second bins
+	require.Len(t, c.Bars, 30)
+	var total float64
+	for _, b := range c.Bars {
+		total += b.Height
+	}
+	// every bar with an order is as high as the plot, each is 30 orders per minute
+	assert.InDelta(t, 3*(c.Bottom-c.Top), total, 0.001)
+	assert.Equal(t, "30", c.YLabels[2].Text)
+}
@@ -0,0 +1,37 @@
+package monitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+)
+
+// NewServer creates the HTTP server of the monitor
+// It serves /metrics in the Prometheus text exposition format and /snapshot as JSON, the caller starts it with ListenAndServe
+// If a stream is given, it also serves the events as Server-Sent Events on /events
+func NewServer(address string, metrics *Metrics, shop ShopStater, stream *EventStream) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", PrometheusHandler(metrics, shop))
+	mux.Handle("/snapshot", SnapshotHandler(metrics))
+	if stream != nil {
+		mux.Handle("/events", EventStreamHandler(stream))
+	}
+
+	return &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// SnapshotHandler serves a snapshot of the metrics, including the rolling windows, as JSON
+func SnapshotHandler(metrics *Metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(metrics.Snapshot()); err != nil {
+			utils.Logger().WithError(err).Error("Error writing metrics snapshot")
+		}
+	})
+}
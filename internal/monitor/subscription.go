@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// EventFilter decides whether a subscriber receives an event
+// A nil filter accepts every event
+type EventFilter func(event Event) bool
+
+// EventTypes returns a filter accepting only the given event types
+func EventTypes(eventTypes ...EventType) EventFilter {
+	accepted := make(map[EventType]bool, len(eventTypes))
+	for _, eventType := range eventTypes {
+		accepted[eventType] = true
+	}
+	return func(event Event) bool {
+		return accepted[event.Type]
+	}
+}
+
+// Subscription is a subscriber registered with the event system
+// Each subscription has its own buffer and goroutine, so a slow subscriber only delays its own events
+// When the buffer is full, the overflow policy decides whether the sender waits or an event is dropped
+// next is the sequence number of the next event to offer, the senders take their turn in sequence order,
+// so the events are buffered in order without holding the lock of the event system
+type Subscription struct {
+	name     string
+	consumer EventConsumer
+	filter   EventFilter
+	policy   OverflowPolicy
+	events   chan Event
+	done     chan struct{}
+	dropped  atomic.Int64
+	next     uint64
+	closed   bool
+	turn     *sync.Cond
+	mutex    sync.Mutex
+}
+
+// newSubscription creates a subscription and starts delivering its events to the consumer
+// next is the sequence number of the first event it receives
+func newSubscription(name string, consumer EventConsumer, filter EventFilter, bufferSize int, policy OverflowPolicy, next uint64) *Subscription {
+	s := &Subscription{
+		name:     name,
+		consumer: consumer,
+		filter:   filter,
+		policy:   policy,
+		events:   make(chan Event, bufferSize),
+		done:     make(chan struct{}),
+		next:     next,
+	}
+	s.turn = sync.NewCond(&s.mutex)
+	go s.deliver()
+	return s
+}
+
+// Name returns the name of the subscription
+func (s *Subscription) Name() string {
+	return s.name
+}
+
+// Pending returns the number of events buffered but not yet consumed
+func (s *Subscription) Pending() int {
+	return len(s.events)
+}
+
+// Dropped returns the number of events dropped because the buffer was full
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// send waits for the turn of the event, then buffers it if the subscriber accepts it
+// It returns false if an event was dropped, or if the subscription was closed meanwhile
+func (s *Subscription) send(event Event) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for s.next != event.Sequence {
+		s.turn.Wait()
+	}
+	defer s.turn.Broadcast()
+	s.next++
+
+	if s.closed {
+		return false
+	}
+	return !s.accepts(event) || s.offer(event)
+}
+
+// offer buffers the event according to the overflow policy
+// It returns false if an event, the offered one or an older one, was dropped
+func (s *Subscription) offer(event Event) bool {
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.events <- event:
+			return true
+		default:
+			s.dropped.Add(1)
+			return false
+		}
+	case DropOldest:
+		delivered := true
+		for {
+			select {
+			case s.events <- event:
+				return delivered
+			default:
+			}
+			// the buffer is full, discard the oldest event unless the consumer just took it
+			select {
+			case <-s.events:
+				s.dropped.Add(1)
+				delivered = false
+			default:
+			}
+		}
+	default:
+		s.events <- event
+		return true
+	}
+}
+
+// accepts returns true if the subscriber wants the event
+func (s *Subscription) accepts(event Event) bool {
+	return s.filter == nil || s.filter(event)
+}
+
+// deliver feeds the buffered events to the consumer until the subscription is closed
+func (s *Subscription) deliver() {
+	defer close(s.done)
+	for event := range s.events {
+		s.consumer.ConsumeEvent(event)
+	}
+}
+
+// close stops accepting events and waits for the buffered ones to be consumed
+func (s *Subscription) close() {
+	s.mutex.Lock()
+	s.closed = true
+	close(s.events)
+	s.mutex.Unlock()
+	<-s.done
+}
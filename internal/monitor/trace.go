@@ -0,0 +1,286 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// The processes of the trace, each cashier, barista, grinder, brewer, steamer and ice dispenser is a thread of its process
+const (
+	cashierTraceProcess = iota + 1
+	baristaTraceProcess
+	grinderTraceProcess
+	brewerTraceProcess
+	steamerTraceProcess
+	iceDispenserTraceProcess
+)
+
+// traceProcessNames are the names shown by the trace viewer for each process
+var traceProcessNames = map[int]string{
+	cashierTraceProcess:      "Cashiers",
+	baristaTraceProcess:      "Baristas",
+	grinderTraceProcess:      "Grinders",
+	brewerTraceProcess:       "Brewers",
+	steamerTraceProcess:      "Steamers",
+	iceDispenserTraceProcess: "Ice dispensers",
+}
+
+// traceEvent is an event of the Chrome Trace Event format
+// The timestamps and durations are in microseconds since the first event of the run
+type traceEvent struct {
+	Name         string                 `json:"name"`
+	Category     string                 `json:"cat,omitempty"`
+	Phase        string                 `json:"ph"`
+	Timestamp    float64                `json:"ts"`
+	Duration     float64                `json:"dur,omitempty"`
+	ProcessID    int                    `json:"pid"`
+	ThreadID     int                    `json:"tid"`
+	ID           int64                  `json:"id,omitempty"`
+	BindingPoint string                 `json:"bp,omitempty"`
+	Args         map[string]interface{} `json:"args,omitempty"`
+}
+
+// traceFile is the JSON object format of a trace file
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// traceTrack is a thread of the trace
+type traceTrack struct {
+	process int
+	thread  int
+}
+
+// TraceRecorder builds a trace of the run in the Chrome Trace Event format from the lifecycle events
+// The trace has one track per cashier, barista, grinder, brewer, steamer and ice dispenser, with a span for each customer served,
+// order processed, grinding, brewing, steaming, ice dispensed and cold brew batch prepared,
+// and flow arrows following each customer across the tracks, until the coffee is picked up
+// It can be loaded in chrome://tracing or https://ui.perfetto.dev
+type TraceRecorder struct {
+	events []traceEvent
+	start  time.Time
+	tracks map[int]map[string]traceTrack
+	// assignedCustomers is the time each customer joined the queue of a cashier
+	assignedCustomers map[int64]time.Time
+	// cashierFree is the time each cashier queued its last order
+	cashierFree map[int]time.Time
+	// the start of the spans still open, by order
+	baristaStarts map[int64]time.Time
+	grindStarts   map[int64]time.Time
+	brewStarts    map[int64]time.Time
+	steamStarts   map[int64]time.Time
+	mutex         sync.Mutex
+}
+
+// NewTraceRecorder creates an empty trace recorder
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{
+		tracks:            make(map[int]map[string]traceTrack),
+		assignedCustomers: make(map[int64]time.Time),
+		cashierFree:       make(map[int]time.Time),
+		baristaStarts:     make(map[int64]time.Time),
+		grindStarts:       make(map[int64]time.Time),
+		brewStarts:        make(map[int64]time.Time),
+		steamStarts:       make(map[int64]time.Time),
+	}
+}
+
+// ConsumeEvent adds the spans the event opens or closes to the trace
+func (tr *TraceRecorder) ConsumeEvent(event Event) {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+
+	if tr.start.IsZero() {
+		tr.start = event.Timestamp
+	}
+	at := event.Timestamp
+	switch payload := event.Payload.(type) {
+	case *CustomerAssignedToCashierPayload:
+		tr.assignedCustomers[payload.CustomerID] = at
+	case *OrderQueuedPayload:
+		// a cashier serves its customers one at a time, so it starts with a customer
+		// when the customer is in its queue and it is done with the previous one
+		start := at
+		if assigned, ok := tr.assignedCustomers[payload.Order.CustomerID]; ok {
+			start = assigned
+			delete(tr.assignedCustomers, payload.Order.CustomerID)
+		}
+		if free, ok := tr.cashierFree[payload.Cashier]; ok && free.After(start) {
+			start = free
+		}
+		tr.cashierFree[payload.Cashier] = at
+		track := tr.track(cashierTraceProcess, fmt.Sprintf("Cashier %d", payload.Cashier))
+		tr.addSpan(track, "Customer "+payload.Order.Customer, "customer", start, at, map[string]interface{}{
+			"customerId": payload.Order.CustomerID,
+			"orderId":    payload.Order.OrderID,
+		})
+		tr.addFlow(track, "s", payload.Order.CustomerID, start)
+	case *BaristaAssignedPayload:
+		tr.baristaStarts[payload.Order.OrderID] = at
+		tr.addFlow(tr.track(baristaTraceProcess, fmt.Sprintf("Barista %d", payload.Barista)), "t", payload.Order.CustomerID, at)
+	case *GrinderAcquiredPayload:
+		tr.addWait(tr.track(baristaTraceProcess, fmt.Sprintf("Barista %d", payload.Barista)), "Waiting for a grinder", at, payload.WaitTime)
+	case *GrindStartedPayload:
+		tr.grindStarts[payload.OrderID] = at
+		tr.addFlow(tr.track(grinderTraceProcess, payload.Grinder), "t", payload.CustomerID, at)
+	case *GrindFinishedPayload:
+		if start, ok := tr.grindStarts[payload.OrderID]; ok {
+			delete(tr.grindStarts, payload.OrderID)
+			tr.addSpan(tr.track(grinderTraceProcess, payload.Grinder), fmt.Sprintf("Grind order %d", payload.OrderID), "grind", start, at, map[string]interface{}{
+				"orderId": payload.OrderID,
+				"barista": payload.Barista,
+			})
+		}
+	case *BrewerAcquiredPayload:
+		tr.addWait(tr.track(baristaTraceProcess, fmt.Sprintf("Barista %d", payload.Barista)), "Waiting for a brewer", at, payload.WaitTime)
+	case *BrewStartedPayload:
+		tr.brewStarts[payload.OrderID] = at
+		tr.addFlow(tr.track(brewerTraceProcess, payload.Brewer), "t", payload.CustomerID, at)
+	case *BrewFinishedPayload:
+		if start, ok := tr.brewStarts[payload.OrderID]; ok {
+			delete(tr.brewStarts, payload.OrderID)
+			tr.addSpan(tr.track(brewerTraceProcess, payload.Brewer), fmt.Sprintf("Brew order %d", payload.OrderID), "brew", start, at, map[string]interface{}{
+				"orderId": payload.OrderID,
+				"barista": payload.Barista,
+			})
+		}
+	case *SteamerAcquiredPayload:
+		tr.addWait(tr.track(baristaTraceProcess, fmt.Sprintf("Barista %d", payload.Barista)), "Waiting for a steamer", at, payload.WaitTime)
+	case *SteamStartedPayload:
+		tr.steamStarts[payload.OrderID] = at
+	case *SteamFinishedPayload:
+		if start, ok := tr.steamStarts[payload.OrderID]; ok {
+			delete(tr.steamStarts, payload.OrderID)
+			tr.addSpan(tr.track(steamerTraceProcess, payload.Steamer), fmt.Sprintf("Steam order %d", payload.OrderID), "steam", start, at, map[string]interface{}{
+				"orderId": payload.OrderID,
+				"barista": payload.Barista,
+			})
+		}
+	case *IceDispenserAcquiredPayload:
+		tr.addWait(tr.track(baristaTraceProcess, fmt.Sprintf("Barista %d", payload.Barista)), "Waiting for an ice dispenser", at, payload.WaitTime)
+	case *IceDispensedPayload:
+		tr.addSpan(tr.track(iceDispenserTraceProcess, payload.IceDispenser), fmt.Sprintf("Ice order %d", payload.OrderID), "ice", at.Add(-payload.IceTime), at, map[string]interface{}{
+			"orderId": payload.OrderID,
+			"barista": payload.Barista,
+		})
+	case *ColdBrewReplenishedPayload:
+		tr.addSpan(tr.track(baristaTraceProcess, fmt.Sprintf("Barista %d", payload.Barista)), "Prepare cold brew", "cold brew", at.Add(-payload.PrepareTime), at, map[string]interface{}{
+			"batch": payload.Batch.String(),
+			"stock": payload.Stock.String(),
+		})
+	case *StepAbortedPayload:
+		// the work thrown away has no span, the barista starts it over
+		delete(tr.grindStarts, payload.OrderID)
+		delete(tr.brewStarts, payload.OrderID)
+		delete(tr.steamStarts, payload.OrderID)
+	case *OrderCancelledPayload:
+		// the order is given up, its flow ends on the barista without a span
+		delete(tr.baristaStarts, payload.Order.OrderID)
+		tr.addFlow(tr.track(baristaTraceProcess, fmt.Sprintf("Barista %d", payload.Barista)), "f", payload.Order.CustomerID, at)
+	case *OrderPickedUpPayload:
+		// the flow ends once per order, whatever steps the recipe had and however many times they were started over
+		track := tr.track(baristaTraceProcess, fmt.Sprintf("Barista %d", payload.Barista))
+		tr.addFlow(track, "f", payload.Order.CustomerID, at)
+		if start, ok := tr.baristaStarts[payload.Order.OrderID]; ok {
+			delete(tr.baristaStarts, payload.Order.OrderID)
+			tr.addSpan(track, fmt.Sprintf("Order %d", payload.Order.OrderID), "order", start, at, map[string]interface{}{
+				"orderId":  payload.Order.OrderID,
+				"customer": payload.Order.Customer,
+				"coffee":   payload.Order.Coffee,
+				"size":     payload.Order.Size.String(),
+				"extras":   payload.Order.Extras,
+			})
+		}
+	}
+}
+
+// track returns the thread of the named resource, naming the thread in the trace the first time it is seen
+func (tr *TraceRecorder) track(process int, name string) traceTrack {
+	threads, ok := tr.tracks[process]
+	if !ok {
+		threads = make(map[string]traceTrack)
+		tr.tracks[process] = threads
+		tr.events = append(tr.events, traceEvent{
+			Name:      "process_name",
+			Phase:     "M",
+			ProcessID: process,
+			Args:      map[string]interface{}{"name": traceProcessNames[process]},
+		})
+	}
+	track, ok := threads[name]
+	if !ok {
+		track = traceTrack{process: process, thread: len(threads) + 1}
+		threads[name] = track
+		tr.events = append(tr.events, traceEvent{
+			Name:      "thread_name",
+			Phase:     "M",
+			ProcessID: track.process,
+			ThreadID:  track.thread,
+			Args:      map[string]interface{}{"name": name},
+		})
+	}
+	return track
+}
+
+// timestamp converts a time to microseconds since the first event
+func (tr *TraceRecorder) timestamp(at time.Time) float64 {
+	return float64(at.Sub(tr.start).Nanoseconds()) / 1e3
+}
+
+// addSpan adds a complete span to the track
+func (tr *TraceRecorder) addSpan(track traceTrack, name string, category string, start time.Time, end time.Time, args map[string]interface{}) {
+	tr.events = append(tr.events, traceEvent{
+		Name:      name,
+		Category:  category,
+		Phase:     "X",
+		Timestamp: tr.timestamp(start),
+		Duration:  float64(end.Sub(start).Nanoseconds()) / 1e3,
+		ProcessID: track.process,
+		ThreadID:  track.thread,
+		Args:      args,
+	})
+}
+
+// addWait adds the span of a barista waiting for equipment, if it had to wait at all
+func (tr *TraceRecorder) addWait(track traceTrack, name string, acquired time.Time, wait time.Duration) {
+	if wait <= contentionThreshold {
+		return
+	}
+	tr.addSpan(track, name, "wait", acquired.Add(-wait), acquired, nil)
+}
+
+// addFlow adds a step of the flow arrow of a customer, bound to the span enclosing it on the track
+// phase is s for the first step, t for the intermediate steps and f for the last one
+func (tr *TraceRecorder) addFlow(track traceTrack, phase string, customerID int64, at time.Time) {
+	tr.events = append(tr.events, traceEvent{
+		Name:         "customer",
+		Category:     "customer",
+		Phase:        phase,
+		Timestamp:    tr.timestamp(at),
+		ProcessID:    track.process,
+		ThreadID:     track.thread,
+		ID:           customerID,
+		BindingPoint: "e",
+	})
+}
+
+// WriteTrace writes the trace in the Chrome Trace Event JSON format
+func (tr *TraceRecorder) WriteTrace(w io.Writer) error {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+
+	events := tr.events
+	if events == nil {
+		events = []traceEvent{}
+	}
+	return json.NewEncoder(w).Encode(traceFile{TraceEvents: events, DisplayTimeUnit: "ms"})
+}
+
+// Export writes the trace to the file at the given path
+func (tr *TraceRecorder) Export(path string) error {
+	return writeFile(path, tr.WriteTrace)
+}
@@ -0,0 +1,171 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceRecorder(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	order := OrderSnapshot{OrderRef: OrderRef{OrderID: 7, CustomerRef: CustomerRef{CustomerID: 3, Customer: "alice"}}, Coffee: "Latte"}
+	ref := order.OrderRef
+	events := []Event{
+		{Timestamp: start, Payload: &CustomerAssignedToCashierPayload{CustomerRef: ref.CustomerRef, Cashier: 1}},
+		{Timestamp: start.Add(time.Second), Payload: &OrderQueuedPayload{Cashier: 1, Order: order}},
+		{Timestamp: start.Add(2 * time.Second), Payload: &BaristaAssignedPayload{Barista: 0, Order: order}},
+		{Timestamp: start.Add(3 * time.Second), Payload: &GrinderAcquiredPayload{OrderRef: ref, Grinder: "grinder1", WaitTime: time.Second}},
+		{Timestamp: start.Add(3 * time.Second), Payload: &GrindStartedPayload{OrderRef: ref, Grinder: "grinder1"}},
+		{Timestamp: start.Add(5 * time.Second), Payload: &GrindFinishedPayload{OrderRef: ref, Grinder: "grinder1"}},
+		{Timestamp: start.Add(5 * time.Second), Payload: &BrewerAcquiredPayload{OrderRef: ref, Brewer: "brewer1"}},
+		{Timestamp: start.Add(5 * time.Second), Payload: &BrewStartedPayload{OrderRef: ref, Brewer: "brewer1"}},
+		{Timestamp: start.Add(9 * time.Second), Payload: &BrewFinishedPayload{OrderRef: ref, Brewer: "brewer1"}},
+		{Timestamp: start.Add(10 * time.Second), Payload: &OrderPickedUpPayload{Barista: 0, Order: order}},
+	}
+	recorder := NewTraceRecorder()
+	for _, event := range events {
+		recorder.ConsumeEvent(event)
+	}
+
+	var buffer bytes.Buffer
+	require.NoError(t, recorder.WriteTrace(&buffer))
+	var trace traceFile
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &trace))
+
+	spans := make(map[string]traceEvent)
+	threads := make(map[string]traceTrack)
+	var flows []traceEvent
+	for _, event := range trace.TraceEvents {
+		switch event.Phase {
+		case "X":
+			spans[event.Name] = event
+		case "M":
+			if event.Name == "thread_name" {
+				threads[event.Args["name"].(string)] = traceTrack{process: event.ProcessID, thread: event.ThreadID}
+			}
+		default:
+			flows = append(flows, event)
+		}
+	}
+
+	assert.Len(t, threads, 4)
+	assert.Equal(t, traceEvent{Name: "Customer alice", Category: "customer", Phase: "X", Timestamp: 0, Duration: 1e6,
+		ProcessID: cashierTraceProcess, ThreadID: threads["Cashier 1"].thread,
+		Args: map[string]interface{}{"customerId": 3.0, "orderId": 7.0}}, spans["Customer alice"])
+	assert.Equal(t, 2e6, spans["Order 7"].Timestamp)
+	assert.Equal(t, 8e6, spans["Order 7"].Duration)
+	assert.Equal(t, baristaTraceProcess, spans["Order 7"].ProcessID)
+	assert.Equal(t, 2e6, spans["Waiting for a grinder"].Timestamp)
+	assert.Equal(t, 1e6, spans["Waiting for a grinder"].Duration)
+	assert.Equal(t, 3e6, spans["Grind order 7"].Timestamp)
+	assert.Equal(t, grinderTraceProcess, spans["Grind order 7"].ProcessID)
+	assert.Equal(t, 4e6, spans["Brew order 7"].Duration)
+	assert.Equal(t, brewerTraceProcess, spans["Brew order 7"].ProcessID)
+	// the brewer was available, so the barista did not wait for it
+	assert.NotContains(t, spans, "Waiting for a brewer")
+
+	// the flow of the customer goes from the cashier to the barista, the grinder and the brewer, and back to the barista
+	require.Len(t, flows, 5)
+	phases := ""
+	for _, flow := range flows {
+		assert.Equal(t, int64(3), flow.ID)
+		phases += flow.Phase
+	}
+	assert.Equal(t, "stttf", phases)
+	assert.Equal(t, grinderTraceProcess, flows[2].ProcessID)
+	assert.Equal(t, baristaTraceProcess, flows[4].ProcessID)
+}
+
+// flows returns the phases of the flow steps of the trace, and the processes they are on
+func flows(recorder *TraceRecorder) (string, []int) {
+	phases := ""
+	var processes []int
+	for _, event := range recorder.events {
+		switch event.Phase {
+		case "s", "t", "f":
+			phases += event.Phase
+			processes = append(processes, event.ProcessID)
+		}
+	}
+	return phases, processes
+}
+
+func TestTraceRecorderEndsTheFlowOfAColdBrew(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	order := OrderSnapshot{OrderRef: OrderRef{OrderID: 7, CustomerRef: CustomerRef{CustomerID: 3, Customer: "alice"}}, Coffee: "Cold Brew"}
+	recorder := NewTraceRecorder()
+	for _, event := range []Event{
+		{Timestamp: start, Payload: &OrderQueuedPayload{Cashier: 1, Order: order}},
+		{Timestamp: start.Add(time.Second), Payload: &BaristaAssignedPayload{Barista: 0, Order: order}},
+		{Timestamp: start.Add(2 * time.Second), Payload: &ColdBrewDrawnPayload{OrderRef: order.OrderRef}},
+		{Timestamp: start.Add(3 * time.Second), Payload: &OrderPickedUpPayload{Barista: 0, Order: order}},
+	} {
+		recorder.ConsumeEvent(event)
+	}
+
+	phases, processes := flows(recorder)
+	// a cold brew is neither ground nor brewed, its flow still ends when it is picked up
+	assert.Equal(t, "stf", phases)
+	assert.Equal(t, []int{cashierTraceProcess, baristaTraceProcess, baristaTraceProcess}, processes)
+}
+
+func TestTraceRecorderEndsTheFlowOnceWhenTheBrewIsStartedOver(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	order := OrderSnapshot{OrderRef: OrderRef{OrderID: 7, CustomerRef: CustomerRef{CustomerID: 3, Customer: "alice"}}, Coffee: "Latte"}
+	ref := order.OrderRef
+	recorder := NewTraceRecorder()
+	for _, event := range []Event{
+		{Timestamp: start, Payload: &OrderQueuedPayload{Cashier: 1, Order: order}},
+		{Timestamp: start.Add(time.Second), Payload: &BaristaAssignedPayload{Barista: 0, Order: order}},
+		{Timestamp: start.Add(time.Second), Payload: &GrindStartedPayload{OrderRef: ref, Grinder: "grinder1"}},
+		{Timestamp: start.Add(2 * time.Second), Payload: &GrindFinishedPayload{OrderRef: ref, Grinder: "grinder1"}},
+		{Timestamp: start.Add(2 * time.Second), Payload: &BrewStartedPayload{OrderRef: ref, Brewer: "brewer1"}},
+		{Timestamp: start.Add(3 * time.Second), Payload: &StepAbortedPayload{OrderRef: ref, Equipment: "brewer1"}},
+		{Timestamp: start.Add(3 * time.Second), Payload: &GrindStartedPayload{OrderRef: ref, Grinder: "grinder1"}},
+		{Timestamp: start.Add(4 * time.Second), Payload: &GrindFinishedPayload{OrderRef: ref, Grinder: "grinder1"}},
+		{Timestamp: start.Add(4 * time.Second), Payload: &BrewStartedPayload{OrderRef: ref, Brewer: "brewer2"}},
+		{Timestamp: start.Add(6 * time.Second), Payload: &BrewFinishedPayload{OrderRef: ref, Brewer: "brewer2"}},
+		{Timestamp: start.Add(7 * time.Second), Payload: &OrderPickedUpPayload{Barista: 0, Order: order}},
+	} {
+		recorder.ConsumeEvent(event)
+	}
+
+	phases, _ := flows(recorder)
+	assert.Equal(t, "stttttf", phases, "the flow goes through the restarted steps and ends once")
+	var brews []traceEvent
+	for _, event := range recorder.events {
+		if event.Category == "brew" {
+			brews = append(brews, event)
+		}
+	}
+	// the aborted brew has no span
+	require.Len(t, brews, 1)
+	assert.Equal(t, 4e6, brews[0].Timestamp)
+	assert.Equal(t, 2e6, brews[0].Duration)
+}
+
+func TestTraceRecorderCashierServesOneCustomerAtATime(t *testing.T) {
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	recorder := NewTraceRecorder()
+	for i, name := range []string{"alice", "bob"} {
+		customer := CustomerRef{CustomerID: int64(i), Customer: name}
+		recorder.ConsumeEvent(Event{Timestamp: start, Payload: &CustomerAssignedToCashierPayload{CustomerRef: customer}})
+		recorder.ConsumeEvent(Event{Timestamp: start.Add(time.Duration(i+1) * time.Second),
+			Payload: &OrderQueuedPayload{Order: OrderSnapshot{OrderRef: OrderRef{OrderID: int64(i), CustomerRef: customer}}}})
+	}
+
+	var spans []traceEvent
+	for _, event := range recorder.events {
+		if event.Phase == "X" {
+			spans = append(spans, event)
+		}
+	}
+	require.Len(t, spans, 2)
+	// bob joined the queue with alice, but the cashier only started with him once done with her
+	assert.Equal(t, 1e6, spans[1].Timestamp)
+	assert.Equal(t, 1e6, spans[1].Duration)
+}
@@ -0,0 +1,216 @@
+package monitor
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// contentionThreshold is the wait above which an acquisition is counted as contended
+const contentionThreshold = time.Millisecond
+
+// ResourceKind is the kind of a resource of the coffee shop
+type ResourceKind string
+
+const (
+	// BaristaResource is a barista, it is acquired when it picks an order from the order queue
+	BaristaResource ResourceKind = "barista"
+	// GrinderResource is a grinder, it is acquired from the grinder pool
+	GrinderResource ResourceKind = "grinder"
+	// BrewerResource is a brewer, it is acquired from the brewer pool
+	BrewerResource ResourceKind = "brewer"
+	// SteamerResource is a milk steamer, it is acquired from the steamer pool
+	SteamerResource ResourceKind = "steamer"
+	// IceDispenserResource is an ice dispenser, it is acquired from the ice dispenser pool
+	IceDispenserResource ResourceKind = "ice dispenser"
+)
+
+// ResourceUtilization is the usage of a single barista, grinder, brewer, steamer or ice dispenser, the times are in seconds
+// AcquisitionWait is the time spent waiting before the resource was acquired:
+// orders waiting in the order queue for a barista, baristas waiting in the pool for a grinder, brewer, steamer or ice dispenser
+type ResourceUtilization struct {
+	Kind            ResourceKind `json:"kind"`
+	Name            string       `json:"name"`
+	Jobs            int          `json:"jobs"`
+	BusyTime        float64      `json:"busyTime"`
+	IdleTime        float64      `json:"idleTime"`
+	Utilization     float64      `json:"utilization"`
+	AcquisitionWait float64      `json:"acquisitionWait"`
+}
+
+// PoolUtilization is the usage of all the resources of a kind, the times are in seconds
+// Utilization is the average utilization of the resources of the pool in percent
+// ContendedAcquisitions is the number of acquisitions that had to wait for a resource to be available
+type PoolUtilization struct {
+	Kind                   ResourceKind `json:"kind"`
+	Resources              int          `json:"resources"`
+	Jobs                   int          `json:"jobs"`
+	Utilization            float64      `json:"utilization"`
+	ContendedAcquisitions  int          `json:"contendedAcquisitions"`
+	AverageAcquisitionWait float64      `json:"averageAcquisitionWait"`
+	MaxAcquisitionWait     float64      `json:"maxAcquisitionWait"`
+}
+
+// resourceUsage accumulates the usage of a resource
+// busySince is zero while the resource is idle
+type resourceUsage struct {
+	busySince          time.Time
+	busyTime           time.Duration
+	jobs               int
+	contendedJobs      int
+	acquisitionWait    time.Duration
+	maxAcquisitionWait time.Duration
+}
+
+// utilization tracks the usage of the resources from the lifecycle events
+// It is not thread safe, Metrics guards it with its own mutex
+type utilization struct {
+	resources map[ResourceKind]map[string]*resourceUsage
+	// queuedOrders is the time each order waiting for a barista was queued
+	queuedOrders map[int64]time.Time
+}
+
+// newUtilization creates an empty utilization tracker
+func newUtilization() *utilization {
+	return &utilization{
+		resources:    make(map[ResourceKind]map[string]*resourceUsage),
+		queuedOrders: make(map[int64]time.Time),
+	}
+}
+
+// consumeEvent updates the usage of the resource the event is about
+// The registered resources are counted from the start, so a resource that is never used counts as idle
+func (u *utilization) consumeEvent(event Event) {
+	switch payload := event.Payload.(type) {
+	case *ResourcesRegisteredPayload:
+		for _, name := range payload.Names {
+			u.usage(payload.Kind, name)
+		}
+	case *OrderQueuedPayload:
+		u.queuedOrders[payload.Order.OrderID] = event.Timestamp
+	case *BaristaAssignedPayload:
+		var wait time.Duration
+		if queued, ok := u.queuedOrders[payload.Order.OrderID]; ok {
+			wait = event.Timestamp.Sub(queued)
+			delete(u.queuedOrders, payload.Order.OrderID)
+		}
+		u.acquire(BaristaResource, strconv.Itoa(payload.Barista), event.Timestamp, wait)
+	case *OrderPickedUpPayload:
+		u.release(BaristaResource, strconv.Itoa(payload.Barista), event.Timestamp)
+	case *OrderCancelledPayload:
+		u.release(BaristaResource, strconv.Itoa(payload.Barista), event.Timestamp)
+	case *GrinderAcquiredPayload:
+		u.acquire(GrinderResource, payload.Grinder, event.Timestamp, payload.WaitTime)
+	case *GrinderReleasedPayload:
+		u.release(GrinderResource, payload.Grinder, event.Timestamp)
+	case *BrewerAcquiredPayload:
+		u.acquire(BrewerResource, payload.Brewer, event.Timestamp, payload.WaitTime)
+	case *BrewerReleasedPayload:
+		u.release(BrewerResource, payload.Brewer, event.Timestamp)
+	case *SteamerAcquiredPayload:
+		u.acquire(SteamerResource, payload.Steamer, event.Timestamp, payload.WaitTime)
+	case *SteamerReleasedPayload:
+		u.release(SteamerResource, payload.Steamer, event.Timestamp)
+	case *IceDispenserAcquiredPayload:
+		u.acquire(IceDispenserResource, payload.IceDispenser, event.Timestamp, payload.WaitTime)
+	case *IceDispenserReleasedPayload:
+		u.release(IceDispenserResource, payload.IceDispenser, event.Timestamp)
+	}
+}
+
+// usage returns the usage of a resource, creating it when it is registered or on first use
+func (u *utilization) usage(kind ResourceKind, name string) *resourceUsage {
+	resources, ok := u.resources[kind]
+	if !ok {
+		resources = make(map[string]*resourceUsage)
+		u.resources[kind] = resources
+	}
+	usage, ok := resources[name]
+	if !ok {
+		usage = &resourceUsage{}
+		resources[name] = usage
+	}
+	return usage
+}
+
+// acquire marks the resource as busy from the given time
+func (u *utilization) acquire(kind ResourceKind, name string, at time.Time, wait time.Duration) {
+	usage := u.usage(kind, name)
+	usage.busySince = at
+	usage.jobs++
+	usage.acquisitionWait += wait
+	if wait > usage.maxAcquisitionWait {
+		usage.maxAcquisitionWait = wait
+	}
+	if wait > contentionThreshold {
+		usage.contendedJobs++
+	}
+}
+
+// release marks the resource as idle from the given time
+func (u *utilization) release(kind ResourceKind, name string, at time.Time) {
+	usage := u.usage(kind, name)
+	if usage.busySince.IsZero() {
+		return
+	}
+	usage.busyTime += at.Sub(usage.busySince)
+	usage.busySince = time.Time{}
+}
+
+// summaries returns the usage of each resource and pool over the observed period ending now
+// A resource still busy now counts as busy until now
+func (u *utilization) summaries(now time.Time, observed time.Duration) ([]ResourceUtilization, []PoolUtilization) {
+	kinds := make([]string, 0, len(u.resources))
+	for kind := range u.resources {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+
+	resources := make([]ResourceUtilization, 0)
+	pools := make([]PoolUtilization, 0, len(kinds))
+	for _, k := range kinds {
+		kind := ResourceKind(k)
+		names := make([]string, 0, len(u.resources[kind]))
+		for name := range u.resources[kind] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		pool := PoolUtilization{Kind: kind, Resources: len(names)}
+		var totalWait time.Duration
+		var maxWait time.Duration
+		for _, name := range names {
+			usage := u.resources[kind][name]
+			busy := usage.busyTime
+			if !usage.busySince.IsZero() {
+				busy += now.Sub(usage.busySince)
+			}
+			resource := ResourceUtilization{
+				Kind:            kind,
+				Name:            name,
+				Jobs:            usage.jobs,
+				BusyTime:        busy.Seconds(),
+				AcquisitionWait: usage.acquisitionWait.Seconds(),
+			}
+			if observed > 0 {
+				resource.IdleTime = (observed - busy).Seconds()
+				resource.Utilization = 100 * busy.Seconds() / observed.Seconds()
+			}
+			resources = append(resources, resource)
+
+			pool.Jobs += usage.jobs
+			pool.ContendedAcquisitions += usage.contendedJobs
+			pool.Utilization += resource.Utilization / float64(len(names))
+			totalWait += usage.acquisitionWait
+			if usage.maxAcquisitionWait > maxWait {
+				maxWait = usage.maxAcquisitionWait
+			}
+		}
+		if pool.Jobs > 0 {
+			pool.AverageAcquisitionWait = (totalWait / time.Duration(pool.Jobs)).Seconds()
+		}
+		pool.MaxAcquisitionWait = maxWait.Seconds()
+		pools = append(pools, pool)
+	}
+	return resources, pools
+}
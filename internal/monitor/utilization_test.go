@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsUtilization(t *testing.T) {
+	metrics := NewMetrics()
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	order := OrderSnapshot{OrderRef: OrderRef{OrderID: 1}}
+	orderRef := order.OrderRef
+
+	for _, event := range []Event{
+		{Timestamp: at(0), Payload: &OrderQueuedPayload{Order: order}},
+		// the order waits 10s for barista 1
+		{Timestamp: at(10), Payload: &BaristaAssignedPayload{Barista: 1, Order: order}},
+		{Timestamp: at(10), Payload: &GrinderAcquiredPayload{OrderRef: orderRef, Barista: 1, Grinder: "grinder1"}},
+		{Timestamp: at(20), Payload: &GrinderReleasedPayload{OrderRef: orderRef, Barista: 1, Grinder: "grinder1"}},
+		// barista 1 waits 5s for a brewer
+		{Timestamp: at(25), Payload: &BrewerAcquiredPayload{OrderRef: orderRef, Barista: 1, Brewer: "brewer1", WaitTime: 5 * time.Second}},
+		{Timestamp: at(35), Payload: &BrewerReleasedPayload{OrderRef: orderRef, Barista: 1, Brewer: "brewer1"}},
+		{Timestamp: at(40), Payload: &OrderPickedUpPayload{Barista: 1, Order: order}},
+		// barista 2 is still busy at the end of the observed period
+		{Timestamp: at(80), Payload: &BaristaAssignedPayload{Barista: 2, Order: OrderSnapshot{OrderRef: OrderRef{OrderID: 2}}}},
+		{Timestamp: at(100), Payload: &GrinderAcquiredPayload{Barista: 2, Grinder: "grinder2"}},
+	} {
+		event.Type = event.Payload.EventType()
+		metrics.ConsumeEvent(event)
+	}
+
+	snapshot := metrics.Snapshot()
+	require.Len(t, snapshot.Resources, 5)
+	assert.Equal(t, ResourceUtilization{Kind: BaristaResource, Name: "1", Jobs: 1, BusyTime: 30, IdleTime: 70, Utilization: 30, AcquisitionWait: 10}, snapshot.Resources[0])
+	assert.Equal(t, ResourceUtilization{Kind: BaristaResource, Name: "2", Jobs: 1, BusyTime: 20, IdleTime: 80, Utilization: 20}, snapshot.Resources[1])
+	assert.Equal(t, ResourceUtilization{Kind: BrewerResource, Name: "brewer1", Jobs: 1, BusyTime: 10, IdleTime: 90, Utilization: 10, AcquisitionWait: 5}, snapshot.Resources[2])
+	assert.Equal(t, ResourceUtilization{Kind: GrinderResource, Name: "grinder1", Jobs: 1, BusyTime: 10, IdleTime: 90, Utilization: 10}, snapshot.Resources[3])
+	assert.Equal(t, ResourceUtilization{Kind: GrinderResource, Name: "grinder2", Jobs: 1, IdleTime: 100}, snapshot.Resources[4])
+
+	require.Len(t, snapshot.Pools, 3)
+	assert.Equal(t, PoolUtilization{Kind: BaristaResource, Resources: 2, Jobs: 2, Utilization: 25, ContendedAcquisitions: 1, AverageAcquisitionWait: 5, MaxAcquisitionWait: 10}, snapshot.Pools[0])
+	assert.Equal(t, PoolUtilization{Kind: BrewerResource, Resources: 1, Jobs: 1, Utilization: 10, ContendedAcquisitions: 1, AverageAcquisitionWait: 5, MaxAcquisitionWait: 5}, snapshot.Pools[1])
+	assert.Equal(t, PoolUtilization{Kind: GrinderResource, Resources: 2, Jobs: 2, Utilization: 5}, snapshot.Pools[2])
+}
+
+func TestMetricsUtilizationCountsRegisteredResources(t *testing.T) {
+	metrics := NewMetrics()
+	start := time.Date(2023, 4, 21, 8, 0, 0, 0, time.UTC)
+	for _, event := range []Event{
+		{Timestamp: start, Payload: &ResourcesRegisteredPayload{Kind: BrewerResource, Names: []string{"brewer1", "brewer2"}}},
+		{Timestamp: start, Payload: &BrewerAcquiredPayload{Brewer: "brewer1"}},
+		{Timestamp: start.Add(10 * time.Second), Payload: &BrewerReleasedPayload{Brewer: "brewer1"}},
+	} {
+		metrics.ConsumeEvent(event)
+	}
+
+	snapshot := metrics.Snapshot()
+	require.Len(t, snapshot.Resources, 2)
+	assert.Equal(t, ResourceUtilization{Kind: BrewerResource, Name: "brewer2", IdleTime: 10}, snapshot.Resources[1], "The brewer never used is idle")
+	require.Len(t, snapshot.Pools, 1)
+	assert.Equal(t, PoolUtilization{Kind: BrewerResource, Resources: 2, Jobs: 1, Utilization: 50}, snapshot.Pools[0])
+}
@@ -0,0 +1,347 @@
+package recipe
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The variables a duration formula can use
+const (
+	// SizeVariable is the size of the coffee, 0 for standard, 1 for large and 2 for extra large
+	SizeVariable = "size"
+	// BeansVariable is the beans of the coffee, in grams
+	BeansVariable = "beans"
+	// WaterVariable is the water of the coffee, in ounces
+	WaterVariable = "water"
+	// MilkVariable is the milk steamed for the coffee, in ounces
+	MilkVariable = "milk"
+	// IceVariable is the ice the coffee is poured over, in ounces
+	IceVariable = "ice"
+	// RateVariable is the rate of the equipment of the step, per second, it is only known for the steps on equipment
+	RateVariable = "rate"
+	// CondimentsVariable is the seconds configured for the condiments of the order
+	CondimentsVariable = "condiments"
+)
+
+// knownVariables are the variables a formula may use
+var knownVariables = map[string]bool{
+	SizeVariable:       true,
+	BeansVariable:      true,
+	WaterVariable:      true,
+	MilkVariable:       true,
+	IceVariable:        true,
+	RateVariable:       true,
+	CondimentsVariable: true,
+}
+
+// functions are the functions a formula may call, by name, with their number of arguments
+var functions = map[string]struct {
+	arguments int
+	call      func(arguments []float64) float64
+}{
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"min":   {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":   {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+}
+
+// Variables are the values a formula is evaluated with, by name
+type Variables map[string]float64
+
+// Formula is a duration formula in seconds, for example floor(beans / rate) or 2 + size
+// It is made of numbers, variables, the operators + - * /, parentheses and the functions floor, ceil, min and max
+type Formula struct {
+	source    string
+	root      node
+	variables []string
+}
+
+// ParseFormula parses a duration formula, it fails on a syntax error or an unknown variable or function
+func ParseFormula(source string) (*Formula, error) {
+	p := &parser{tokens: tokenize(source), variables: make(map[string]bool)}
+	root := p.expression()
+	if p.err == nil && p.position < len(p.tokens) {
+		p.fail("unexpected %q", p.tokens[p.position])
+	}
+	if p.err != nil {
+		return nil, fmt.Errorf("formula %q: %w", source, p.err)
+	}
+	variables := make([]string, 0, len(p.variables))
+	for variable := range p.variables {
+		variables = append(variables, variable)
+	}
+	sort.Strings(variables)
+	return &Formula{source: source, root: root, variables: variables}, nil
+}
+
+// String returns the source of the formula
+func (f *Formula) String() string {
+	return f.source
+}
+
+// Uses returns whether the formula uses the variable
+func (f *Formula) Uses(variable string) bool {
+	i := sort.SearchStrings(f.variables, variable)
+	return i < len(f.variables) && f.variables[i] == variable
+}
+
+// Evaluate evaluates the formula, it fails if a variable it uses is missing or on a division by zero
+func (f *Formula) Evaluate(variables Variables) (float64, error) {
+	value, err := f.root.evaluate(variables)
+	if err != nil {
+		return 0, fmt.Errorf("formula %q: %w", f.source, err)
+	}
+	return value, nil
+}
+
+// Duration evaluates the formula as a number of seconds, a negative duration is zero
+func (f *Formula) Duration(variables Variables) (time.Duration, error) {
+	seconds, err := f.Evaluate(variables)
+	if err != nil || seconds <= 0 {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// node is a node of the syntax tree of a formula
+type node interface {
+	evaluate(variables Variables) (float64, error)
+}
+
+// number is a constant
+type number float64
+
+func (n number) evaluate(Variables) (float64, error) {
+	return float64(n), nil
+}
+
+// variable is the value of a variable
+type variable string
+
+func (v variable) evaluate(variables Variables) (float64, error) {
+	value, ok := variables[string(v)]
+	if !ok {
+		return 0, fmt.Errorf("%s is not known for this step", string(v))
+	}
+	return value, nil
+}
+
+// negation is the opposite of its operand
+type negation struct {
+	operand node
+}
+
+func (n negation) evaluate(variables Variables) (float64, error) {
+	value, err := n.operand.evaluate(variables)
+	return -value, err
+}
+
+// operation is a binary operation
+type operation struct {
+	operator    string
+	left, right node
+}
+
+func (o operation) evaluate(variables Variables) (float64, error) {
+	left, err := o.left.evaluate(variables)
+	if err != nil {
+		return 0, err
+	}
+	right, err := o.right.evaluate(variables)
+	if err != nil {
+		return 0, err
+	}
+	switch o.operator {
+	case "+":
+		return left + right, nil
+	case "-":
+		return left - right, nil
+	case "*":
+		return left * right, nil
+	}
+	if right == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return left / right, nil
+}
+
+// call is a call of a function
+type call struct {
+	function  string
+	arguments []node
+}
+
+func (c call) evaluate(variables Variables) (float64, error) {
+	arguments := make([]float64, len(c.arguments))
+	for i, argument := range c.arguments {
+		value, err := argument.evaluate(variables)
+		if err != nil {
+			return 0, err
+		}
+		arguments[i] = value
+	}
+	return functions[c.function].call(arguments), nil
+}
+
+// tokenize splits a formula in numbers, names, operators and parentheses
+// An invalid character is kept as a token of its own, the parser rejects it
+func tokenize(source string) []string {
+	var tokens []string
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		case unicode.IsLetter(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, string(runes[start:i]))
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+// parser is a recursive descent parser of formulas, it stops at the first error
+type parser struct {
+	tokens    []string
+	position  int
+	variables map[string]bool
+	err       error
+}
+
+// expression parses a sum or difference of terms
+func (p *parser) expression() node {
+	left := p.term()
+	for p.err == nil && (p.peek() == "+" || p.peek() == "-") {
+		operator := p.next()
+		left = operation{operator: operator, left: left, right: p.term()}
+	}
+	return left
+}
+
+// term parses a product or quotient of factors
+func (p *parser) term() node {
+	left := p.factor()
+	for p.err == nil && (p.peek() == "*" || p.peek() == "/") {
+		operator := p.next()
+		left = operation{operator: operator, left: left, right: p.factor()}
+	}
+	return left
+}
+
+// factor parses a number, a variable, a function call, a negation or a parenthesized expression
+func (p *parser) factor() node {
+	if p.err != nil {
+		return nil
+	}
+	token := p.next()
+	switch {
+	case token == "":
+		p.fail("unexpected end")
+	case token == "-":
+		return negation{operand: p.factor()}
+	case token == "(":
+		inner := p.expression()
+		p.expect(")")
+		return inner
+	case unicode.IsDigit(rune(token[0])) || token[0] == '.':
+		value, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			p.fail("invalid number %q", token)
+		}
+		return number(value)
+	case unicode.IsLetter(rune(token[0])):
+		if p.peek() == "(" {
+			return p.call(token)
+		}
+		if !knownVariables[token] {
+			p.fail("unknown variable %q, the variables are %s", token, strings.Join(sortedKeys(knownVariables), ", "))
+		}
+		p.variables[token] = true
+		return variable(token)
+	default:
+		p.fail("unexpected %q", token)
+	}
+	return nil
+}
+
+// call parses the arguments of a call of the named function
+func (p *parser) call(name string) node {
+	function, ok := functions[name]
+	if !ok {
+		p.fail("unknown function %q", name)
+		return nil
+	}
+	p.expect("(")
+	c := call{function: name}
+	for p.err == nil {
+		c.arguments = append(c.arguments, p.expression())
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	p.expect(")")
+	if p.err == nil && len(c.arguments) != function.arguments {
+		p.fail("%s takes %d arguments", name, function.arguments)
+	}
+	return c
+}
+
+// peek returns the next token without consuming it, empty at the end
+func (p *parser) peek() string {
+	if p.position < len(p.tokens) {
+		return p.tokens[p.position]
+	}
+	return ""
+}
+
+// next consumes the next token, empty at the end
+func (p *parser) next() string {
+	token := p.peek()
+	if token != "" {
+		p.position++
+	}
+	return token
+}
+
+// expect consumes the next token, failing if it is not the expected one
+func (p *parser) expect(token string) {
+	if p.err == nil && p.next() != token {
+		p.fail("expected %q", token)
+	}
+}
+
+// fail records the first error of the parse
+func (p *parser) fail(format string, args ...interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+}
+
+// sortedKeys returns the keys of the set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
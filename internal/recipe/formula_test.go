@@ -0,0 +1,77 @@
+package recipe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormulaEvaluate(t *testing.T) {
+	variables := Variables{"beans": 45.5, "rate": 15, "size": 2, "milk": 6}
+	tests := []struct {
+		formula string
+		want    float64
+	}{
+		{"2", 2},
+		{"floor(beans / rate)", 3},
+		{"ceil(beans / rate)", 4},
+		{"1 + 2 * size", 5},
+		{"(1 + 2) * size", 6},
+		{"-size + 3", 1},
+		{"max(milk / 4, 1)", 1.5},
+		{"min(milk, 2.5)", 2.5},
+		{".5 * milk", 3},
+	}
+	for _, test := range tests {
+		formula, err := ParseFormula(test.formula)
+		if assert.NoError(t, err, test.formula) {
+			value, err := formula.Evaluate(variables)
+			assert.NoError(t, err, test.formula)
+			assert.Equal(t, test.want, value, test.formula)
+		}
+	}
+}
+
+func TestParseFormulaErrors(t *testing.T) {
+	tests := []struct {
+		formula string
+		err     string
+	}{
+		{"", `formula "": unexpected end`},
+		{"1 +", `formula "1 +": unexpected end`},
+		{"(1 + 2", `formula "(1 + 2": expected ")"`},
+		{"1 2", `formula "1 2": unexpected "2"`},
+		{"beans % 2", `formula "beans % 2": unexpected "%"`},
+		{"sugar * 2", `formula "sugar * 2": unknown variable "sugar", the variables are beans, condiments, ice, milk, rate, size, water`},
+		{"round(beans)", `formula "round(beans)": unknown function "round"`},
+		{"min(beans)", `formula "min(beans)": min takes 2 arguments`},
+		{"1..2", `formula "1..2": invalid number "1..2"`},
+	}
+	for _, test := range tests {
+		_, err := ParseFormula(test.formula)
+		assert.EqualError(t, err, test.err, test.formula)
+	}
+}
+
+func TestFormulaDuration(t *testing.T) {
+	formula, err := ParseFormula("milk / rate - 1")
+	assert.NoError(t, err)
+	assert.True(t, formula.Uses(MilkVariable))
+	assert.True(t, formula.Uses(RateVariable))
+	assert.False(t, formula.Uses(BeansVariable))
+
+	duration, err := formula.Duration(Variables{"milk": 6, "rate": 4})
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, duration)
+
+	duration, err = formula.Duration(Variables{"milk": 2, "rate": 4})
+	assert.NoError(t, err)
+	assert.Zero(t, duration, "A negative duration is zero")
+
+	_, err = formula.Duration(Variables{"milk": 2, "rate": 0})
+	assert.EqualError(t, err, `formula "milk / rate - 1": division by zero`)
+
+	_, err = formula.Duration(Variables{"milk": 2})
+	assert.EqualError(t, err, `formula "milk / rate - 1": rate is not known for this step`)
+}
@@ -0,0 +1,209 @@
+package recipe
+
+import (
+	"fmt"
+	"time"
+)
+
+// Action is what a step of a recipe does to the coffee
+type Action string
+
+// The actions of the steps of a recipe
+const (
+	// Grind grinds the beans of the coffee, it is the only action that consumes the beans
+	Grind Action = "grind"
+	// Brew brews the coffee
+	Brew Action = "brew"
+	// Shot pulls an extra shot of espresso on the brewer
+	Shot Action = "shot"
+	// Steam steams the milk of the coffee, it is skipped if the coffee has no steamed extra or no milk
+	Steam Action = "steam"
+	// Ice pours the coffee over ice, it is skipped if the coffee has no ice
+	Ice Action = "ice"
+	// ColdBrew draws the coffee from the cold brew stock, it takes the time to wait for a batch
+	ColdBrew Action = "coldbrew"
+	// Condiments adds the extras that are not steamed, like sugar, it is skipped if there are none
+	Condiments Action = "condiments"
+	// Pour pours the coffee in the cup
+	Pour Action = "pour"
+)
+
+// Equipment is the class of equipment a step is performed on
+type Equipment string
+
+// The classes of equipment
+const (
+	// Grinder is a grinder that handles the beans of the coffee
+	Grinder Equipment = "grinder"
+	// Brewer is a brewer
+	Brewer Equipment = "brewer"
+	// Steamer is a milk steamer
+	Steamer Equipment = "steamer"
+	// IceDispenser is an ice dispenser
+	IceDispenser Equipment = "ice dispenser"
+	// None is for the steps the barista performs by hand
+	None Equipment = "none"
+)
+
+// defaults are the equipment and duration of each action when the step does not name them
+var defaults = map[Action]struct {
+	equipment Equipment
+	duration  string
+}{
+	Grind:      {Grinder, "floor(beans / rate)"},
+	Brew:       {Brewer, "floor(water / rate)"},
+	Shot:       {Brewer, "1"},
+	Steam:      {Steamer, "floor(milk / rate)"},
+	Ice:        {IceDispenser, "floor(ice / rate)"},
+	ColdBrew:   {None, ""},
+	Condiments: {None, "condiments"},
+	Pour:       {None, "0"},
+}
+
+// Step is a step of a recipe
+// Duration is the formula of the seconds the step takes, it is nil for a cold brew step which takes the time of the stock
+// A step performed Together with the previous one is in the same group, see Groups
+type Step struct {
+	Action    Action
+	Equipment Equipment
+	Duration  *Formula
+	Together  bool
+}
+
+// NewStep creates a step of the given action
+// The equipment and duration are the defaults of the action when they are empty
+// The rate variable, the rate of the equipment, can only be used by a step on equipment
+func NewStep(action, equipment, duration string) (Step, error) {
+	defaultStep, ok := defaults[Action(action)]
+	if !ok {
+		return Step{}, fmt.Errorf("unknown action %q", action)
+	}
+	step := Step{Action: Action(action), Equipment: Equipment(equipment)}
+	if step.Equipment == "" {
+		step.Equipment = defaultStep.equipment
+	}
+	switch step.Equipment {
+	case Grinder, Brewer, Steamer, IceDispenser, None:
+	default:
+		return Step{}, fmt.Errorf("%s: unknown equipment %q", action, equipment)
+	}
+	// the grinder knows the beans the coffee is made with, and the stock is the only source of cold brew
+	if step.Action == Grind && step.Equipment != Grinder {
+		return Step{}, fmt.Errorf("%s: must be performed on a grinder", action)
+	}
+	if step.Action == Condiments && step.Equipment != None {
+		return Step{}, fmt.Errorf("%s: must be added by hand at the condiment station", action)
+	}
+	if step.Action == ColdBrew {
+		if step.Equipment != None || duration != "" {
+			return Step{}, fmt.Errorf("%s: takes no equipment or duration", action)
+		}
+		return step, nil
+	}
+	if duration == "" {
+		duration = defaultStep.duration
+	}
+	formula, err := ParseFormula(duration)
+	if err != nil {
+		return Step{}, fmt.Errorf("%s: %w", action, err)
+	}
+	if step.Equipment == None && formula.Uses(RateVariable) {
+		return Step{}, fmt.Errorf("%s: %s is only known for a step on equipment", action, RateVariable)
+	}
+	step.Duration = formula
+	return step, nil
+}
+
+// MustStep creates a step like NewStep, it panics if the step is invalid
+func MustStep(action, equipment, duration string) Step {
+	step, err := NewStep(action, equipment, duration)
+	if err != nil {
+		panic(err)
+	}
+	return step
+}
+
+// Time returns the time the step takes with the given variables, zero for a cold brew step
+func (s Step) Time(variables Variables) (time.Duration, error) {
+	if s.Duration == nil {
+		return 0, nil
+	}
+	return s.Duration.Duration(variables)
+}
+
+// String returns the action of the step, with its equipment if it is not performed by hand
+func (s Step) String() string {
+	if s.Equipment == None {
+		return string(s.Action)
+	}
+	return fmt.Sprintf("%s on %s", s.Action, s.Equipment)
+}
+
+// Recipe is the ordered steps of the preparation of a coffee type
+type Recipe []Step
+
+// Default returns the recipe of the coffee types that do not declare one
+// A cold brew is drawn from the stock instead of being ground and brewed, an iced coffee is poured over ice and its milk is not steamed
+func Default(iced, coldBrew bool) Recipe {
+	var recipe Recipe
+	if coldBrew {
+		recipe = append(recipe, MustStep(string(ColdBrew), "", ""))
+	} else {
+		recipe = append(recipe, MustStep(string(Grind), "", ""), MustStep(string(Brew), "", ""))
+	}
+	if iced {
+		recipe = append(recipe, MustStep(string(Ice), "", ""))
+	} else {
+		recipe = append(recipe, MustStep(string(Steam), "", ""))
+	}
+	return append(recipe, MustStep(string(Condiments), "", ""), MustStep(string(Pour), "", ""))
+}
+
+// Has returns whether the recipe has a step of the action
+func (r Recipe) Has(action Action) bool {
+	for _, step := range r {
+		if step.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Uses returns whether a step of the recipe is performed on the equipment
+func (r Recipe) Uses(equipment Equipment) bool {
+	for _, step := range r {
+		if step.Equipment == equipment {
+			return true
+		}
+	}
+	return false
+}
+
+// Groups splits the recipe in the groups of steps performed together, in order
+// The equipment of a group is reserved at once and held until its last step is done,
+// for example the brewer is not released between the shot and the steaming of the milk, and a class of equipment is used once per group
+func (r Recipe) Groups() []Recipe {
+	var groups []Recipe
+	for i, step := range r {
+		if i == 0 || !step.Together {
+			groups = append(groups, Recipe{})
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], step)
+	}
+	return groups
+}
+
+// Restart returns the index of the step the recipe starts over from when the step at the index was aborted and the coffee thrown away
+// A brew or a shot throws the ground beans away with the coffee, so the recipe starts over from the last grind before it,
+// any other step only loses what it was making, like the steamed milk, and it is the one performed again
+func (r Recipe) Restart(aborted int) int {
+	if r[aborted].Action != Brew && r[aborted].Action != Shot {
+		return aborted
+	}
+	for i := aborted - 1; i >= 0; i-- {
+		if r[i].Action == Grind {
+			return i
+		}
+	}
+	return aborted
+}
@@ -0,0 +1,105 @@
+package recipe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStepDefaults(t *testing.T) {
+	step, err := NewStep("grind", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, Grinder, step.Equipment)
+	assert.Equal(t, "floor(beans / rate)", step.Duration.String())
+
+	step, err = NewStep("shot", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "shot on brewer", step.String())
+	duration, err := step.Time(Variables{})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, duration)
+
+	step, err = NewStep("pour", "", "1 + size")
+	assert.NoError(t, err)
+	assert.Equal(t, "pour", step.String())
+	duration, err = step.Time(Variables{"size": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, duration)
+
+	step, err = NewStep("coldbrew", "", "")
+	assert.NoError(t, err)
+	assert.Nil(t, step.Duration)
+	duration, err = step.Time(Variables{})
+	assert.NoError(t, err)
+	assert.Zero(t, duration)
+}
+
+func TestNewStepErrors(t *testing.T) {
+	tests := []struct {
+		action, equipment, duration string
+		err                         string
+	}{
+		{"whisk", "", "", `unknown action "whisk"`},
+		{"brew", "kettle", "", `brew: unknown equipment "kettle"`},
+		{"grind", "none", "2", "grind: must be performed on a grinder"},
+		{"condiments", "steamer", "", "condiments: must be added by hand at the condiment station"},
+		{"coldbrew", "brewer", "", "coldbrew: takes no equipment or duration"},
+		{"coldbrew", "", "10", "coldbrew: takes no equipment or duration"},
+		{"pour", "", "water / rate", "pour: rate is only known for a step on equipment"},
+		{"steam", "", "milk /", `steam: formula "milk /": unexpected end`},
+	}
+	for _, test := range tests {
+		_, err := NewStep(test.action, test.equipment, test.duration)
+		assert.EqualError(t, err, test.err, test.action)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	actions := func(recipe Recipe) []Action {
+		var actions []Action
+		for _, step := range recipe {
+			actions = append(actions, step.Action)
+		}
+		return actions
+	}
+	assert.Equal(t, []Action{Grind, Brew, Steam, Condiments, Pour}, actions(Default(false, false)))
+	assert.Equal(t, []Action{Grind, Brew, Ice, Condiments, Pour}, actions(Default(true, false)))
+	assert.Equal(t, []Action{ColdBrew, Ice, Condiments, Pour}, actions(Default(true, true)))
+
+	recipe := Default(true, true)
+	assert.True(t, recipe.Has(ColdBrew))
+	assert.False(t, recipe.Has(Grind))
+	assert.True(t, recipe.Uses(IceDispenser))
+	assert.False(t, recipe.Uses(Grinder))
+}
+
+func TestGroups(t *testing.T) {
+	steam := MustStep("steam", "", "")
+	steam.Together = true
+	shot := MustStep("shot", "", "")
+	shot.Together = true
+	recipe := Recipe{MustStep("grind", "", ""), MustStep("brew", "", ""), shot, steam, MustStep("pour", "", "")}
+
+	groups := recipe.Groups()
+	if assert.Len(t, groups, 3) {
+		assert.Equal(t, Recipe{recipe[0]}, groups[0])
+		assert.Equal(t, Recipe{recipe[1], shot, steam}, groups[1])
+		assert.Equal(t, Recipe{recipe[4]}, groups[2])
+	}
+	assert.Len(t, Default(false, false).Groups(), 5, "The steps of the default recipe are performed one by one")
+}
+
+func TestRestart(t *testing.T) {
+	steam := MustStep("steam", "", "")
+	steam.Together = true
+	shot := MustStep("shot", "", "")
+	shot.Together = true
+	recipe := Recipe{MustStep("grind", "", ""), MustStep("brew", "", ""), shot, steam, MustStep("pour", "", "")}
+
+	assert.Equal(t, 0, recipe.Restart(0), "The beans are ground again")
+	assert.Equal(t, 0, recipe.Restart(1), "The ground beans are thrown away with the coffee")
+	assert.Equal(t, 0, recipe.Restart(2))
+	assert.Equal(t, 3, recipe.Restart(3), "Only the milk is steamed again")
+	assert.Equal(t, 0, Recipe{MustStep("brew", "", "")}.Restart(0), "Nothing was ground before the brew")
+}
@@ -0,0 +1,26 @@
+package tracing
+
+// NoopTracer records nothing
+// Its spans still get IDs, so the log lines are correlated even when the spans are not recorded
+type NoopTracer struct{}
+
+// StartSpan starts a span that is not recorded
+func (NoopTracer) StartSpan(parent SpanContext, name string) Span {
+	return noopSpan{context: newSpanContext(parent)}
+}
+
+// noopSpan is a span that is not recorded
+type noopSpan struct {
+	context SpanContext
+}
+
+// Context returns the span context of the span
+func (s noopSpan) Context() SpanContext {
+	return s.context
+}
+
+// SetAttribute does nothing
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+// End does nothing
+func (noopSpan) End() {}
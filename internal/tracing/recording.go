@@ -0,0 +1,141 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RecordedSpan is a span that has ended
+// ParentSpanID is not valid for the root span of a trace
+type RecordedSpan struct {
+	TraceID      TraceID                `json:"traceId"`
+	SpanID       SpanID                 `json:"spanId"`
+	ParentSpanID SpanID                 `json:"parentSpanId"`
+	Name         string                 `json:"name"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// Duration returns how long the span lasted
+func (s RecordedSpan) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// RecordingTracer keeps the spans in memory once they end
+type RecordingTracer struct {
+	spans []RecordedSpan
+	mutex sync.Mutex
+}
+
+// NewRecordingTracer creates a tracer that records the spans in memory
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+// StartSpan starts a span that is recorded when it ends
+func (rt *RecordingTracer) StartSpan(parent SpanContext, name string) Span {
+	context := newSpanContext(parent)
+	return &recordingSpan{
+		tracer:  rt,
+		context: context,
+		span: RecordedSpan{
+			TraceID:      context.TraceID,
+			SpanID:       context.SpanID,
+			ParentSpanID: parent.SpanID,
+			Name:         name,
+			Start:        time.Now(),
+		},
+	}
+}
+
+// Spans returns the spans that have ended, in the order they ended
+func (rt *RecordingTracer) Spans() []RecordedSpan {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	return append([]RecordedSpan{}, rt.spans...)
+}
+
+// Trace returns the spans of a trace, in the order they started
+func (rt *RecordingTracer) Trace(traceID TraceID) []RecordedSpan {
+	spans := make([]RecordedSpan, 0)
+	for _, span := range rt.Spans() {
+		if span.TraceID == traceID {
+			spans = append(spans, span)
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].Start.Before(spans[j].Start) })
+	return spans
+}
+
+// WriteSpans writes the spans that have ended as a JSON array
+func (rt *RecordingTracer) WriteSpans(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rt.Spans())
+}
+
+// Export writes the spans that have ended to the file at the given path
+func (rt *RecordingTracer) Export(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := rt.WriteSpans(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// record adds a span that has ended
+func (rt *RecordingTracer) record(span RecordedSpan) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.spans = append(rt.spans, span)
+}
+
+// recordingSpan is a span of a RecordingTracer
+type recordingSpan struct {
+	tracer  *RecordingTracer
+	span    RecordedSpan
+	context SpanContext
+	ended   bool
+	mutex   sync.Mutex
+}
+
+// Context returns the span context of the span
+func (s *recordingSpan) Context() SpanContext {
+	return s.context
+}
+
+// SetAttribute sets an attribute of the span, it has no effect once the span has ended
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return
+	}
+	if s.span.Attributes == nil {
+		s.span.Attributes = make(map[string]interface{})
+	}
+	s.span.Attributes[key] = value
+}
+
+// End records the span, only the first call has an effect
+func (s *recordingSpan) End() {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.span.End = time.Now()
+	span := s.span
+	s.mutex.Unlock()
+	s.tracer.record(span)
+}
@@ -0,0 +1,114 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync/atomic"
+
+	"github.com/s3ndd/coffeeshop/pkg/utils"
+)
+
+// globalTracer holds the Tracer used by the coffee shop, NoopTracer until SetGlobalTracer is called
+var globalTracer atomic.Value
+
+// TraceID identifies the journey of a customer through the coffee shop
+type TraceID [16]byte
+
+// SpanID identifies a stage of the journey
+type SpanID [8]byte
+
+// String returns the hex representation of the trace ID
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// MarshalText encodes the trace ID as hex
+func (id TraceID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// IsValid returns whether the trace ID is set
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// String returns the hex representation of the span ID
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// MarshalText encodes the span ID as hex
+func (id SpanID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// IsValid returns whether the span ID is set
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext is what a stage passes to the next one so their spans belong to the same trace
+// The zero value is not part of any trace, a span started from it starts a new trace
+type SpanContext struct {
+	TraceID TraceID `json:"traceId"`
+	SpanID  SpanID  `json:"spanId"`
+}
+
+// IsValid returns whether the span context is part of a trace
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// LogFields returns the fields that correlate a log line with the trace
+// It returns no fields if the span context is not part of a trace
+func (sc SpanContext) LogFields() utils.LogFields {
+	if !sc.IsValid() {
+		return utils.LogFields{}
+	}
+	return utils.LogFields{
+		"traceId": sc.TraceID.String(),
+		"spanId":  sc.SpanID.String(),
+	}
+}
+
+// Span is a stage of the journey of a customer
+type Span interface {
+	Context() SpanContext
+	SetAttribute(key string, value interface{})
+	End()
+}
+
+// Tracer starts the spans of the coffee shop
+type Tracer interface {
+	// StartSpan starts a child span of the parent, or the root span of a new trace if the parent is not valid
+	StartSpan(parent SpanContext, name string) Span
+}
+
+// GlobalTracer returns the tracer used by the coffee shop
+func GlobalTracer() Tracer {
+	if tracer, ok := globalTracer.Load().(tracerHolder); ok {
+		return tracer.Tracer
+	}
+	return NoopTracer{}
+}
+
+// SetGlobalTracer sets the tracer used by the coffee shop
+func SetGlobalTracer(tracer Tracer) {
+	// atomic.Value needs values of the same concrete type, so the tracer is wrapped
+	globalTracer.Store(tracerHolder{tracer})
+}
+
+// tracerHolder wraps a Tracer so tracers of different types can be stored in globalTracer
+type tracerHolder struct {
+	Tracer
+}
+
+// newSpanContext creates the span context of a new span of the parent's trace, or of a new trace
+func newSpanContext(parent SpanContext) SpanContext {
+	sc := SpanContext{TraceID: parent.TraceID}
+	if !sc.TraceID.IsValid() {
+		_, _ = rand.Read(sc.TraceID[:])
+	}
+	_, _ = rand.Read(sc.SpanID[:])
+	return sc
+}
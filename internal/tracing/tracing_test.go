@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoopTracer(t *testing.T) {
+	tracer := NoopTracer{}
+	root := tracer.StartSpan(SpanContext{}, "customer")
+	child := tracer.StartSpan(root.Context(), "order")
+	other := tracer.StartSpan(SpanContext{}, "customer")
+
+	assert.True(t, root.Context().IsValid())
+	assert.Equal(t, root.Context().TraceID, child.Context().TraceID)
+	assert.NotEqual(t, root.Context().SpanID, child.Context().SpanID)
+	assert.NotEqual(t, root.Context().TraceID, other.Context().TraceID)
+}
+
+func TestRecordingTracer(t *testing.T) {
+	tracer := NewRecordingTracer()
+	root := tracer.StartSpan(SpanContext{}, "customer")
+	child := tracer.StartSpan(root.Context(), "order")
+	child.SetAttribute("orderId", 1)
+	tracer.StartSpan(SpanContext{}, "customer").End()
+
+	assert.Empty(t, tracer.Trace(root.Context().TraceID), "spans are recorded when they end")
+	child.End()
+	root.End()
+	// only the first End has an effect
+	root.End()
+	child.SetAttribute("ignored", true)
+
+	spans := tracer.Trace(root.Context().TraceID)
+	require.Len(t, spans, 2)
+	assert.Equal(t, "customer", spans[0].Name)
+	assert.False(t, spans[0].ParentSpanID.IsValid())
+	assert.Equal(t, "order", spans[1].Name)
+	assert.Equal(t, root.Context().SpanID, spans[1].ParentSpanID)
+	assert.Equal(t, map[string]interface{}{"orderId": 1}, spans[1].Attributes)
+	assert.GreaterOrEqual(t, spans[1].Duration().Nanoseconds(), int64(0))
+	assert.Len(t, tracer.Spans(), 3)
+}
+
+func TestRecordingTracerWriteSpans(t *testing.T) {
+	tracer := NewRecordingTracer()
+	span := tracer.StartSpan(SpanContext{}, "customer")
+	span.End()
+
+	var buffer bytes.Buffer
+	require.NoError(t, tracer.WriteSpans(&buffer))
+	var spans []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &spans))
+	require.Len(t, spans, 1)
+	assert.Equal(t, span.Context().TraceID.String(), spans[0]["traceId"])
+	assert.Equal(t, span.Context().SpanID.String(), spans[0]["spanId"])
+	assert.Len(t, spans[0]["traceId"], 32)
+}
+
+func TestSpanContextLogFields(t *testing.T) {
+	assert.Empty(t, SpanContext{}.LogFields())
+
+	context := NoopTracer{}.StartSpan(SpanContext{}, "customer").Context()
+	fields := context.LogFields()
+	assert.Equal(t, context.TraceID.String(), fields["traceId"])
+	assert.Equal(t, context.SpanID.String(), fields["spanId"])
+}
+
+func TestGlobalTracer(t *testing.T) {
+	assert.IsType(t, NoopTracer{}, GlobalTracer())
+
+	tracer := NewRecordingTracer()
+	SetGlobalTracer(tracer)
+	defer SetGlobalTracer(NoopTracer{})
+	assert.Same(t, tracer, GlobalTracer())
+}
@@ -3,6 +3,8 @@ package types
 import (
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/tracing"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 	"github.com/shopspring/decimal"
 )
@@ -20,6 +22,21 @@ const (
 	ExtraLarge
 )
 
+// coffeeSizeNames are the names of the coffee sizes
+var coffeeSizeNames = map[CoffeeSize]string{
+	Standard:   "standard",
+	Large:      "large",
+	ExtraLarge: "extra-large",
+}
+
+// String returns the name of the coffee size
+func (s CoffeeSize) String() string {
+	if name, ok := coffeeSizeNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 const (
 	// StandardSizeInOunces represents the standard size in ounces
 	// different coffee sizes will affect the amount of water and beans needed
@@ -27,11 +44,21 @@ const (
 )
 
 // CoffeeType represents the type of a coffee
+// Bean is the name of the beans it is made with, empty if any beans will do
+// MilkInOunces is the milk steamed for a standard size when the coffee is ordered with milk
+// An iced coffee is poured over IceInOunces of ice for a standard size, a cold brew coffee is drawn from the cold brew stock
+// Recipe is the steps of its preparation as configured, the barista follows the parsed recipe of the coffee type
 type CoffeeType struct {
 	Name              string
 	BeansToWaterRatio decimal.Decimal
 	Price             decimal.Decimal
 	SizeInOunces      int
+	Bean              string
+	MilkInOunces      decimal.Decimal
+	Iced              bool
+	IceInOunces       decimal.Decimal
+	ColdBrew          bool
+	Recipe            []config.RecipeStep
 }
 
 // Coffee represents a coffee
@@ -41,10 +68,19 @@ type Coffee struct {
 	extras      []string
 	beansNeeded decimal.Decimal
 	waterNeeded decimal.Decimal
+	milkNeeded  decimal.Decimal
+	iceNeeded   decimal.Decimal
 	beansReady  chan bool
 	waterReady  chan bool
+	milkReady   chan bool
+	iceReady    chan bool
 	grindTime   *time.Duration
 	brewTime    *time.Duration
+	// steamTime, iceTime and condimentTime are zero when the coffee has no milk, ice or condiments
+	steamTime     time.Duration
+	iceTime       time.Duration
+	condimentTime time.Duration
+	spanContext   tracing.SpanContext
 }
 
 // NewCoffee creates a new coffee
@@ -55,8 +91,12 @@ func NewCoffee(coffeeType CoffeeType, size CoffeeSize, extras []string) *Coffee
 		extras:      extras,
 		waterNeeded: calculateWaterNeeded(&coffeeType, size),
 		beansNeeded: calculateBeansNeeded(&coffeeType, size),
+		milkNeeded:  calculateMilkNeeded(&coffeeType, size),
+		iceNeeded:   calculateIceNeeded(&coffeeType, size),
 		beansReady:  make(chan bool),
 		waterReady:  make(chan bool),
+		milkReady:   make(chan bool),
+		iceReady:    make(chan bool),
 	}
 }
 
@@ -75,6 +115,19 @@ func (c *Coffee) Extras() []string {
 	return c.extras
 }
 
+// SubstituteExtra replaces an extra of the coffee by another one, for example when the extra is out of stock
+// The extras are copied, so the slice the coffee was created with is left untouched
+func (c *Coffee) SubstituteExtra(extra string, substitute string) {
+	extras := make([]string, len(c.extras))
+	for i, e := range c.extras {
+		if e == extra {
+			e = substitute
+		}
+		extras[i] = e
+	}
+	c.extras = extras
+}
+
 // BeansNeeded returns the amount of beans needed for the coffee
 func (c *Coffee) BeansNeeded() decimal.Decimal {
 	return c.beansNeeded
@@ -85,6 +138,16 @@ func (c *Coffee) WaterNeeded() decimal.Decimal {
 	return c.waterNeeded
 }
 
+// MilkNeeded returns the amount of milk steamed for the coffee when it is ordered with milk
+func (c *Coffee) MilkNeeded() decimal.Decimal {
+	return c.milkNeeded
+}
+
+// IceNeeded returns the amount of ice the coffee is poured over, zero if the coffee is not iced
+func (c *Coffee) IceNeeded() decimal.Decimal {
+	return c.iceNeeded
+}
+
 // BeansReady returns a channel that will be notified when the beans are ready
 func (c *Coffee) BeansReady() chan bool {
 	return c.beansReady
@@ -95,13 +158,31 @@ func (c *Coffee) WaterReady() chan bool {
 	return c.waterReady
 }
 
+// MilkReady returns a channel that will be notified when the milk is steamed
+func (c *Coffee) MilkReady() chan bool {
+	return c.milkReady
+}
+
+// IceReady returns a channel that will be notified when the ice is dispensed
+func (c *Coffee) IceReady() chan bool {
+	return c.iceReady
+}
+
 // SetGrindTime sets the time it took to grind the beans
 func (c *Coffee) SetGrindTime(grindTime time.Duration) {
 	c.grindTime = &grindTime
 }
 
-// GrindTime returns the time it took to grind the beans
+// AddGrindTime adds to the time spent grinding the beans, for a recipe that grinds more than once
+func (c *Coffee) AddGrindTime(grindTime time.Duration) {
+	c.SetGrindTime(c.GrindTime() + grindTime)
+}
+
+// GrindTime returns the time it took to grind the beans, zero if they were not ground, like for a cold brew
 func (c *Coffee) GrindTime() time.Duration {
+	if c.grindTime == nil {
+		return 0
+	}
 	return *c.grindTime
 }
 
@@ -110,11 +191,59 @@ func (c *Coffee) SetBrewTime(brewTime time.Duration) {
 	c.brewTime = &brewTime
 }
 
-// BrewTime returns the time it took to brew the coffee
+// AddBrewTime adds to the time spent on the brewer, for a recipe with extra shots
+func (c *Coffee) AddBrewTime(brewTime time.Duration) {
+	c.SetBrewTime(c.BrewTime() + brewTime)
+}
+
+// BrewTime returns the time it took to brew the coffee, zero if it was not brewed, like for a cold brew
 func (c *Coffee) BrewTime() time.Duration {
+	if c.brewTime == nil {
+		return 0
+	}
 	return *c.brewTime
 }
 
+// SetSteamTime sets the time it took to steam the milk
+func (c *Coffee) SetSteamTime(steamTime time.Duration) {
+	c.steamTime = steamTime
+}
+
+// AddSteamTime adds to the time spent steaming the milk
+func (c *Coffee) AddSteamTime(steamTime time.Duration) {
+	c.steamTime += steamTime
+}
+
+// SteamTime returns the time it took to steam the milk, zero if no milk was steamed
+func (c *Coffee) SteamTime() time.Duration {
+	return c.steamTime
+}
+
+// SetIceTime sets the time it took to dispense the ice
+func (c *Coffee) SetIceTime(iceTime time.Duration) {
+	c.iceTime = iceTime
+}
+
+// AddIceTime adds to the time spent dispensing the ice
+func (c *Coffee) AddIceTime(iceTime time.Duration) {
+	c.iceTime += iceTime
+}
+
+// IceTime returns the time it took to dispense the ice, zero if the coffee is not iced
+func (c *Coffee) IceTime() time.Duration {
+	return c.iceTime
+}
+
+// SetCondimentTime sets the time it took to add the condiments
+func (c *Coffee) SetCondimentTime(condimentTime time.Duration) {
+	c.condimentTime = condimentTime
+}
+
+// CondimentTime returns the time it took to add the condiments, zero if there were none
+func (c *Coffee) CondimentTime() time.Duration {
+	return c.condimentTime
+}
+
 // SetBeansReady sets the beans ready flag
 func (c *Coffee) SetBeansReady(ready bool) {
 	c.beansReady <- ready
@@ -125,6 +254,16 @@ func (c *Coffee) SetWaterReady(ready bool) {
 	c.waterReady <- ready
 }
 
+// SetMilkReady sets the milk ready flag
+func (c *Coffee) SetMilkReady(ready bool) {
+	c.milkReady <- ready
+}
+
+// SetIceReady sets the ice ready flag
+func (c *Coffee) SetIceReady(ready bool) {
+	c.iceReady <- ready
+}
+
 // CalculateBeansNeeded calculates the amount of beans needed for a coffee
 // The unit of measure is grams
 func calculateBeansNeeded(coffeeType *CoffeeType, size CoffeeSize) decimal.Decimal {
@@ -136,3 +275,29 @@ func calculateBeansNeeded(coffeeType *CoffeeType, size CoffeeSize) decimal.Decim
 func calculateWaterNeeded(coffeeType *CoffeeType, size CoffeeSize) decimal.Decimal {
 	return utils.FloatToDecimal(1 + sizeWaterRatio*float64(size)).Mul(decimal.NewFromInt(int64(coffeeType.SizeInOunces))).Round(2)
 }
+
+// calculateMilkNeeded calculates the amount of milk steamed for a coffee based on the coffee type and size
+// The larger sizes get more milk in the same proportion as water, the unit of measure is ounces
+func calculateMilkNeeded(coffeeType *CoffeeType, size CoffeeSize) decimal.Decimal {
+	return utils.FloatToDecimal(1 + sizeWaterRatio*float64(size)).Mul(coffeeType.MilkInOunces).Round(2)
+}
+
+// calculateIceNeeded calculates the amount of ice an iced coffee is poured over based on the coffee type and size
+// The larger sizes get more ice in the same proportion as water, the unit of measure is ounces
+func calculateIceNeeded(coffeeType *CoffeeType, size CoffeeSize) decimal.Decimal {
+	if !coffeeType.Iced {
+		return decimal.Zero
+	}
+	return utils.FloatToDecimal(1 + sizeWaterRatio*float64(size)).Mul(coffeeType.IceInOunces).Round(2)
+}
+
+// SpanContext returns the span context of the stage the coffee is in
+// It is how the grinders and brewers, which only get the coffee, know which trace they are working for
+func (c *Coffee) SpanContext() tracing.SpanContext {
+	return c.spanContext
+}
+
+// SetSpanContext sets the span context of the stage the coffee is in
+func (c *Coffee) SetSpanContext(spanContext tracing.SpanContext) {
+	c.spanContext = spanContext
+}
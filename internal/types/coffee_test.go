@@ -32,3 +32,44 @@ func TestCalculateWaterNeeded(t *testing.T) {
 
 	assert.Equal(t, expectedWaterNeeded, waterNeeded, "Water needed should match expected value")
 }
+
+func TestCoffeeSizeString(t *testing.T) {
+	assert.Equal(t, "standard", Standard.String())
+	assert.Equal(t, "large", Large.String())
+	assert.Equal(t, "extra-large", ExtraLarge.String())
+	assert.Equal(t, "unknown", CoffeeSize(42).String())
+}
+
+func TestSubstituteExtra(t *testing.T) {
+	extras := []string{"milk", "sugar"}
+	coffee := NewCoffee(CoffeeType{Name: "Latte", SizeInOunces: 12}, Standard, extras)
+
+	coffee.SubstituteExtra("milk", "oat milk")
+
+	assert.Equal(t, []string{"oat milk", "sugar"}, coffee.Extras())
+	assert.Equal(t, []string{"milk", "sugar"}, extras, "the extras the coffee was created with are not modified")
+}
+
+func TestCalculateMilkNeeded(t *testing.T) {
+	coffeeType := CoffeeType{
+		SizeInOunces: 12,
+		MilkInOunces: decimal.NewFromInt(8),
+	}
+
+	assert.Equal(t, "8", calculateMilkNeeded(&coffeeType, Standard).String())
+	assert.Equal(t, "12", calculateMilkNeeded(&coffeeType, ExtraLarge).String(), "Larger sizes should get more milk")
+	assert.True(t, calculateMilkNeeded(&CoffeeType{SizeInOunces: 10}, Large).IsZero(), "A coffee type without milk should steam none")
+}
+
+func TestCalculateIceNeeded(t *testing.T) {
+	coffeeType := CoffeeType{
+		SizeInOunces: 12,
+		Iced:         true,
+		IceInOunces:  decimal.NewFromInt(4),
+	}
+
+	assert.Equal(t, "4", calculateIceNeeded(&coffeeType, Standard).String())
+	assert.Equal(t, "5", calculateIceNeeded(&coffeeType, Large).String(), "Larger sizes should get more ice")
+	coffeeType.Iced = false
+	assert.True(t, calculateIceNeeded(&coffeeType, Large).IsZero(), "A hot coffee should get no ice")
+}
@@ -2,9 +2,12 @@ package types
 
 import (
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"github.com/s3ndd/coffeeshop/internal/config"
+	"github.com/s3ndd/coffeeshop/internal/tracing"
+	"github.com/s3ndd/coffeeshop/pkg/utils"
 )
 
 // extrasOptions is a list of extras options
@@ -17,21 +20,37 @@ var extrasOptions = [][]string{
 	{"milk", "sugar"},
 }
 
+// lastCustomerID is the ID of the last customer created, customer names are not unique
+var lastCustomerID atomic.Int64
+
 // Customer represents a customer
+// span is the root span of the trace of the customer's journey, from arrival to leaving with the coffee
 type Customer struct {
+	id          int64
 	name        string
 	arrivedTime time.Time
 	leaveTime   *time.Time
 	config      config.Configurer
+	span        tracing.Span
 }
 
-// NewCustomer creates a new customer
+// NewCustomer creates a new customer and starts the trace of its journey
 func NewCustomer(name string, config config.Configurer) *Customer {
-	return &Customer{
+	customer := &Customer{
+		id:          lastCustomerID.Add(1),
 		name:        name,
 		arrivedTime: time.Now(),
 		config:      config,
+		span:        tracing.GlobalTracer().StartSpan(tracing.SpanContext{}, "customer"),
 	}
+	customer.span.SetAttribute("customerId", customer.id)
+	customer.span.SetAttribute("customer", name)
+	return customer
+}
+
+// ID returns the customer's unique ID
+func (c *Customer) ID() int64 {
+	return c.id
 }
 
 // Name returns the customer's name
@@ -49,9 +68,29 @@ func (c *Customer) LeaveTime() *time.Time {
 	return c.leaveTime
 }
 
-// SetLeaveTime sets the time the customer left
+// SetLeaveTime sets the time the customer left, which ends the trace of its journey
 func (c *Customer) SetLeaveTime(leaveTime time.Time) {
 	c.leaveTime = &leaveTime
+	if c.span != nil {
+		c.span.End()
+	}
+}
+
+// SpanContext returns the span context of the customer's journey, the stages serving the customer are its children
+// A customer that was not created by NewCustomer is not traced, its span context is not valid
+func (c *Customer) SpanContext() tracing.SpanContext {
+	if c.span == nil {
+		return tracing.SpanContext{}
+	}
+	return c.span.Context()
+}
+
+// Logger returns a logger whose lines carry the trace and customer IDs
+func (c *Customer) Logger() utils.LoggerInterface {
+	fields := c.SpanContext().LogFields()
+	fields["customerId"] = c.id
+	fields["customer"] = c.name
+	return utils.Logger().WithFields(fields)
 }
 
 // WaitTime returns the time the customer waited
@@ -77,3 +77,10 @@ func TestCustomerPlaceOrder(t *testing.T) {
 	assert.Equal(t, customer, order.Customer(), "Order customer should be the customer that placed the order")
 	assert.Equal(t, CoffeeType(*mockConfig.CoffeeTypes()[0]), order.Coffee().CoffeeType(), "Order coffee type should be the first coffee type in the configuration")
 }
+
+func TestCustomerIDsAreUnique(t *testing.T) {
+	customer1 := NewCustomer("Shelly Shi", createMockConfig())
+	customer2 := NewCustomer("Shelly Shi", createMockConfig())
+
+	assert.NotEqual(t, customer1.ID(), customer2.ID(), "Customers with the same name should have different IDs")
+}
@@ -1,29 +1,49 @@
 package types
 
 import (
+	"sync/atomic"
 	"time"
 
+	"github.com/s3ndd/coffeeshop/internal/tracing"
 	"github.com/s3ndd/coffeeshop/pkg/utils"
 	"github.com/shopspring/decimal"
 )
 
+// lastOrderID is the ID of the last order created
+var lastOrderID atomic.Int64
+
 // Order represents an order
+// span is a child of the customer's span, it lasts from the order to its completion
 type Order struct {
+	id         int64
 	customer   *Customer
 	coffee     *Coffee
 	orderTime  time.Time
 	servedTime *time.Time
 	price      decimal.Decimal
+	span       tracing.Span
 }
 
 // NewOrder creates a new order
 func NewOrder(customer *Customer, coffeeType CoffeeType, coffeeSize CoffeeSize, extras []string) *Order {
-	return &Order{
+	order := &Order{
+		id:        lastOrderID.Add(1),
 		customer:  customer,
 		orderTime: time.Now(),
 		coffee:    NewCoffee(coffeeType, coffeeSize, extras),
 		price:     calculatePrice(coffeeType, coffeeSize, extras),
+		span:      tracing.GlobalTracer().StartSpan(customer.SpanContext(), "order"),
 	}
+	order.span.SetAttribute("orderId", order.id)
+	order.span.SetAttribute("coffee", coffeeType.Name)
+	order.span.SetAttribute("size", coffeeSize.String())
+	order.coffee.SetSpanContext(order.span.Context())
+	return order
+}
+
+// ID returns the order's unique ID
+func (o *Order) ID() int64 {
+	return o.id
 }
 
 // Customer returns the order's customer
@@ -40,8 +60,35 @@ func (o *Order) Coffee() *Coffee {
 func (o *Order) Complete() {
 	now := time.Now()
 	o.servedTime = &now
+	o.span.End()
 	o.Customer().SetLeaveTime(now)
-	utils.Logger().WithField("order", o.customer.Name()).Info("Order completed")
+	o.Logger().Info("Order completed")
+}
+
+// Cancel cancels the order, the customer leaves without a coffee
+func (o *Order) Cancel(reason string) {
+	o.span.SetAttribute("cancelled", reason)
+	o.span.End()
+	o.Customer().SetLeaveTime(time.Now())
+	o.Logger().WithField("reason", reason).Info("Order cancelled")
+}
+
+// SpanContext returns the span context of the order, the stages processing the order are its children
+func (o *Order) SpanContext() tracing.SpanContext {
+	return o.span.Context()
+}
+
+// Logger returns a logger whose lines carry the trace and order IDs
+func (o *Order) Logger() utils.LoggerInterface {
+	fields := o.SpanContext().LogFields()
+	fields["orderId"] = o.id
+	fields["customer"] = o.customer.Name()
+	return utils.Logger().WithFields(fields)
+}
+
+// Price returns the order's price
+func (o *Order) Price() decimal.Decimal {
+	return o.price
 }
 
 func (o *Order) OrderTime() time.Time {
@@ -3,6 +3,7 @@ package types
 import (
 	"testing"
 
+	"github.com/s3ndd/coffeeshop/internal/tracing"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
@@ -35,3 +36,43 @@ func TestCompleteOrder(t *testing.T) {
 	assert.NotNil(t, order.Customer().LeaveTime())
 	assert.True(t, order.ProcessingTime() > 0)
 }
+
+func TestCancelOrder(t *testing.T) {
+	customer := &Customer{name: "Erin"}
+	order := NewOrder(customer, CoffeeType{Name: "Latte", Price: decimal.NewFromFloat(3.5)}, Standard, nil)
+	order.Cancel("out of beans")
+
+	assert.Nil(t, order.ServedTime())
+	assert.NotNil(t, order.Customer().LeaveTime())
+	assert.Zero(t, order.ProcessingTime())
+}
+
+func TestOrderIDsAreUnique(t *testing.T) {
+	customer := &Customer{name: "Carol"}
+	coffeeType := CoffeeType{Name: "Latte", Price: decimal.NewFromFloat(3.5)}
+
+	order1 := NewOrder(customer, coffeeType, Standard, nil)
+	order2 := NewOrder(customer, coffeeType, Standard, nil)
+
+	assert.NotEqual(t, order1.ID(), order2.ID())
+}
+
+func TestOrderIsTracedAsPartOfTheCustomerJourney(t *testing.T) {
+	tracer := tracing.NewRecordingTracer()
+	tracing.SetGlobalTracer(tracer)
+	defer tracing.SetGlobalTracer(tracing.NoopTracer{})
+
+	customer := NewCustomer("Dave", nil)
+	order := NewOrder(customer, CoffeeType{Name: "Latte", Price: decimal.NewFromFloat(3.5)}, Standard, nil)
+	assert.Equal(t, customer.SpanContext().TraceID, order.SpanContext().TraceID)
+	assert.Equal(t, order.SpanContext(), order.Coffee().SpanContext())
+
+	order.Complete()
+
+	spans := tracer.Trace(customer.SpanContext().TraceID)
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "customer", spans[0].Name)
+	assert.Equal(t, "order", spans[1].Name)
+	assert.Equal(t, customer.SpanContext().SpanID, spans[1].ParentSpanID)
+	assert.Equal(t, order.ID(), spans[1].Attributes["orderId"])
+}
@@ -70,6 +70,9 @@ func setupLoggerConfig() *zap.Config {
 }
 
 // Logger returns the global log entry.
+// Its lines carry no trace or order IDs. The lines about a customer or an order are logged with Customer.Logger or Order.Logger,
+// or with the LogFields of a span, which add them. The lines about the shop itself, like the deliveries, the equipment outages
+// and the summaries, belong to no order and carry none.
 func Logger() LoggerInterface {
 	if sharedLogger.Load() == nil {
 		sharedLogger.Store(newZapLogger())
@@ -77,6 +80,18 @@ func Logger() LoggerInterface {
 	return sharedLogger.Load().(LoggerInterface)
 }
 
+// SetLogOutputPaths sends the log lines to the given paths, for example a file, instead of stdout.
+// The loggers already derived from the global logger keep writing to the previous paths.
+func SetLogOutputPaths(paths ...string) error {
+	zapLoggerConfig.OutputPaths = paths
+	logger, err := zapLoggerConfig.Build()
+	if err != nil {
+		return err
+	}
+	sharedLogger.Store(LoggerInterface(&ZapLogger{logger}))
+	return nil
+}
+
 // WithField returns the logger at the supplied field.
 func (zl *ZapLogger) WithField(key string, value interface{}) LoggerInterface {
 	newLogger := zl.Logger.WithOptions(zap.Fields(zap.Any(key, value)))